@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matst80/slask-finder/pkg/embeddings"
+	"github.com/matst80/slask-finder/pkg/index"
+	"github.com/matst80/slask-finder/pkg/messaging"
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+const clusterReportFile = "clusters.json"
+const clusterBatchSize = 500
+
+var clusterFacetId = types.FacetId(0)
+
+func init() {
+	if v, ok := os.LookupEnv("CLUSTER_FACET_ID"); ok {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			log.Printf("Invalid CLUSTER_FACET_ID %s: %v", v, err)
+			return
+		}
+		clusterFacetId = types.FacetId(id)
+	}
+}
+
+type ClusterRequest struct {
+	embeddings.ClusterOptions
+	// FacetId is the key facet the cluster id is written to, 0 only creates the
+	// report. The writer creates the facet of CLUSTER_FACET_ID
+	FacetId types.FacetId `json:"facetId"`
+}
+
+type ClusterReport struct {
+	Running    bool                       `json:"running"`
+	Started    time.Time                  `json:"started"`
+	Duration   string                     `json:"duration,omitempty"`
+	Options    ClusterRequest             `json:"options"`
+	Items      int                        `json:"items"`
+	Sizes      []int                      `json:"sizes,omitempty"`
+	Duplicates []embeddings.DuplicatePair `json:"duplicates,omitempty"`
+	Updated    int                        `json:"updated"`
+	Error      string                     `json:"error,omitempty"`
+}
+
+type clusterJob struct {
+	mu     sync.RWMutex
+	report *ClusterReport
+}
+
+// Cluster starts a clustering run on POST and returns the latest report
+func (ws *app) Cluster(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		req := ClusterRequest{
+			ClusterOptions: embeddings.DefaultClusterOptions(),
+			FacetId:        clusterFacetId,
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		ws.clusters.mu.Lock()
+		if ws.clusters.report != nil && ws.clusters.report.Running {
+			ws.clusters.mu.Unlock()
+			http.Error(w, "clustering is already running", http.StatusConflict)
+			return
+		}
+		report := &ClusterReport{
+			Running: true,
+			Started: time.Now(),
+			Options: req,
+		}
+		ws.clusters.report = report
+		snapshot := *report
+		ws.clusters.mu.Unlock()
+
+		go ws.runClustering(report)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+			log.Printf("failed to encode cluster report: %v", err)
+		}
+		return
+	}
+
+	ws.clusters.mu.RLock()
+	defer ws.clusters.mu.RUnlock()
+	report := ws.clusters.report
+	if report == nil {
+		report = &ClusterReport{}
+		if err := ws.storage.LoadJson(report, clusterReportFile); err != nil {
+			http.Error(w, "no cluster report found", http.StatusNotFound)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("failed to encode cluster report: %v", err)
+	}
+}
+
+func (ws *app) runClustering(report *ClusterReport) {
+	data := ws.index.GetAllEmbeddings()
+	log.Printf("Clustering %d embeddings", len(data))
+	result := embeddings.ClusterEmbeddings(data, report.Options.ClusterOptions)
+
+	updated := 0
+	var err error
+	if report.Options.FacetId != 0 {
+		updated, err = ws.publishClusterFacet(report.Options.FacetId, result)
+		if err != nil {
+			log.Printf("Failed to publish cluster facet: %v", err)
+		}
+	}
+
+	ws.clusters.mu.Lock()
+	report.Running = false
+	report.Duration = time.Since(report.Started).String()
+	report.Items = len(result.Assignments)
+	report.Sizes = result.ClusterSizes()
+	report.Duplicates = result.Duplicates
+	report.Updated = updated
+	if err != nil {
+		report.Error = err.Error()
+	}
+	ws.clusters.mu.Unlock()
+
+	log.Printf("Clustered %d items into %d clusters, %d likely duplicates in %s", report.Items, result.Clusters, len(result.Duplicates), report.Duration)
+	ws.clusters.mu.RLock()
+	defer ws.clusters.mu.RUnlock()
+	if err := ws.storage.SaveJson(report, clusterReportFile); err != nil {
+		log.Printf("Could not save cluster report: %v", err)
+	}
+}
+
+// publishClusterFacet sends the items with their cluster id through
+// item_added, the facet is created by the writer from CLUSTER_FACET_ID
+func (ws *app) publishClusterFacet(facetId types.FacetId, result *embeddings.ClusterResult) (int, error) {
+	if err := ws.checkFacet(facetId); err != nil {
+		return 0, err
+	}
+
+	ids := make([]types.ItemId, 0, len(result.Assignments))
+	for id := range result.Assignments {
+		ids = append(ids, id)
+	}
+	updated := 0
+	for start := 0; start < len(ids); start += clusterBatchSize {
+		batch := ids[start:min(start+clusterBatchSize, len(ids))]
+		items, err := ws.fetchItems(batch)
+		if err != nil {
+			return updated, err
+		}
+		changed := make([]*index.DataItem, 0, len(items))
+		for _, item := range items {
+			cluster := strconv.Itoa(result.Assignments[item.GetId()])
+			if current, ok := item.GetStringFieldValue(facetId); ok && current == cluster {
+				continue
+			}
+			item.Fields.SetKeyFacet(facetId, []string{cluster})
+			changed = append(changed, item)
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		if err = messaging.SendChange(ws.conn, ws.country, "item_added", changed); err != nil {
+			return updated, err
+		}
+		updated += len(changed)
+	}
+	return updated, nil
+}
+
+// checkFacet returns an error when the readers don't have the facet
+func (ws *app) checkFacet(id types.FacetId) error {
+	resp, err := http.Get(fmt.Sprintf("%s/api/values/%d", ws.proxyUrl, id))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("facet %d not found, status %d", id, resp.StatusCode)
+	}
+	return nil
+}
+
+func (ws *app) fetchItems(ids []types.ItemId) ([]*index.DataItem, error) {
+	var bodyBuilder strings.Builder
+	for _, id := range ids {
+		fmt.Fprintln(&bodyBuilder, id)
+	}
+	resp, err := http.Post(ws.proxyUrl+"/api/stream-items", "text/plain", strings.NewReader(bodyBuilder.String()))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch items, status %d", resp.StatusCode)
+	}
+	items := make([]*index.DataItem, 0, len(ids))
+	dec := json.NewDecoder(resp.Body)
+	for dec.More() {
+		item := &index.DataItem{}
+		if err = dec.Decode(item); err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -10,15 +11,94 @@ import (
 	"time"
 
 	"github.com/matst80/slask-finder/pkg/embeddings"
+	"github.com/matst80/slask-finder/pkg/index"
 	"github.com/matst80/slask-finder/pkg/storage"
 	"github.com/matst80/slask-finder/pkg/types"
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type app struct {
-	country  string
-	storage  *storage.DiskStorage
-	index    *embeddings.ItemEmbeddingsHandler
-	proxyUrl string
+	country     string
+	storage     *storage.DiskStorage
+	index       *embeddings.ItemEmbeddingsHandler
+	textBuilder *embeddings.ItemTextBuilder
+	engine      *embeddings.OllamaEmbeddingsEngine
+	queryEngine *embeddings.CachedEmbeddingsEngine
+	proxyUrl    string
+	conn        *amqp.Connection
+	clusters    clusterJob
+}
+
+type TemplatePreviewResponse struct {
+	Id          types.ItemId             `json:"id"`
+	ProductType string                   `json:"productType,omitempty"`
+	Template    types.EmbeddingsTemplate `json:"template"`
+	Configured  bool                     `json:"configured"`
+	Text        string                   `json:"text"`
+}
+
+// TemplatePreview renders the embeddings text for an item, a POST body with a
+// template is used instead of the configured one to try changes before saving
+func (ws *app) TemplatePreview(w http.ResponseWriter, r *http.Request) {
+	idString := r.PathValue("id")
+	id, err := strconv.ParseUint(idString, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	item, err := ws.fetchItem(types.ItemId(id))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	tmpl, configured := types.CurrentSettings.GetEmbeddingsTemplate(item)
+	if r.Method == http.MethodPost {
+		tmpl = types.EmbeddingsTemplate{}
+		if err = json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		configured = false
+	}
+
+	response := TemplatePreviewResponse{
+		Id:         item.GetId(),
+		Template:   tmpl,
+		Configured: configured,
+	}
+	response.ProductType, _ = item.GetStringFieldValue(types.CurrentSettings.ProductTypeId)
+	if configured || r.Method == http.MethodPost {
+		response.Text, err = ws.textBuilder.Render(item, tmpl)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to render template: %v", err), http.StatusBadRequest)
+			return
+		}
+	} else {
+		response.Text = ws.textBuilder.Build(item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("failed to encode preview: %v", err)
+	}
+}
+
+func (ws *app) fetchItem(id types.ItemId) (*index.DataItem, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/api/get/%d", ws.proxyUrl, id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("item not found with id: %d", id)
+	}
+	item := &index.DataItem{}
+	if err = json.NewDecoder(resp.Body).Decode(item); err != nil {
+		return nil, err
+	}
+	return item, nil
 }
 
 func (ws *app) CosineSimilar(w http.ResponseWriter, r *http.Request) {
@@ -51,7 +131,7 @@ func (ws *app) SearchEmbeddings(w http.ResponseWriter, r *http.Request) {
 
 	start := time.Now()
 	// Generate embeddings for the query
-	queryEmbeddings, err := ws.index.GetEmbeddingsEngine().GenerateEmbeddings(query)
+	queryEmbeddings, err := ws.queryEngine.GenerateEmbeddings(query)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to generate embeddings: %v", err), http.StatusInternalServerError)
 		return
@@ -71,6 +151,14 @@ func (ws *app) SearchEmbeddings(w http.ResponseWriter, r *http.Request) {
 	ws.proxyIdsToStream(w, r, ids)
 }
 
+func (ws *app) EndpointStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(ws.engine.EndpointStatus()); err != nil {
+		log.Printf("failed to encode endpoint status: %v", err)
+	}
+}
+
 func (ws *app) proxyIdsToStream(w http.ResponseWriter, _ *http.Request, ids []uint32) {
 	if len(ids) == 0 {
 		w.WriteHeader(http.StatusOK)
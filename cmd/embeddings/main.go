@@ -42,8 +42,19 @@ func main() {
 	// Application entry point
 	diskStorage := storage.NewDiskStorage(country, "data")
 
+	if err := diskStorage.LoadSettings(); err != nil {
+		log.Printf("Could not load settings from file: %v", err)
+	}
+	storageFacets := []types.StorageFacet{}
+	if err := diskStorage.LoadFacets(&storageFacets); err != nil {
+		log.Printf("Could not load facets from file: %v", err)
+	}
+	textBuilder := embeddings.NewItemTextBuilder(storageFacets)
+
 	embeddingsEngine := embeddings.NewOllamaEmbeddingsEngineWithMultipleEndpoints(ollamaModel, ollamaUrls...)
-	embeddingsIndex := embeddings.NewItemEmbeddingsHandler(embeddings.DefaultEmbeddingsHandlerOptions(embeddingsEngine), func(data map[types.ItemId]types.Embeddings) error {
+	opts := embeddings.DefaultEmbeddingsHandlerOptions(embeddingsEngine)
+	opts.TextBuilder = textBuilder
+	embeddingsIndex := embeddings.NewItemEmbeddingsHandler(opts, func(data map[types.ItemId]types.Embeddings) error {
 		log.Printf("Queue done, saving %d embeddings to disk", len(data))
 		err := diskStorage.SaveEmbeddings(&data)
 		if err != nil {
@@ -65,10 +76,13 @@ func main() {
 	}
 
 	a := &app{
-		country:  country,
-		storage:  diskStorage,
-		index:    embeddingsIndex,
-		proxyUrl: os.Getenv("PROXY_URL"),
+		country:     country,
+		storage:     diskStorage,
+		index:       embeddingsIndex,
+		textBuilder: textBuilder,
+		engine:      embeddingsEngine,
+		queryEngine: embeddings.NewCachedEmbeddingsEngine(embeddingsEngine, ollamaModel, 10000),
+		proxyUrl:    os.Getenv("PROXY_URL"),
 	}
 
 	// Entry point for the master command
@@ -83,6 +97,7 @@ func main() {
 		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
 	defer conn.Close()
+	a.conn = conn
 
 	itemCh, err := conn.Channel()
 	if err != nil {
@@ -103,10 +118,36 @@ func main() {
 		log.Fatalf("Failed to register a listener: %v", err)
 	}
 
+	err = messaging.ListenToTopic(itemCh, country, "facet_change", func(d amqp.Delivery) error {
+		var changes []types.FieldChange
+		err := json.Unmarshal(d.Body, &changes)
+		if err == nil {
+			log.Printf("Got fieldchanges %d", len(changes))
+			textBuilder.HandleFieldChanges(changes)
+		}
+		return err
+	})
+	if err != nil {
+		log.Fatalf("Failed to listen to facet_change topic: %v", err)
+	}
+	err = messaging.ListenToTopic(itemCh, country, "settings_change", func(d amqp.Delivery) error {
+		log.Printf("Got settings change, reloading settings")
+		if err := diskStorage.LoadSettings(); err != nil {
+			log.Printf("Could not update settings from file: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to listen to settings_change topic: %v", err)
+	}
+
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/ai/cosine-similar/{id}", a.CosineSimilar)
 	mux.HandleFunc("/ai/natural", a.SearchEmbeddings)
+	mux.HandleFunc("/ai/template-preview/{id}", a.TemplatePreview)
+	mux.HandleFunc("/ai/cluster", a.Cluster)
+	mux.HandleFunc("GET /ai/endpoints", a.EndpointStatus)
 
 	cfg := common.LoadTimeoutConfig(common.TimeoutConfig{
 		ReadHeader: 5 * time.Second,
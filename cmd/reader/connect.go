@@ -106,6 +106,10 @@ func (a *app) ConnectSettingsChange(conn *amqp.Connection) {
 			if err != nil {
 				log.Printf("Could not update settings from file: %v", err)
 			}
+			go a.sortingHandler.SetExperiment(types.CurrentSettings.GetExperiment(), a.itemIndex.GetAllItems())
+			if item.Type == "sessionBoost" {
+				go a.sortingHandler.SetSessionBoost(types.CurrentSettings.GetSessionBoost(), a.itemIndex.GetAllItems())
+			}
 		} else {
 			log.Printf("Failed to unmarshal upset message %v", err)
 		}
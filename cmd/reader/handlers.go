@@ -2,8 +2,10 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"log"
 	"net/http"
 	"slices"
@@ -14,6 +16,7 @@ import (
 
 	"github.com/matst80/slask-finder/pkg/facet"
 	"github.com/matst80/slask-finder/pkg/search"
+	"github.com/matst80/slask-finder/pkg/sorting"
 	"github.com/matst80/slask-finder/pkg/types"
 	"go.opentelemetry.io/otel/attribute"
 )
@@ -44,7 +47,16 @@ func (ws *app) GetFacets(w http.ResponseWriter, r *http.Request, sessionId int,
 	if baseIds.Len() == 0 {
 		baseIds.Merge(ws.searchIndex.All)
 	}
-	ws.facetHandler.GetOtherFacets(ids, sr, ch, wg)
+	profile := ws.facetHandler.GetFacetProfile(sr, ids)
+	cacheable := true
+	if profile == nil {
+		// the facet order of the experiment variant makes the response session specific
+		if variant, ok := types.CurrentSettings.GetExperiment().Assign(sessionId); ok && len(variant.FacetOrder) > 0 {
+			profile = &types.FacetProfile{FacetIds: variant.FacetOrder}
+			cacheable = false
+		}
+	}
+	ws.facetHandler.GetOtherFacets(ids, sr, profile, ch, wg)
 	ws.facetHandler.GetSearchedFacets(ictx, baseIds, sr, ch, wg)
 	// todo optimize
 	go func() {
@@ -63,10 +75,23 @@ func (ws *app) GetFacets(w http.ResponseWriter, r *http.Request, sessionId int,
 		}
 	}
 
-	publicHeaders(w, r, true, "600")
+	if cacheable {
+		publicHeaders(w, r, true, "600")
+	} else {
+		defaultHeaders(w, r, true, "600")
+	}
 	w.Header().Set("x-duration", fmt.Sprintf("%v", time.Since(s)))
 	w.WriteHeader(http.StatusOK)
-	ws.facetHandler.SortJsonFacets(ret)
+	ret = ws.facetHandler.ApplyFacetProfile(profile, ret)
+	if len(sr.Stats) > 0 {
+		statsIds := ids
+		// without a query or filters nothing is matched and the stats include all items
+		if sr.Query == "" && len(sr.Stock) == 0 && (sr.Filters == nil ||
+			len(sr.StringFilter)+len(sr.RangeFilter)+len(sr.PathFilter) == 0) {
+			statsIds = nil
+		}
+		ret = ws.facetHandler.AddStats(sr.Stats, statsIds, ret)
+	}
 	return enc.Encode(ret)
 }
 
@@ -94,11 +119,13 @@ func (ws *app) SearchStreamed(w http.ResponseWriter, r *http.Request, sessionId
 
 	qm.Wait()
 
-	sortedItemsItr := ws.sortingHandler.GetSortedItemsIterator(sessionId, sr.Sort, ids, start)
-
+	sortSession := sessionId
+	if sr.SkipPersonalization {
+		sortSession = 0
+	}
 	idx := 0
 
-	for item := range ws.itemIndex.GetItems(sortedItemsItr) {
+	for item := range ws.pageItems(sortSession, sr, ids, start) {
 		idx++
 
 		_, err = item.Write(w)
@@ -118,7 +145,8 @@ func (ws *app) SearchStreamed(w http.ResponseWriter, r *http.Request, sessionId
 	l := ids.Len()
 
 	if ws.tracker != nil && !sr.SkipTracking {
-		go ws.tracker.TrackSearch(sessionId, sr.Filters, l, sr.Query, sr.Page, r)
+		assignment := ws.sortingHandler.ExperimentAssignment(sortSession, sr.Sort)
+		go ws.tracker.TrackSearch(sessionId, sr.Filters, l, sr.Query, sr.Page, assignment, r)
 	}
 
 	return enc.Encode(SearchResponse{
@@ -132,6 +160,41 @@ func (ws *app) SearchStreamed(w http.ResponseWriter, r *http.Request, sessionId
 	})
 }
 
+// pageItems yields the sorted items from start, the first results of the
+// popular sort are re-ranked for diversity when enabled for the request
+func (ws *app) pageItems(sessionId int, sr *types.SearchRequest, ids *types.ItemList, start int) iter.Seq[types.Item] {
+	diversity := types.CurrentSettings.GetDiversity()
+	strength := sr.DiversityStrength(diversity)
+	if strength == 0 || sr.Sort != "popular" || start >= diversity.Window || len(diversity.FacetIds) == 0 {
+		return ws.itemIndex.GetItems(ws.sortingHandler.GetSortedItemsIterator(sessionId, sr.Sort, ids, start))
+	}
+	return func(yield func(types.Item) bool) {
+		head := make([]types.Item, 0, diversity.Window)
+		for item := range ws.itemIndex.GetItems(ws.sortingHandler.GetSortedItemsIterator(sessionId, sr.Sort, ids, 0)) {
+			head = append(head, item)
+			if len(head) == diversity.Window {
+				break
+			}
+		}
+		if start >= len(head) {
+			return
+		}
+		for _, item := range sorting.Diversify(head, diversity.FacetIds, strength)[start:] {
+			if !yield(item) {
+				return
+			}
+		}
+		if len(head) < diversity.Window {
+			return
+		}
+		for item := range ws.itemIndex.GetItems(ws.sortingHandler.GetSortedItemsIterator(sessionId, sr.Sort, ids, diversity.Window)) {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
 // func (a *app) UpdateSort(w http.ResponseWriter, r *http.Request, sessionId int, enc *json.Encoder) error {
 // 	go a.sortingHandler.UpdateSorts()
 // 	w.WriteHeader(http.StatusOK)
@@ -206,7 +269,8 @@ func (ws *app) Related(w http.ResponseWriter, r *http.Request, sessionId int, en
 	i := 0
 	related := <-relatedChan
 
-	for item := range ws.itemIndex.GetItems(ws.sortingHandler.GetSortedItemsIterator(sessionId, "popular", related, 0)) {
+	// the response is cached publicly, so it can't be sorted for the session
+	for item := range ws.itemIndex.GetItems(ws.sortingHandler.GetSortedItemsIterator(0, "popular", related, 0)) {
 		if ok && item.GetId() != types.ItemId(id64) {
 			_, err = item.Write(w)
 			i++
@@ -267,7 +331,8 @@ func (ws *app) Compatible(w http.ResponseWriter, r *http.Request, sessionId int,
 	}
 	i := 0
 
-	for item := range ws.itemIndex.GetItems(ws.sortingHandler.GetSortedItemsIterator(sessionId, "popular", related, 0)) {
+	// the response is cached publicly, so it can't be sorted for the session
+	for item := range ws.itemIndex.GetItems(ws.sortingHandler.GetSortedItemsIterator(0, "popular", related, 0)) {
 
 		if len(excludedProductTypes) > 0 {
 			if productType, typeOk := item.GetStringFieldValue(types.CurrentSettings.ProductTypeId); typeOk {
@@ -307,6 +372,50 @@ func (ws *app) GetValues(w http.ResponseWriter, r *http.Request, sessionId int,
 	return nil
 }
 
+// SearchValues pages the values of a key facet with counts for the items
+// matching the request, filtered by the q text
+func (ws *app) SearchValues(w http.ResponseWriter, r *http.Request, sessionId int, enc *json.Encoder) error {
+	idString := r.PathValue("id")
+	id64, err := strconv.ParseUint(idString, 10, 64)
+	if err != nil {
+		return err
+	}
+	if id64 > uint64(^uint(0)) {
+		return fmt.Errorf("facet id out of range")
+	}
+	id := types.FacetId(id64)
+	keyField, ok := ws.facetHandler.GetKeyFacet(id)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return nil
+	}
+	sr, err := types.GetValueSearchFromRequest(r)
+	if err != nil {
+		return err
+	}
+	baseIds := ws.getValueBaseIds(r.Context(), id, sr.FacetRequest)
+	result := facet.SearchValues(keyField.BaseField, keyField.CountValues(baseIds), sr.Q, sr.Page, sr.PageSize)
+	publicHeaders(w, r, true, "600")
+	w.WriteHeader(http.StatusOK)
+	return enc.Encode(result)
+}
+
+// getValueBaseIds returns the items matching the request without the filter on
+// the facet itself, nil when the request has nothing to filter on
+func (ws *app) getValueBaseIds(ctx context.Context, id types.FacetId, sr *types.FacetRequest) *types.ItemList {
+	filters := sr.WithOut(id, false)
+	if sr.Query == "" && len(sr.Stock) == 0 && len(filters.StringFilter) == 0 && len(filters.RangeFilter) == 0 && len(filters.PathFilter) == 0 {
+		return nil
+	}
+	ids := &types.ItemList{}
+	qm := types.NewQueryMerger(ctx, ids)
+	ws.searchIndex.MatchQuery(sr.Query, qm)
+	ws.itemIndex.MatchStock(sr.Stock, qm)
+	ws.facetHandler.Match(filters, qm)
+	qm.Wait()
+	return ids
+}
+
 func (ws *app) Facets(w http.ResponseWriter, r *http.Request, sessionId int, enc *json.Encoder) error {
 	//publicHeaders(w, r, true, "1200")
 
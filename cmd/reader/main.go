@@ -72,6 +72,9 @@ func main() {
 	}
 	itemIndex := index.NewIndexWithStock()
 	sortingHandler := sorting.NewSortingItemHandler(itemPopularity)
+	if events, err := diskStorage.LoadSortOverride(types.EventPopularityKey); err == nil {
+		sortingHandler.HandleSortOverrideUpdate(types.SortOverrideUpdate{Key: types.EventPopularityKey, Data: *events})
+	}
 	searchHandler := search.NewFreeTextItemHandler(search.DefaultFreeTextHandlerOptions())
 	facets := []types.StorageFacet{}
 	fieldPopularity, err := diskStorage.LoadSortOverride("popular-fields")
@@ -83,6 +86,8 @@ func main() {
 		log.Printf("Could not load facets from storage: %v", err)
 	}
 	facetHandler := facet.NewFacetItemHandler(facets, fieldPopularity)
+	sortingHandler.SetFieldSource(facetHandler)
+	sortingHandler.SetItemSource(itemIndex)
 
 	app := &app{
 		country:        country,
@@ -106,6 +111,7 @@ func main() {
 	go func() {
 		wg.Wait()
 		loading = false
+		sortingHandler.SetExperiment(types.CurrentSettings.GetExperiment(), itemIndex.GetAllItems())
 		sortingHandler.UpdateSorts()
 		log.Printf("Finished loading items, now serving requests")
 		if ok {
@@ -157,6 +163,7 @@ func main() {
 	mux.HandleFunc("GET /api/related/{id}", common.JsonHandler(tracker, app.Related))
 	mux.HandleFunc("/api/compatible/{id}", common.JsonHandler(tracker, app.Compatible))
 	mux.HandleFunc("GET /api/values/{id}", common.JsonHandler(tracker, app.GetValues))
+	mux.HandleFunc("GET /api/values/{id}/search", common.JsonHandler(tracker, app.SearchValues))
 	mux.HandleFunc("GET /api/suggest", common.JsonHandler(tracker, app.Suggest))
 	mux.HandleFunc("GET /api/popular", common.JsonHandler(tracker, app.Popular))
 	mux.HandleFunc("GET /api/save-trigger", common.JsonHandler(tracker, app.SaveTrigger))
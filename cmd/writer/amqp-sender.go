@@ -34,6 +34,10 @@ func (app *AmqpSender) SendSettingsChange(item types.SettingsChange) error {
 	return messaging.SendChange(app.connection, app.Country, "settings_change", item)
 }
 
+func (app *AmqpSender) SendSortOverride(item types.SortOverrideUpdate) error {
+	return messaging.SendChange(app.connection, "global", "sort_override", item)
+}
+
 func (app *AmqpSender) defineTopics() {
 	ch, err := app.connection.Channel()
 	if err != nil {
@@ -49,4 +53,7 @@ func (app *AmqpSender) defineTopics() {
 	if err := messaging.DefineTopic(ch, app.Country, "settings_change"); err != nil {
 		log.Fatalf("Failed to declare topic settings_change: %v", err)
 	}
+	if err := messaging.DefineTopic(ch, "global", "sort_override"); err != nil {
+		log.Fatalf("Failed to declare topic sort_override: %v", err)
+	}
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"slices"
@@ -13,7 +14,7 @@ import (
 
 // getFieldType safely converts an internal int field type to uint while:
 // 1. Preventing negative values (which would underflow to huge uints)
-// 2. Validating against the set of supported facet field types (1=key,2=decimal,3=integer)
+// 2. Validating against the set of supported facet field types (1=key,2=decimal,3=integer,4=tree,5=date,6=bool)
 // 3. Avoiding triggering gosec G115 (int -> uint potential overflow) by explicit checks
 // Accept either facet.FieldType or its underlying int (DataType) for flexibility
 // T can be an int-like or uint-like underlying type (writer DataType likely int, facet.FieldType is uint)
@@ -22,7 +23,7 @@ func getFieldType[T ~int | ~uint](v T) (uint, bool) {
 		return 0, false
 	}
 	switch v { // enumerate allowed types; adjust if new types introduced
-	case 1, 2, 3:
+	case types.FacetKeyType, types.FacetNumberType, types.FacetIntegerType, types.FacetTreeType, types.FacetDateType, types.FacetBoolType:
 		return uint(v), true
 	default:
 		return 0, false
@@ -155,13 +156,60 @@ func (ws *app) findFacet(id types.FacetId) (*types.StorageFacet, bool) {
 	return nil, false
 }
 
+// ensureClusterFacet creates the hidden key facet the embeddings service writes
+// the similarity cluster of each item to, nothing is sent when it exists
+func (ws *app) ensureClusterFacet(id types.FacetId) error {
+	if _, found := ws.findFacet(id); found {
+		return nil
+	}
+	baseField := &types.BaseField{
+		Id:           id,
+		Name:         "Cluster",
+		Description:  "Similarity cluster from embeddings",
+		Searchable:   true,
+		HideFacet:    true,
+		InternalOnly: true,
+	}
+	ws.mu.Lock()
+	ws.storageFacets = append(ws.storageFacets, types.StorageFacet{
+		BaseField: baseField,
+		Type:      types.FacetKeyType,
+	})
+	err := ws.storage.SaveFacets(&ws.storageFacets)
+	ws.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	log.Printf("Created cluster facet %d", id)
+	return ws.amqpSender.SendFacetChanges(types.FieldChange{
+		Action:    types.ADD_FIELD,
+		BaseField: baseField,
+		FieldType: types.FacetKeyType,
+	})
+}
+
 func (ws *app) CreateFacetFromField(w http.ResponseWriter, r *http.Request) {
 	//defaultHeaders(w, r, true, "0")
 	fieldId := r.PathValue("id")
+	// ?property=ReleaseDate sources the values from an item property, the
+	// facet id does not have to be a known field then
+	property := r.URL.Query().Get("property")
 	field, ok := ws.fieldData[fieldId]
 	if !ok {
-		http.Error(w, "Field not found", http.StatusNotFound)
-		return
+		if property == "" {
+			http.Error(w, "Field not found", http.StatusNotFound)
+			return
+		}
+		id, err := strconv.ParseUint(fieldId, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid facet ID", http.StatusBadRequest)
+			return
+		}
+		field = FieldData{
+			Id:   uint32(id),
+			Name: property,
+			Type: types.FacetKeyType,
+		}
 	}
 	_, found := ws.findFacet(types.FacetId(field.Id))
 	if found {
@@ -174,11 +222,22 @@ func (ws *app) CreateFacetFromField(w http.ResponseWriter, r *http.Request) {
 		Id:          types.FacetId(field.Id),
 		Priority:    10,
 		Searchable:  true,
+		Property:    property,
 	}
 	if slices.Index(field.Purpose, "do not show") != -1 {
 		baseField.HideFacet = true
 	}
-	ft, okType := getFieldType(field.Type)
+	fieldType := field.Type
+	// the facet type can be overridden, e.g. ?type=4 to index a path field as a tree
+	if typeString := r.URL.Query().Get("type"); typeString != "" {
+		t, err := strconv.Atoi(typeString)
+		if err != nil {
+			http.Error(w, "Invalid field type", http.StatusBadRequest)
+			return
+		}
+		fieldType = t
+	}
+	ft, okType := getFieldType(fieldType)
 	if !okType {
 		http.Error(w, "Invalid field type", http.StatusBadRequest)
 		return
@@ -291,6 +350,73 @@ func (ws *app) UpdateFacet(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// FacetValueRules holds the value normalization and display names of a facet
+type FacetValueRules struct {
+	Normalize  *types.ValueNormalization `json:"normalize,omitempty"`
+	ValueNames map[string]string         `json:"valueNames,omitempty"`
+}
+
+// HandleFacetValueRules reads or replaces the value rules of a facet, changed
+// normalization applies to values indexed after the change
+func (ws *app) HandleFacetValueRules(w http.ResponseWriter, r *http.Request) {
+	facetId64, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil || facetId64 > uint64(^uint(0)) {
+		http.Error(w, "Invalid facet ID", http.StatusBadRequest)
+		return
+	}
+	facet, ok := ws.findFacet(types.FacetId(facetId64))
+	if !ok || facet.BaseField == nil {
+		http.Error(w, "Facet not found", http.StatusNotFound)
+		return
+	}
+	current := facet.BaseField
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		data := FacetValueRules{}
+		if err = json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if data.Normalize != nil {
+			switch data.Normalize.Case {
+			case types.CaseKeep, types.CaseLower, types.CaseUpper, types.CaseTitle, types.CaseFold:
+			default:
+				http.Error(w, fmt.Sprintf("unknown case mode %q", data.Normalize.Case), http.StatusBadRequest)
+				return
+			}
+		}
+		ws.mu.Lock()
+		current.Normalize = data.Normalize
+		current.ValueNames = data.ValueNames
+		ws.mu.Unlock()
+		if err = ws.storage.SaveFacets(&ws.storageFacets); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ft, okType := getFieldType(facet.Type)
+		if !okType {
+			http.Error(w, "Invalid facet type", http.StatusInternalServerError)
+			return
+		}
+		change := types.FieldChange{
+			Action:    types.UPDATE_FIELD,
+			BaseField: current,
+			FieldType: ft,
+		}
+		if err = ws.amqpSender.SendFacetChanges(change); err != nil {
+			log.Printf("Could not send facet changes: %v", err)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(FacetValueRules{
+		Normalize:  current.Normalize,
+		ValueNames: current.ValueNames,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (ws *app) MissingFacets(w http.ResponseWriter, r *http.Request) {
 	//defaultHeaders(w, r, true, "0")
 	w.WriteHeader(http.StatusOK)
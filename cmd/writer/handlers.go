@@ -2,9 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/matst80/slask-finder/pkg/embeddings"
 	"github.com/matst80/slask-finder/pkg/types"
 )
 
@@ -178,3 +182,266 @@ func (ws *app) HandleFacetGroups(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
+
+func (ws *app) HandleEmbeddingsTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		templates := map[string]types.EmbeddingsTemplate{}
+		err := json.NewDecoder(r.Body).Decode(&templates)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for key, tmpl := range templates {
+			if _, err = embeddings.ParseTemplate(tmpl.Template); err != nil {
+				http.Error(w, fmt.Sprintf("template %s: %v", key, err), http.StatusBadRequest)
+				return
+			}
+		}
+		types.CurrentSettings.Lock()
+		types.CurrentSettings.EmbeddingsTemplates = templates
+		types.CurrentSettings.Unlock()
+		err = ws.storage.SaveSettings()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = ws.amqpSender.SendSettingsChange(types.SettingsChange{
+			Type:  "embeddingsTemplates",
+			Value: templates,
+		})
+		if err != nil {
+			log.Printf("Failed to send settings change: %v", err)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	types.CurrentSettings.RLock()
+	defer types.CurrentSettings.RUnlock()
+	err := json.NewEncoder(w).Encode(types.CurrentSettings.EmbeddingsTemplates)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (ws *app) HandleFacetProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		profiles := []types.FacetProfile{}
+		err := json.NewDecoder(r.Body).Decode(&profiles)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for idx, profile := range profiles {
+			if profile.Category == "" {
+				http.Error(w, fmt.Sprintf("profile %d: missing category", idx), http.StatusBadRequest)
+				return
+			}
+		}
+		types.CurrentSettings.Lock()
+		types.CurrentSettings.FacetProfiles = profiles
+		types.CurrentSettings.Unlock()
+		err = ws.storage.SaveSettings()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = ws.amqpSender.SendSettingsChange(types.SettingsChange{
+			Type:  "facetProfiles",
+			Value: profiles,
+		})
+		if err != nil {
+			log.Printf("Failed to send settings change: %v", err)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(types.CurrentSettings.GetFacetProfiles())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (ws *app) HandleSessionBoost(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		boost := &types.SessionBoost{}
+		err := json.NewDecoder(r.Body).Decode(boost)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if boost.Weight < 0 {
+			http.Error(w, "weight can not be negative", http.StatusBadRequest)
+			return
+		}
+		if len(boost.FacetIds) == 0 {
+			boost = nil
+		}
+		types.CurrentSettings.Lock()
+		types.CurrentSettings.SessionBoost = boost
+		types.CurrentSettings.Unlock()
+		err = ws.storage.SaveSettings()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = ws.amqpSender.SendSettingsChange(types.SettingsChange{
+			Type:  "sessionBoost",
+			Value: boost,
+		})
+		if err != nil {
+			log.Printf("Failed to send settings change: %v", err)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(types.CurrentSettings.GetSessionBoost())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (ws *app) HandleDiversity(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		diversity := &types.Diversity{}
+		err := json.NewDecoder(r.Body).Decode(diversity)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if diversity.Strength < 0 || diversity.Strength > 1 {
+			http.Error(w, "strength must be between 0 and 1", http.StatusBadRequest)
+			return
+		}
+		if diversity.Window < 0 {
+			http.Error(w, "window can not be negative", http.StatusBadRequest)
+			return
+		}
+		types.CurrentSettings.Lock()
+		types.CurrentSettings.Diversity = diversity
+		types.CurrentSettings.Unlock()
+		err = ws.storage.SaveSettings()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = ws.amqpSender.SendSettingsChange(types.SettingsChange{
+			Type:  "diversity",
+			Value: diversity,
+		})
+		if err != nil {
+			log.Printf("Failed to send settings change: %v", err)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(types.CurrentSettings.GetDiversity())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type expressionValidation struct {
+	Valid    bool   `json:"valid"`
+	Error    string `json:"error,omitempty"`
+	Position int    `json:"position,omitempty"`
+}
+
+// ValidateExpression compiles an ExpressionRule expression without saving it
+func (ws *app) ValidateExpression(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Expression string `json:"expression"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result := expressionValidation{Valid: true}
+	status := http.StatusOK
+	if _, err = types.CompileExpression(request.Expression); err != nil {
+		result = expressionValidation{Error: err.Error()}
+		var exprErr *types.ExpressionError
+		if errors.As(err, &exprErr) {
+			result.Error = exprErr.Message
+			result.Position = exprErr.Position
+		}
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (ws *app) GetExperiment(w http.ResponseWriter, r *http.Request) {
+	experiment := types.CurrentSettings.GetExperiment()
+	if experiment == nil {
+		http.Error(w, "no experiment running", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(experiment)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// setExperiment saves the running experiment and notifies the readers
+func (ws *app) setExperiment(experiment *types.Experiment) error {
+	types.CurrentSettings.Lock()
+	types.CurrentSettings.Experiment = experiment
+	types.CurrentSettings.Unlock()
+	err := ws.storage.SaveSettings()
+	if err != nil {
+		return err
+	}
+	err = ws.amqpSender.SendSettingsChange(types.SettingsChange{
+		Type:  "experiment",
+		Value: experiment,
+	})
+	if err != nil {
+		log.Printf("Failed to send settings change: %v", err)
+	}
+	return nil
+}
+
+// StartExperiment replaces the running experiment
+func (ws *app) StartExperiment(w http.ResponseWriter, r *http.Request) {
+	experiment := &types.Experiment{}
+	err := json.NewDecoder(r.Body).Decode(experiment)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = experiment.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	experiment.Started = time.Now().Unix()
+	if err = ws.setExperiment(experiment); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Started experiment %s", experiment.Id)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(experiment)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (ws *app) StopExperiment(w http.ResponseWriter, r *http.Request) {
+	if types.CurrentSettings.GetExperiment() == nil {
+		http.Error(w, "no experiment running", http.StatusNotFound)
+		return
+	}
+	if err := ws.setExperiment(nil); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"sync"
 	"time"
 
@@ -21,15 +22,27 @@ type app struct {
 	storageFacets []types.StorageFacet
 	storage       *storage.DiskStorage
 	amqpSender    *AmqpSender
+	snapshot      *itemSnapshot
 }
 
 var country = "se"
 
+// clusterFacetId is the facet of the embeddings clustering, 0 when not used
+var clusterFacetId = types.FacetId(0)
+
 func init() {
 	c, ok := os.LookupEnv("COUNTRY")
 	if ok {
 		country = c
 	}
+	if v, ok := os.LookupEnv("CLUSTER_FACET_ID"); ok {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			log.Printf("Invalid CLUSTER_FACET_ID %s: %v", v, err)
+			return
+		}
+		clusterFacetId = types.FacetId(id)
+	}
 }
 
 func main() {
@@ -58,6 +71,7 @@ func main() {
 		fieldData:     make(map[string]FieldData),
 		storageFacets: make([]types.StorageFacet, 3000),
 		amqpSender:    NewAmqpSender(country, conn),
+		snapshot:      &itemSnapshot{},
 		// itemIndex:       idx,
 		// embeddingsIndex: embeddingsIndex,
 		storage: diskStorage,
@@ -74,6 +88,12 @@ func main() {
 	if err != nil {
 		log.Printf("Could not load facets from file: %v", err)
 	}
+	if clusterFacetId != 0 {
+		if err = app.ensureClusterFacet(clusterFacetId); err != nil {
+			log.Printf("Could not create cluster facet: %v", err)
+		}
+	}
+	app.startPopularity(conn)
 	srv := http.NewServeMux()
 
 	srv.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -100,13 +120,23 @@ func main() {
 	srv.HandleFunc("GET /admin/facets", app.GetFacetList)
 	srv.HandleFunc("DELETE /admin/facets/{id}", auth.Middleware(app.DeleteFacet))
 	srv.HandleFunc("PUT /admin/facets/{id}", auth.Middleware(app.UpdateFacet))
+	srv.HandleFunc("/admin/facets/{id}/values", auth.Middleware(app.HandleFacetValueRules))
 
 	srv.HandleFunc("GET /admin/settings", auth.Middleware(app.GetSettings))
 	srv.HandleFunc("PUT /admin/settings", auth.Middleware(app.UpdateSettings))
 	srv.HandleFunc("/admin/words", auth.Middleware(app.HandleWordReplacements))
 	srv.HandleFunc("/admin/rules/popular", auth.Middleware(app.HandlePopularRules))
+	srv.HandleFunc("POST /admin/rules/expression", auth.Middleware(app.ValidateExpression))
+	srv.HandleFunc("POST /admin/rules/popular/simulate", auth.Middleware(app.SimulatePopularRules))
 	srv.HandleFunc("POST /admin/relation-groups", auth.Middleware(app.SaveHandleRelationGroups))
 	srv.HandleFunc("/facet-groups", auth.Middleware(app.HandleFacetGroups))
+	srv.HandleFunc("/admin/embeddings-templates", auth.Middleware(app.HandleEmbeddingsTemplates))
+	srv.HandleFunc("/admin/facet-profiles", auth.Middleware(app.HandleFacetProfiles))
+	srv.HandleFunc("/admin/session-boost", auth.Middleware(app.HandleSessionBoost))
+	srv.HandleFunc("/admin/diversity", auth.Middleware(app.HandleDiversity))
+	srv.HandleFunc("GET /admin/experiment", auth.Middleware(app.GetExperiment))
+	srv.HandleFunc("POST /admin/experiment", auth.Middleware(app.StartExperiment))
+	srv.HandleFunc("DELETE /admin/experiment", auth.Middleware(app.StopExperiment))
 
 	srv.HandleFunc("GET /admin/fields", auth.Middleware(app.GetFields))
 	srv.HandleFunc("PUT /admin/fields", auth.Middleware(app.HandleUpdateFields))
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/matst80/slask-finder/pkg/tracking"
+	"github.com/matst80/slask-finder/pkg/types"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const popularityStateFile = "popularity-state.json"
+
+// startPopularity aggregates clicks, carts and purchases from the tracking
+// topic and publishes the decayed scores as the event popularity override,
+// the readers add it to the popular override. The aggregation is enabled by
+// POPULARITY_INTERVAL, the publish schedule, and should only run on one
+// writer. POPULARITY_DECAY sets the events like purchase=20:168h. The scores
+// are stored every interval and restored on start. With the session boost
+// enabled the interactions of the sessions are published as session-<id>
+// overrides with the same event weights
+func (ws *app) startPopularity(conn *amqp.Connection) {
+	value, ok := os.LookupEnv("POPULARITY_INTERVAL")
+	if !ok {
+		return
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid POPULARITY_INTERVAL %s: %v", value, err)
+		return
+	}
+	if interval <= 0 {
+		log.Printf("Popularity aggregation disabled")
+		return
+	}
+	decay, err := tracking.ParsePopularityDecay(os.Getenv("POPULARITY_DECAY"))
+	if err != nil {
+		log.Printf("Invalid POPULARITY_DECAY: %v", err)
+		return
+	}
+	aggregator := tracking.NewPopularityAggregator(decay)
+	var state []tracking.PopularityState
+	if err = ws.storage.LoadJson(&state, popularityStateFile); err == nil {
+		aggregator.Restore(state)
+		log.Printf("Restored popularity for %d item events", len(state))
+	} else if !os.IsNotExist(err) {
+		log.Printf("Could not load popularity state: %v", err)
+	}
+	err = aggregator.Connect(conn, interval, func(update types.SortOverrideUpdate) error {
+		if update.Key == types.EventPopularityKey {
+			if err := ws.storage.SaveJson(aggregator.State(), popularityStateFile); err != nil {
+				log.Printf("Failed to save popularity state: %v", err)
+			}
+			scores := types.SortOverride(update.Data)
+			if err := ws.storage.SaveSortOverride(types.EventPopularityKey, &scores); err != nil {
+				log.Printf("Failed to save popularity: %v", err)
+			}
+		}
+		return ws.amqpSender.SendSortOverride(update)
+	})
+	if err != nil {
+		log.Printf("Failed to connect popularity aggregation: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/matst80/slask-finder/pkg/facet"
+	"github.com/matst80/slask-finder/pkg/index"
+	"github.com/matst80/slask-finder/pkg/search"
+	"github.com/matst80/slask-finder/pkg/sorting"
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+// ItemSnapshotTTL is how long the items loaded for rule simulations are reused
+const ItemSnapshotTTL = 15 * time.Minute
+
+// simulationIndex indexes the stored items like a reader so the simulations
+// match the same items as a search
+type simulationIndex struct {
+	items  *index.ItemIndexWithStock
+	facets *facet.FacetItemHandler
+	search *search.FreeTextItemHandler
+}
+
+// itemSnapshot keeps the index of the stored items for rule simulations
+type itemSnapshot struct {
+	loadMu sync.Mutex
+	index  *simulationIndex
+	loaded time.Time
+}
+
+// getIndex returns the index of the stored items, loading them again when the
+// snapshot is older than ItemSnapshotTTL
+func (ws *app) getIndex() (*simulationIndex, error) {
+	s := ws.snapshot
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+	if s.index != nil && time.Since(s.loaded) < ItemSnapshotTTL {
+		return s.index, nil
+	}
+	ws.mu.RLock()
+	facets := slices.Clone(ws.storageFacets)
+	ws.mu.RUnlock()
+	idx := &simulationIndex{
+		items:  index.NewIndexWithStock(),
+		facets: facet.NewFacetItemHandler(facets, nil),
+		search: search.NewFreeTextItemHandler(search.DefaultFreeTextHandlerOptions()),
+	}
+	wg := &sync.WaitGroup{}
+	err := ws.storage.LoadItems(wg, idx.items, idx.facets, idx.search)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	s.index = idx
+	s.loaded = time.Now()
+	log.Printf("Indexed items for rule simulations")
+	return idx, nil
+}
+
+// matchingItems returns the items matching the query, stock and filters of
+// the request with the same matching as a search
+func (idx *simulationIndex) matchingItems(ctx context.Context, req *types.FacetRequest) iter.Seq[types.Item] {
+	if req.Query == "" && len(req.Stock) == 0 && (req.Filters == nil ||
+		len(req.StringFilter)+len(req.RangeFilter)+len(req.PathFilter) == 0) {
+		return idx.items.GetAllItems()
+	}
+	ids := &types.ItemList{}
+	qm := types.NewQueryMerger(ctx, ids)
+	idx.search.MatchQuery(req.Query, qm)
+	idx.items.MatchStock(req.Stock, qm)
+	if req.Filters != nil {
+		idx.facets.Match(req.Filters, qm)
+	}
+	qm.Wait()
+	return idx.items.GetItems(func(yield func(types.ItemId) bool) {
+		ids.ForEach(func(id uint32) bool {
+			return yield(types.ItemId(id))
+		})
+	})
+}
+
+type ruleSimulationRequest struct {
+	*types.FacetRequest
+	Rules types.JsonTypes `json:"rules"`
+	Top   int             `json:"top"`
+}
+
+// SimulatePopularRules compares the popular order of the current rules with
+// candidate rules for the items matching the query and filters, nothing is saved
+func (ws *app) SimulatePopularRules(w http.ResponseWriter, r *http.Request) {
+	req := &ruleSimulationRequest{
+		FacetRequest: &types.FacetRequest{Filters: &types.Filters{}},
+	}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Sanitize()
+	if len(req.Rules) == 0 {
+		http.Error(w, "no rules to simulate", http.StatusBadRequest)
+		return
+	}
+	proposed := make(types.ItemPopularityRules, 0, len(req.Rules))
+	for _, rule := range req.Rules {
+		v, ok := rule.(types.ItemPopularityRule)
+		if !ok {
+			http.Error(w, fmt.Sprintf("%s is not a popularity rule", rule.Type()), http.StatusBadRequest)
+			return
+		}
+		proposed = append(proposed, v)
+	}
+	var current types.ItemPopularityRules
+	types.CurrentSettings.RLock()
+	if rules := types.CurrentSettings.PopularityRules; rules != nil {
+		current = slices.Clone(*rules)
+	}
+	types.CurrentSettings.RUnlock()
+	// the readers add the event popularity to the popular override
+	overrides := make([]types.SortOverride, 0, 2)
+	for _, key := range []string{"popular", types.EventPopularityKey} {
+		if stored, err := ws.storage.LoadSortOverride(key); err == nil && stored != nil {
+			overrides = append(overrides, *stored)
+		} else if err != nil && !os.IsNotExist(err) {
+			log.Printf("Could not load %s override, simulating without it: %v", key, err)
+		}
+	}
+	override := types.MergeSortOverrides(overrides...)
+	idx, err := ws.getIndex()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not load items: %v", err), http.StatusInternalServerError)
+		return
+	}
+	impact := sorting.SimulateRuleImpact(idx.matchingItems(r.Context(), req.FacetRequest), current, proposed, override, req.Top)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(impact)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,363 @@
+package embeddings
+
+import (
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+// ClusterOptions configures the k-means clustering and duplicate detection job
+type ClusterOptions struct {
+	Clusters           int     `json:"clusters"`           // Number of clusters, 0 picks one from the number of items
+	Dimensions         int     `json:"dimensions"`         // Length of the vectors to cluster, 0 uses the most common length
+	Iterations         int     `json:"iterations"`         // Max k-means iterations
+	DuplicateThreshold float64 `json:"duplicateThreshold"` // Min cosine similarity for a likely duplicate
+	DuplicateProbes    int     `json:"duplicateProbes"`    // Number of nearest clusters searched for duplicates of an item
+	MaxDuplicates      int     `json:"maxDuplicates"`      // Max number of pairs in the report, 0 for all
+	Seed               uint64  `json:"seed"`
+}
+
+func DefaultClusterOptions() ClusterOptions {
+	return ClusterOptions{
+		Clusters:           0,
+		Iterations:         20,
+		DuplicateThreshold: 0.97,
+		DuplicateProbes:    3,
+		MaxDuplicates:      5000,
+		Seed:               1,
+	}
+}
+
+// DuplicatePair is two items with embeddings similar enough to likely be the same product
+type DuplicatePair struct {
+	A          types.ItemId `json:"a"`
+	B          types.ItemId `json:"b"`
+	Similarity float64      `json:"similarity"`
+}
+
+// ClusterResult contains the cluster each item was assigned to and the likely duplicates
+type ClusterResult struct {
+	Clusters    int                  `json:"clusters"`
+	Dimensions  int                  `json:"dimensions"`
+	Skipped     int                  `json:"skipped"`
+	Iterations  int                  `json:"iterations"`
+	Assignments map[types.ItemId]int `json:"assignments"`
+	Duplicates  []DuplicatePair      `json:"duplicates"`
+}
+
+// ClusterSizes returns the number of items in each cluster
+func (r *ClusterResult) ClusterSizes() []int {
+	sizes := make([]int, r.Clusters)
+	for _, c := range r.Assignments {
+		sizes[c]++
+	}
+	return sizes
+}
+
+func normalized(v types.Embeddings) types.Embeddings {
+	var sum float64
+	for _, f := range v {
+		sum += float64(f * f)
+	}
+	if sum == 0 {
+		return nil
+	}
+	norm := float32(1 / math.Sqrt(sum))
+	res := make(types.Embeddings, len(v))
+	for i, f := range v {
+		res[i] = f * norm
+	}
+	return res
+}
+
+func dot(a, b types.Embeddings) float64 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return float64(sum)
+}
+
+// parallel runs fn for each index in [0, n) spread over the available cpus
+func parallel(n int, fn func(i int)) {
+	workers := min(runtime.NumCPU(), n)
+	wg := sync.WaitGroup{}
+	for w := range workers {
+		wg.Add(1)
+		go func(offset int) {
+			defer wg.Done()
+			for i := offset; i < n; i += workers {
+				fn(i)
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// mostCommonLength returns the most common vector length, the shortest on ties
+func mostCommonLength(data map[types.ItemId]types.Embeddings) int {
+	counts := make(map[int]int)
+	for _, v := range data {
+		if len(v) > 0 {
+			counts[len(v)]++
+		}
+	}
+	dim, count := 0, 0
+	for l, c := range counts {
+		if c > count || (c == count && l < dim) {
+			dim, count = l, c
+		}
+	}
+	return dim
+}
+
+// ClusterEmbeddings groups the embeddings with spherical k-means (cosine
+// similarity) and reports item pairs above the duplicate threshold within the
+// nearest clusters of each item, vectors of another length are skipped
+func ClusterEmbeddings(data map[types.ItemId]types.Embeddings, opts ClusterOptions) *ClusterResult {
+	ids := make([]types.ItemId, 0, len(data))
+	vectors := make([]types.Embeddings, 0, len(data))
+	dim := opts.Dimensions
+	if dim <= 0 {
+		dim = mostCommonLength(data)
+	}
+	for id, v := range data {
+		if len(v) == 0 || len(v) != dim {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	// sorted ids makes the result stable for the same input and seed
+	slices.Sort(ids)
+	for _, id := range ids {
+		vectors = append(vectors, normalized(data[id]))
+	}
+	result := &ClusterResult{
+		Dimensions:  dim,
+		Skipped:     len(data) - len(ids),
+		Assignments: make(map[types.ItemId]int, len(ids)),
+		Duplicates:  []DuplicatePair{},
+	}
+	if len(ids) == 0 {
+		return result
+	}
+
+	k := opts.Clusters
+	if k <= 0 {
+		k = int(math.Sqrt(float64(len(ids)) / 2))
+	}
+	k = max(1, min(k, len(ids)))
+	result.Clusters = k
+
+	rnd := rand.New(rand.NewPCG(opts.Seed, uint64(len(ids))))
+	centroids := initCentroids(vectors, k, rnd)
+	assignments := make([]int, len(vectors))
+	for i := range assignments {
+		assignments[i] = -1
+	}
+
+	for result.Iterations < max(1, opts.Iterations) {
+		result.Iterations++
+		changed := make([]bool, len(vectors))
+		parallel(len(vectors), func(i int) {
+			v := vectors[i]
+			if v == nil {
+				if assignments[i] != 0 {
+					assignments[i] = 0
+					changed[i] = true
+				}
+				return
+			}
+			best, bestSim := 0, math.Inf(-1)
+			for c, centroid := range centroids {
+				if sim := dot(v, centroid); sim > bestSim {
+					best, bestSim = c, sim
+				}
+			}
+			if assignments[i] != best {
+				assignments[i] = best
+				changed[i] = true
+			}
+		})
+		if !slices.Contains(changed, true) {
+			break
+		}
+		centroids = updateCentroids(vectors, assignments, centroids, dim)
+	}
+
+	members := make([][]int, k)
+	for i, c := range assignments {
+		result.Assignments[ids[i]] = c
+		members[c] = append(members[c], i)
+	}
+
+	if opts.DuplicateThreshold > 0 {
+		probes := nearestClusters(vectors, assignments, centroids, max(1, opts.DuplicateProbes))
+		result.Duplicates = findDuplicates(ids, vectors, members, probes, opts.DuplicateThreshold)
+		if opts.MaxDuplicates > 0 && len(result.Duplicates) > opts.MaxDuplicates {
+			result.Duplicates = result.Duplicates[:opts.MaxDuplicates]
+		}
+	}
+	return result
+}
+
+// initCentroids picks starting centroids with k-means++ seeding
+func initCentroids(vectors []types.Embeddings, k int, rnd *rand.Rand) []types.Embeddings {
+	centroids := make([]types.Embeddings, 0, k)
+	first := rnd.IntN(len(vectors))
+	centroids = append(centroids, vectors[first])
+	distances := make([]float64, len(vectors))
+	for i := range distances {
+		distances[i] = math.Inf(1)
+	}
+	for len(centroids) < k {
+		last := centroids[len(centroids)-1]
+		total := 0.0
+		for i, v := range vectors {
+			if v == nil || last == nil {
+				distances[i] = 0
+				continue
+			}
+			d := 1 - dot(v, last)
+			if d < distances[i] {
+				distances[i] = d
+			}
+			total += distances[i] * distances[i]
+		}
+		if total == 0 {
+			centroids = append(centroids, vectors[rnd.IntN(len(vectors))])
+			continue
+		}
+		target := rnd.Float64() * total
+		next := len(vectors) - 1
+		for i, d := range distances {
+			target -= d * d
+			if target <= 0 {
+				next = i
+				break
+			}
+		}
+		centroids = append(centroids, vectors[next])
+	}
+	return centroids
+}
+
+func updateCentroids(vectors []types.Embeddings, assignments []int, previous []types.Embeddings, dim int) []types.Embeddings {
+	sums := make([]types.Embeddings, len(previous))
+	for i, v := range vectors {
+		if v == nil {
+			continue
+		}
+		c := assignments[i]
+		if sums[c] == nil {
+			sums[c] = make(types.Embeddings, dim)
+		}
+		for j, f := range v {
+			sums[c][j] += f
+		}
+	}
+	centroids := make([]types.Embeddings, len(previous))
+	for c, sum := range sums {
+		if n := normalized(sum); n != nil {
+			centroids[c] = n
+		} else {
+			// keep empty clusters where they were
+			centroids[c] = previous[c]
+		}
+	}
+	return centroids
+}
+
+// nearestClusters returns the assigned cluster and the closest other
+// centroids of each item, up to n clusters per item
+func nearestClusters(vectors []types.Embeddings, assignments []int, centroids []types.Embeddings, n int) [][]int {
+	probes := make([][]int, len(vectors))
+	parallel(len(vectors), func(i int) {
+		own := assignments[i]
+		probes[i] = []int{own}
+		v := vectors[i]
+		if v == nil || n <= 1 {
+			return
+		}
+		others := make([]int, 0, len(centroids)-1)
+		sims := make([]float64, len(centroids))
+		for c, centroid := range centroids {
+			if c == own || centroid == nil {
+				continue
+			}
+			sims[c] = dot(v, centroid)
+			others = append(others, c)
+		}
+		slices.SortFunc(others, func(a, b int) int {
+			if sims[a] > sims[b] {
+				return -1
+			}
+			if sims[a] < sims[b] {
+				return 1
+			}
+			return a - b
+		})
+		probes[i] = append(probes[i], others[:min(n-1, len(others))]...)
+	})
+	return probes
+}
+
+// findDuplicates compares each item with the members of its probed clusters,
+// a pair is reported by the item with the lowest index unless only the other
+// item probes its cluster
+func findDuplicates(ids []types.ItemId, vectors []types.Embeddings, members [][]int, probes [][]int, threshold float64) []DuplicatePair {
+	found := make([][]DuplicatePair, len(vectors))
+	parallel(len(vectors), func(x int) {
+		a := vectors[x]
+		if a == nil {
+			return
+		}
+		own := probes[x][0]
+		for _, c := range probes[x] {
+			for _, y := range members[c] {
+				if y == x {
+					continue
+				}
+				if y < x && slices.Contains(probes[y], own) {
+					continue
+				}
+				b := vectors[y]
+				if b == nil {
+					continue
+				}
+				if sim := dot(a, b); sim >= threshold {
+					first, second := ids[x], ids[y]
+					if second < first {
+						first, second = second, first
+					}
+					found[x] = append(found[x], DuplicatePair{
+						A:          first,
+						B:          second,
+						Similarity: sim,
+					})
+				}
+			}
+		}
+	})
+	result := make([]DuplicatePair, 0)
+	for _, pairs := range found {
+		result = append(result, pairs...)
+	}
+	slices.SortFunc(result, func(a, b DuplicatePair) int {
+		if a.Similarity != b.Similarity {
+			if a.Similarity > b.Similarity {
+				return -1
+			}
+			return 1
+		}
+		if a.A != b.A {
+			return int(a.A) - int(b.A)
+		}
+		return int(a.B) - int(b.B)
+	})
+	return result
+}
@@ -0,0 +1,86 @@
+package embeddings
+
+import (
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func TestClusterEmbeddings(t *testing.T) {
+	data := map[types.ItemId]types.Embeddings{
+		1: {1, 0, 0},
+		2: {0.99, 0.01, 0},
+		3: {0.9, 0.1, 0},
+		4: {0, 1, 0},
+		5: {0, 0.98, 0.02},
+		6: {0, 0, 1},
+	}
+	result := ClusterEmbeddings(data, ClusterOptions{
+		Clusters:           3,
+		Iterations:         10,
+		DuplicateThreshold: 0.999,
+		Seed:               1,
+	})
+	if len(result.Assignments) != len(data) {
+		t.Fatalf("Expected %d assignments but got %d", len(data), len(result.Assignments))
+	}
+	if result.Assignments[1] != result.Assignments[2] || result.Assignments[1] != result.Assignments[3] {
+		t.Errorf("Expected items 1, 2 and 3 in the same cluster, got %v", result.Assignments)
+	}
+	if result.Assignments[4] != result.Assignments[5] {
+		t.Errorf("Expected items 4 and 5 in the same cluster, got %v", result.Assignments)
+	}
+	if result.Assignments[1] == result.Assignments[4] || result.Assignments[1] == result.Assignments[6] {
+		t.Errorf("Expected separate clusters, got %v", result.Assignments)
+	}
+	if len(result.Duplicates) != 2 {
+		t.Fatalf("Expected 2 duplicate pairs but got %v", result.Duplicates)
+	}
+	if result.Duplicates[0].A != 1 || result.Duplicates[0].B != 2 {
+		t.Errorf("Expected 1 and 2 as most similar pair, got %v", result.Duplicates[0])
+	}
+}
+
+func TestClusterEmbeddingsSkipsOtherLengths(t *testing.T) {
+	data := map[types.ItemId]types.Embeddings{
+		1: {1, 0, 0},
+		2: {0, 1, 0},
+		3: {0, 0, 1},
+		4: {1, 0},
+	}
+	result := ClusterEmbeddings(data, ClusterOptions{Clusters: 2, Iterations: 5, Seed: 1})
+	if result.Dimensions != 3 || result.Skipped != 1 {
+		t.Errorf("Expected 3 dimensions and 1 skipped, got %d and %d", result.Dimensions, result.Skipped)
+	}
+	if _, ok := result.Assignments[4]; ok {
+		t.Errorf("Expected the shorter vector to be skipped, got %v", result.Assignments)
+	}
+	result = ClusterEmbeddings(data, ClusterOptions{Clusters: 1, Dimensions: 2, Seed: 1})
+	if len(result.Assignments) != 1 || result.Skipped != 3 {
+		t.Errorf("Expected only the vector with the given length, got %v", result.Assignments)
+	}
+}
+
+func TestFindDuplicatesInNeighbourClusters(t *testing.T) {
+	ids := []types.ItemId{1, 2}
+	vectors := []types.Embeddings{{1, 0}, {1, 0}}
+	members := [][]int{{0}, {1}}
+	tests := []struct {
+		probes   [][]int
+		expected int
+	}{
+		{[][]int{{0}, {1}}, 0},
+		{[][]int{{0, 1}, {1, 0}}, 1},
+		{[][]int{{0, 1}, {1}}, 1},
+		{[][]int{{0}, {1, 0}}, 1},
+	}
+	for _, test := range tests {
+		got := findDuplicates(ids, vectors, members, test.probes, 0.99)
+		if len(got) != test.expected {
+			t.Errorf("%v: expected %d pairs but got %v", test.probes, test.expected, got)
+		}
+		if len(got) == 1 && (got[0].A != 1 || got[0].B != 2) {
+			t.Errorf("Expected the pair ordered by id, got %v", got[0])
+		}
+	}
+}
@@ -28,9 +28,10 @@ type ItemEmbeddingsHandler struct {
 // ItemEmbeddingsHandlerOptions contains configuration options for creating a new embeddings handler
 type ItemEmbeddingsHandlerOptions struct {
 	EmbeddingsEngine    types.EmbeddingsEngine
-	EmbeddingsWorkers   int            // Number of workers in the embeddings queue
-	EmbeddingsQueueSize int            // Size of the embeddings queue buffer
-	EmbeddingsRateLimit EmbeddingsRate // Rate limit for embedding requests per second
+	EmbeddingsWorkers   int              // Number of workers in the embeddings queue
+	EmbeddingsQueueSize int              // Size of the embeddings queue buffer
+	EmbeddingsRateLimit EmbeddingsRate   // Rate limit for embedding requests per second
+	TextBuilder         *ItemTextBuilder // Optional builder for per product type embeddings text
 }
 
 // DefaultEmbeddingsHandlerOptions returns default configuration options for embeddings handler creation
@@ -74,6 +75,8 @@ func NewItemEmbeddingsHandler(opts ItemEmbeddingsHandlerOptions, queueDone func(
 			opts.EmbeddingsWorkers,
 			opts.EmbeddingsQueueSize)
 
+		handler.EmbeddingsQueue.SetTextBuilder(opts.TextBuilder)
+
 		// Start the queue
 		handler.EmbeddingsQueue.Start()
 
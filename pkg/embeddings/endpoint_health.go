@@ -0,0 +1,205 @@
+package embeddings
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// Consecutive failures before an endpoint is taken out of rotation
+	circuitFailureThreshold = 3
+	// Time an open circuit waits before letting a trial request through
+	circuitOpenDuration = 30 * time.Second
+	// Max time an endpoint is kept out after repeated failed trials
+	circuitMaxOpenDuration = 5 * time.Minute
+	// Weight of the latest request in the moving latency average
+	latencyDecay = 0.2
+)
+
+var (
+	endpointRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "slaskfinder_embeddings_endpoint_requests_total",
+		Help: "The total number of embeddings requests per endpoint and result",
+	}, []string{"endpoint", "result"})
+	endpointLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "slaskfinder_embeddings_endpoint_latency_seconds",
+		Help:    "Latency of successful embeddings requests per endpoint",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"endpoint"})
+	endpointHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "slaskfinder_embeddings_endpoint_healthy",
+		Help: "1 if the endpoint circuit is closed, 0 if it is open",
+	}, []string{"endpoint"})
+)
+
+// EndpointStatus is a snapshot of the health of an embeddings endpoint
+type EndpointStatus struct {
+	Url                 string    `json:"url"`
+	Healthy             bool      `json:"healthy"`
+	LatencyMs           float64   `json:"latencyMs"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	OpenUntil           time.Time `json:"openUntil,omitzero"`
+}
+
+type endpointHealth struct {
+	mu                  sync.Mutex
+	url                 string
+	latency             float64 // moving average in seconds, 0 until the first success
+	consecutiveFailures int
+	openUntil           time.Time
+	openDuration        time.Duration
+	trialInFlight       bool
+}
+
+func newEndpointHealth(url string) *endpointHealth {
+	endpointHealthy.WithLabelValues(url).Set(1)
+	return &endpointHealth{url: url}
+}
+
+// available reports if the endpoint can take a request, an open circuit lets
+// a single trial request through once the open duration has passed
+func (e *endpointHealth) available(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.availableUnsafe(now)
+}
+
+func (e *endpointHealth) availableUnsafe(now time.Time) bool {
+	if e.consecutiveFailures < circuitFailureThreshold {
+		return true
+	}
+	return now.After(e.openUntil) && !e.trialInFlight
+}
+
+// tryAcquire takes the endpoint for a request, false when the circuit is open
+// or its trial request is already taken
+func (e *endpointHealth) tryAcquire(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.availableUnsafe(now) {
+		return false
+	}
+	if e.consecutiveFailures >= circuitFailureThreshold {
+		e.trialInFlight = true
+	}
+	return true
+}
+
+func (e *endpointHealth) weight() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.latency <= 0 {
+		// untested endpoints get the benefit of the doubt
+		return 10
+	}
+	return 1 / e.latency
+}
+
+func (e *endpointHealth) success(duration time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	seconds := duration.Seconds()
+	if e.latency <= 0 {
+		e.latency = seconds
+	} else {
+		e.latency = e.latency*(1-latencyDecay) + seconds*latencyDecay
+	}
+	e.consecutiveFailures = 0
+	e.openDuration = 0
+	e.trialInFlight = false
+	endpointRequestsTotal.WithLabelValues(e.url, "success").Inc()
+	endpointLatency.WithLabelValues(e.url).Observe(seconds)
+	endpointHealthy.WithLabelValues(e.url).Set(1)
+}
+
+func (e *endpointHealth) failure(now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.consecutiveFailures++
+	e.trialInFlight = false
+	endpointRequestsTotal.WithLabelValues(e.url, "error").Inc()
+	if e.consecutiveFailures >= circuitFailureThreshold {
+		if e.openDuration == 0 {
+			e.openDuration = circuitOpenDuration
+		} else {
+			e.openDuration = min(e.openDuration*2, circuitMaxOpenDuration)
+		}
+		e.openUntil = now.Add(e.openDuration)
+		endpointHealthy.WithLabelValues(e.url).Set(0)
+	}
+}
+
+func (e *endpointHealth) status() EndpointStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s := EndpointStatus{
+		Url:                 e.url,
+		Healthy:             e.consecutiveFailures < circuitFailureThreshold,
+		LatencyMs:           e.latency * 1000,
+		ConsecutiveFailures: e.consecutiveFailures,
+	}
+	if !s.Healthy {
+		s.OpenUntil = e.openUntil
+	}
+	return s
+}
+
+// endpointPool selects endpoints weighted by their inverse latency, skipping
+// endpoints with an open circuit
+type endpointPool struct {
+	endpoints []*endpointHealth
+}
+
+func newEndpointPool(urls []string) *endpointPool {
+	endpoints := make([]*endpointHealth, 0, len(urls))
+	for _, url := range urls {
+		endpoints = append(endpoints, newEndpointHealth(url))
+	}
+	return &endpointPool{endpoints: endpoints}
+}
+
+// candidates returns the available endpoints to try in order picked by
+// latency weight, endpoints with an open circuit are left out
+func (p *endpointPool) candidates() []*endpointHealth {
+	now := time.Now()
+	available := make([]*endpointHealth, 0, len(p.endpoints))
+	weights := make([]float64, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		if e.available(now) {
+			available = append(available, e)
+			weights = append(weights, e.weight())
+		}
+	}
+	result := make([]*endpointHealth, 0, len(p.endpoints))
+	for len(available) > 0 {
+		total := 0.0
+		for _, w := range weights {
+			total += w
+		}
+		idx := 0
+		target := rand.Float64() * total
+		for i, w := range weights {
+			target -= w
+			if target <= 0 {
+				idx = i
+				break
+			}
+		}
+		result = append(result, available[idx])
+		available = append(available[:idx], available[idx+1:]...)
+		weights = append(weights[:idx], weights[idx+1:]...)
+	}
+	return result
+}
+
+func (p *endpointPool) status() []EndpointStatus {
+	result := make([]EndpointStatus, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		result = append(result, e.status())
+	}
+	return result
+}
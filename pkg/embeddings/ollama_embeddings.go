@@ -1,4 +1,3 @@
-//nolint:gosec // G115 (int->uint32) modulo conversion is safe here: len(o.ApiEndpoints) is bounded by slice length and atomic counter wraps naturally.
 package embeddings
 
 import (
@@ -6,7 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"sync/atomic"
+	"sync"
+	"time"
 
 	"github.com/matst80/slask-finder/pkg/types"
 )
@@ -16,6 +16,8 @@ const (
 	ollamaEmbeddingEndpoint = "http://10.10.10.100:11434/api/embeddings"
 	// Model to use for embeddings
 	defaultEmbeddingModel = "mxbai-embed-large"
+	// Time a request may take before the endpoint counts as failing
+	defaultRequestTimeout = 30 * time.Second
 )
 
 // OllamaEmbeddingRequest represents the request body for Ollama embeddings API
@@ -35,7 +37,8 @@ type OllamaEmbeddingsEngine struct {
 	Model        string
 	ApiEndpoints []string
 	HttpClient   *http.Client
-	counter      uint32 // Used for round-robin endpoint selection
+	pool         *endpointPool // Health tracking and latency weighted selection
+	poolOnce     sync.Once
 
 	// For backward compatibility
 	ApiEndpoint string
@@ -48,8 +51,7 @@ func NewOllamaEmbeddingsEngine() *OllamaEmbeddingsEngine {
 		Model:        defaultEmbeddingModel,
 		ApiEndpoints: []string{ollamaEmbeddingEndpoint},
 		ApiEndpoint:  ollamaEmbeddingEndpoint, // For backward compatibility
-		HttpClient:   &http.Client{},
-		counter:      0,
+		HttpClient:   &http.Client{Timeout: defaultRequestTimeout},
 	}
 }
 
@@ -67,13 +69,13 @@ func NewOllamaEmbeddingsEngineWithConfig(model, endpoint string) *OllamaEmbeddin
 		Model:        model,
 		ApiEndpoints: []string{endpoint},
 		ApiEndpoint:  endpoint, // For backward compatibility
-		HttpClient:   &http.Client{},
-		counter:      0,
+		HttpClient:   &http.Client{Timeout: defaultRequestTimeout},
 	}
 }
 
 // NewOllamaEmbeddingsEngineWithMultipleEndpoints creates a new instance of OllamaEmbeddingsEngine
-// with multiple API endpoints for round-robin load balancing
+// with multiple API endpoints, requests are spread by latency and failing
+// endpoints are skipped until they recover
 func NewOllamaEmbeddingsEngineWithMultipleEndpoints(model string, endpoints ...string) *OllamaEmbeddingsEngine {
 	if model == "" {
 		model = defaultEmbeddingModel
@@ -88,8 +90,7 @@ func NewOllamaEmbeddingsEngineWithMultipleEndpoints(model string, endpoints ...s
 		Model:        model,
 		ApiEndpoints: endpoints,
 		ApiEndpoint:  endpoints[0], // For backward compatibility
-		HttpClient:   &http.Client{},
-		counter:      0,
+		HttpClient:   &http.Client{Timeout: defaultRequestTimeout},
 	}
 }
 
@@ -107,18 +108,28 @@ func (o *OllamaEmbeddingsEngine) GenerateEmbeddings(text string) (types.Embeddin
 		return nil, fmt.Errorf("error marshaling request: %w", err)
 	}
 
-	// Select an endpoint using round-robin
-	var endpoint string
-
-	// Use ApiEndpoints if available (for round-robin), otherwise fall back to single ApiEndpoint
-	if len(o.ApiEndpoints) > 0 {
-		// Get the next index using atomic counter for thread safety
-		idx := atomic.AddUint32(&o.counter, 1) % uint32(len(o.ApiEndpoints)) //nolint:gosec // length cast safe; modulo keeps idx within bounds
-		endpoint = o.ApiEndpoints[idx]
-	} else {
-		endpoint = o.ApiEndpoint
-	}
+	var lastErr error
+	for _, endpoint := range o.getPool().candidates() {
+		if !endpoint.tryAcquire(time.Now()) {
+			continue
+		}
+		start := time.Now()
+		ollamaResp, err := o.request(endpoint.url, jsonBody)
+		if err != nil {
+			endpoint.failure(time.Now())
+			lastErr = err
+			continue
+		}
+		endpoint.success(time.Since(start))
+		return toEmbeddings(ollamaResp), nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no embeddings endpoint available")
+	}
+	return nil, lastErr
+}
 
+func (o *OllamaEmbeddingsEngine) request(endpoint string, jsonBody []byte) (*OllamaEmbeddingResponse, error) {
 	// Create the HTTP request
 	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
@@ -144,15 +155,35 @@ func (o *OllamaEmbeddingsEngine) GenerateEmbeddings(text string) (types.Embeddin
 	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
 		return nil, fmt.Errorf("error decoding response from Ollama API: %w", err)
 	}
+	return &ollamaResp, nil
+}
 
+func toEmbeddings(ollamaResp *OllamaEmbeddingResponse) types.Embeddings {
 	// Convert float64 embeddings to float32 for the types.Embeddings interface
 	float32Embeddings := make(types.Embeddings, len(ollamaResp.Embedding))
 	for i, val := range ollamaResp.Embedding {
 		float32Embeddings[i] = float32(val)
 	}
 
-	// Return the embeddings
-	return float32Embeddings, nil
+	return float32Embeddings
+}
+
+// getPool returns the endpoint health tracking, endpoints are picked up from
+// ApiEndpoints (or ApiEndpoint) the first time it is used
+func (o *OllamaEmbeddingsEngine) getPool() *endpointPool {
+	o.poolOnce.Do(func() {
+		endpoints := o.ApiEndpoints
+		if len(endpoints) == 0 && o.ApiEndpoint != "" {
+			endpoints = []string{o.ApiEndpoint}
+		}
+		o.pool = newEndpointPool(endpoints)
+	})
+	return o.pool
+}
+
+// EndpointStatus returns the health of each configured endpoint
+func (o *OllamaEmbeddingsEngine) EndpointStatus() []EndpointStatus {
+	return o.getPool().status()
 }
 
 // // GenerateEmbeddingsFromItem implements EmbeddingsEngine.GenerateEmbeddingsFromItem
@@ -0,0 +1,118 @@
+package embeddings
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func TestOllamaEmbeddingsEngine_SkipsFailingEndpoint(t *testing.T) {
+	var deadCalls int32
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&deadCalls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer dead.Close()
+	alive := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"embedding":[1,2,3]}`))
+	}))
+	defer alive.Close()
+
+	engine := NewOllamaEmbeddingsEngineWithMultipleEndpoints("test", dead.URL, alive.URL)
+	for range 20 {
+		emb, err := engine.GenerateEmbeddings("hello")
+		if err != nil {
+			t.Fatalf("Expected fallback to healthy endpoint, got %v", err)
+		}
+		if len(emb) != 3 {
+			t.Fatalf("Expected 3 values but got %d", len(emb))
+		}
+	}
+	calls := atomic.LoadInt32(&deadCalls)
+	if calls > circuitFailureThreshold {
+		t.Errorf("Expected the dead endpoint to be skipped after %d failures, got %d calls", circuitFailureThreshold, calls)
+	}
+	status := engine.EndpointStatus()
+	if !status[1].Healthy || (calls == circuitFailureThreshold && status[0].Healthy) {
+		t.Errorf("Unexpected endpoint status %+v", status)
+	}
+}
+
+func TestEndpointHealth_SingleTrial(t *testing.T) {
+	e := newEndpointHealth("trial")
+	now := time.Now()
+	for range circuitFailureThreshold {
+		if !e.tryAcquire(now) {
+			t.Fatal("Expected a closed circuit to take requests")
+		}
+		e.failure(now)
+	}
+	if e.tryAcquire(now) {
+		t.Error("Expected an open circuit to refuse requests")
+	}
+	later := now.Add(circuitOpenDuration + time.Second)
+	if !e.tryAcquire(later) {
+		t.Fatal("Expected one trial request after the open duration")
+	}
+	if e.tryAcquire(later) || e.available(later) {
+		t.Error("Expected only one trial request at a time")
+	}
+	e.success(time.Millisecond)
+	if !e.tryAcquire(later) || !e.tryAcquire(later) {
+		t.Error("Expected a closed circuit after a successful trial")
+	}
+}
+
+func TestOllamaEmbeddingsEngine_Timeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"embedding":[1]}`))
+	}))
+	defer slow.Close()
+	engine := NewOllamaEmbeddingsEngineWithConfig("test", slow.URL)
+	if engine.HttpClient.Timeout == 0 {
+		t.Error("Expected a default request timeout")
+	}
+	engine.HttpClient.Timeout = 20 * time.Millisecond
+	if _, err := engine.GenerateEmbeddings("hello"); err == nil {
+		t.Error("Expected the slow request to time out")
+	}
+}
+
+type countingEngine struct {
+	calls int
+	last  string
+}
+
+func (c *countingEngine) GenerateEmbeddings(text string) (types.Embeddings, error) {
+	c.calls++
+	c.last = text
+	return types.Embeddings{float32(len(text))}, nil
+}
+
+func TestCachedEmbeddingsEngine(t *testing.T) {
+	inner := &countingEngine{}
+	cache := NewCachedEmbeddingsEngine(inner, "test", 2)
+
+	_, _ = cache.GenerateEmbeddings("Red  Shoes")
+	_, _ = cache.GenerateEmbeddings(" red shoes ")
+	if inner.calls != 1 {
+		t.Errorf("Expected normalized queries to share cache entry, got %d calls", inner.calls)
+	}
+	if inner.last != "Red  Shoes" {
+		t.Errorf("Expected the text to be sent unchanged, got %q", inner.last)
+	}
+	_, _ = cache.GenerateEmbeddings("blue")
+	_, _ = cache.GenerateEmbeddings("green")
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 cached entries but got %d", cache.Len())
+	}
+	_, _ = cache.GenerateEmbeddings("red shoes")
+	if inner.calls != 4 {
+		t.Errorf("Expected least recently used entry to be evicted, got %d calls", inner.calls)
+	}
+}
@@ -0,0 +1,116 @@
+package embeddings
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+
+	"github.com/matst80/slask-finder/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	queryCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "slaskfinder_embeddings_query_cache_hits_total",
+		Help: "The total number of query embeddings served from cache",
+	})
+	queryCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "slaskfinder_embeddings_query_cache_misses_total",
+		Help: "The total number of query embeddings that had to be generated",
+	})
+	queryCacheSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "slaskfinder_embeddings_query_cache_size",
+		Help: "The current number of cached query embeddings",
+	})
+)
+
+type cacheEntry struct {
+	key        string
+	embeddings types.Embeddings
+}
+
+// CachedEmbeddingsEngine wraps an engine with an LRU cache keyed by the
+// normalized text and the model, meant for search queries that repeat a lot
+type CachedEmbeddingsEngine struct {
+	mu      sync.Mutex
+	engine  types.EmbeddingsEngine
+	model   string
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func NewCachedEmbeddingsEngine(engine types.EmbeddingsEngine, model string, size int) *CachedEmbeddingsEngine {
+	if size <= 0 {
+		size = 10000
+	}
+	return &CachedEmbeddingsEngine{
+		engine:  engine,
+		model:   model,
+		size:    size,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+// NormalizeQuery lowercases the text and collapses whitespace so trivially
+// different queries share a cache entry
+func NormalizeQuery(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+func (c *CachedEmbeddingsEngine) cacheKey(text string) string {
+	return c.model + "\x00" + NormalizeQuery(text)
+}
+
+func (c *CachedEmbeddingsEngine) get(key string) (types.Embeddings, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*cacheEntry).embeddings, true
+	}
+	return nil, false
+}
+
+func (c *CachedEmbeddingsEngine) put(key string, embeddings types.Embeddings) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*cacheEntry).embeddings = embeddings
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, embeddings: embeddings})
+	for c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.entries, last.Value.(*cacheEntry).key)
+	}
+	queryCacheSize.Set(float64(c.order.Len()))
+}
+
+// GenerateEmbeddings implements types.EmbeddingsEngine, only the cache key is
+// normalized and the text is sent unchanged to the wrapped engine on a miss
+func (c *CachedEmbeddingsEngine) GenerateEmbeddings(text string) (types.Embeddings, error) {
+	key := c.cacheKey(text)
+	if embeddings, ok := c.get(key); ok {
+		queryCacheHits.Inc()
+		return embeddings, nil
+	}
+	queryCacheMisses.Inc()
+	embeddings, err := c.engine.GenerateEmbeddings(text)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, embeddings)
+	return embeddings, nil
+}
+
+// Len returns the number of cached entries
+func (c *CachedEmbeddingsEngine) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
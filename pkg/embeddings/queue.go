@@ -41,6 +41,7 @@ type EmbeddingsQueue struct {
 	queue       chan EmbeddingJob
 	storeFunc   func(types.ItemId, types.Embeddings)
 	doneFunc    func() error
+	textBuilder *ItemTextBuilder
 	workerCount int
 	wg          sync.WaitGroup
 	stopCh      chan struct{}
@@ -94,12 +95,25 @@ func (eq *EmbeddingsQueue) Stop() {
 	})
 }
 
+// SetTextBuilder sets the builder used to render the item text, when nil the
+// item embeddings text is used as is
+func (eq *EmbeddingsQueue) SetTextBuilder(builder *ItemTextBuilder) {
+	eq.textBuilder = builder
+}
+
+func (eq *EmbeddingsQueue) itemText(item types.Item) string {
+	if eq.textBuilder != nil {
+		return eq.textBuilder.Build(item)
+	}
+	return buildItemRepresentation(item)
+}
+
 // QueueItem adds an item to the embeddings generation queue
 // Returns true if queued successfully, false if queue is full or not running
 func (eq *EmbeddingsQueue) QueueItem(item types.Item) bool {
 	select {
 	case eq.queue <- EmbeddingJob{
-		Text:      buildItemRepresentation(item),
+		Text:      eq.itemText(item),
 		Id:        item.GetId(),
 		CreatedAt: time.Now(),
 		StartedAt: time.Now(),
@@ -127,7 +141,7 @@ func (eq *EmbeddingsQueue) QueueItems(items []types.Item) int {
 	for _, item := range items {
 		select {
 		case eq.queue <- EmbeddingJob{
-			Text:      buildItemRepresentation(item),
+			Text:      eq.itemText(item),
 			Id:        item.GetId(),
 			CreatedAt: time.Now(),
 		}:
@@ -0,0 +1,188 @@
+package embeddings
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+// defaultItemTemplate is used when a matching template has no template text,
+// it lists the selected facets between the title and the bullet points
+const defaultItemTemplate = `{{.Title}}
+{{range .Facets}}{{.Name}}: {{.Value}}
+{{end}}{{.BulletPoints}}`
+
+// TemplateFacet is a facet value exposed to an embeddings template
+type TemplateFacet struct {
+	Id    types.FacetId
+	Name  string
+	Value string
+}
+
+// TemplateData is the data an embeddings template is executed with
+type TemplateData struct {
+	Id           types.ItemId
+	Sku          string
+	Title        string
+	BulletPoints string
+	Facets       []TemplateFacet
+}
+
+// Facet returns the value of a selected facet by name, usable in templates as {{.Facet "Brand"}}
+func (d TemplateData) Facet(name string) string {
+	for _, f := range d.Facets {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// ItemTextBuilder renders the embeddings text for items using the
+// templates configured in the settings
+type ItemTextBuilder struct {
+	mu         sync.RWMutex
+	facetNames map[types.FacetId]string
+	compiled   map[string]*template.Template
+}
+
+func NewItemTextBuilder(facets []types.StorageFacet) *ItemTextBuilder {
+	b := &ItemTextBuilder{
+		facetNames: make(map[types.FacetId]string),
+		compiled:   make(map[string]*template.Template),
+	}
+	b.SetFacets(facets)
+	return b
+}
+
+// SetFacets replaces the facet names used when rendering templates
+func (b *ItemTextBuilder) SetFacets(facets []types.StorageFacet) {
+	names := make(map[types.FacetId]string, len(facets))
+	for _, f := range facets {
+		if f.BaseField == nil {
+			continue
+		}
+		names[f.Id] = f.Name
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.facetNames = names
+}
+
+// HandleFieldChanges keeps the facet names in sync with facet_change messages
+func (b *ItemTextBuilder) HandleFieldChanges(changes []types.FieldChange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, change := range changes {
+		if change.BaseField == nil {
+			continue
+		}
+		switch change.Action {
+		case types.ADD_FIELD, types.UPDATE_FIELD:
+			b.facetNames[change.Id] = change.Name
+		case types.REMOVE_FIELD:
+			delete(b.facetNames, change.Id)
+		}
+	}
+}
+
+// ParseTemplate parses an embeddings template with the functions available
+// when rendering, an empty text is the default template
+func ParseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultItemTemplate
+	}
+	return template.New("embeddings").Funcs(template.FuncMap{
+		"join": strings.Join,
+		"trim": strings.TrimSpace,
+	}).Option("missingkey=zero").Parse(text)
+}
+
+func (b *ItemTextBuilder) getTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultItemTemplate
+	}
+	b.mu.RLock()
+	tmpl, ok := b.compiled[text]
+	b.mu.RUnlock()
+	if ok {
+		return tmpl, nil
+	}
+	tmpl, err := ParseTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	b.compiled[text] = tmpl
+	b.mu.Unlock()
+	return tmpl, nil
+}
+
+func (b *ItemTextBuilder) templateData(item types.Item, facetIds []types.FacetId) TemplateData {
+	bulletPoints, _ := item.GetPropertyValue("BulletPoints").(string)
+	data := TemplateData{
+		Id:           item.GetId(),
+		Sku:          item.GetSku(),
+		Title:        item.GetTitle(),
+		BulletPoints: bulletPoints,
+		Facets:       make([]TemplateFacet, 0, len(facetIds)),
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, id := range facetIds {
+		value, ok := facetValue(item, id)
+		if !ok {
+			continue
+		}
+		name, ok := b.facetNames[id]
+		if !ok {
+			name = fmt.Sprintf("%d", id)
+		}
+		data.Facets = append(data.Facets, TemplateFacet{
+			Id:    id,
+			Name:  name,
+			Value: value,
+		})
+	}
+	return data
+}
+
+func facetValue(item types.Item, id types.FacetId) (string, bool) {
+	if values, ok := item.GetStringsFieldValue(id); ok && len(values) > 0 {
+		return strings.Join(values, ", "), true
+	}
+	if value, ok := item.GetNumberFieldValue(id); ok {
+		return fmt.Sprintf("%v", value), true
+	}
+	return "", false
+}
+
+// Render executes the given template for the item
+func (b *ItemTextBuilder) Render(item types.Item, tmpl types.EmbeddingsTemplate) (string, error) {
+	t, err := b.getTemplate(tmpl.Template)
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	if err = t.Execute(&builder, b.templateData(item, tmpl.FacetIds)); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(builder.String()), nil
+}
+
+// Build returns the embeddings text for the item, using the template for its
+// product type when one is configured and the item text otherwise
+func (b *ItemTextBuilder) Build(item types.Item) string {
+	tmpl, ok := types.CurrentSettings.GetEmbeddingsTemplate(item)
+	if !ok {
+		return buildItemRepresentation(item)
+	}
+	text, err := b.Render(item, tmpl)
+	if err != nil || text == "" {
+		return buildItemRepresentation(item)
+	}
+	return text
+}
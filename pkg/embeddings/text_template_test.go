@@ -0,0 +1,58 @@
+package embeddings
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/index"
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func TestItemTextBuilder_Render(t *testing.T) {
+	item := &index.DataItem{}
+	err := json.Unmarshal([]byte(`{"id":1,"title":"Phone","bp":"Fast","values":{"2":"Apple","4":12}}`), item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := NewItemTextBuilder([]types.StorageFacet{
+		{BaseField: &types.BaseField{Id: 2, Name: "Brand"}, Type: types.FacetKeyType},
+	})
+
+	text, err := builder.Render(item, types.EmbeddingsTemplate{
+		Template: `{{.Facet "brand"}} {{.Title}}`,
+		FacetIds: []types.FacetId{2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Apple Phone" {
+		t.Errorf("Expected 'Apple Phone' but got '%s'", text)
+	}
+
+	text, err = builder.Render(item, types.EmbeddingsTemplate{
+		FacetIds: []types.FacetId{2, 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Phone\nBrand: Apple\n4: 12\nFast"
+	if text != expected {
+		t.Errorf("Expected '%s' but got '%s'", expected, text)
+	}
+
+	if _, err = builder.Render(item, types.EmbeddingsTemplate{Template: "{{.Title"}); err == nil {
+		t.Error("Expected error for invalid template")
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	if _, err := ParseTemplate(`{{split .Title}}`); err == nil {
+		t.Error("Expected unknown function to fail")
+	}
+	if _, err := ParseTemplate(`{{trim .Title}}: {{join .Names ", "}}`); err != nil {
+		t.Errorf("Expected template with trim and join to parse, got %v", err)
+	}
+	if _, err := ParseTemplate(""); err != nil {
+		t.Errorf("Expected the default template to parse, got %v", err)
+	}
+}
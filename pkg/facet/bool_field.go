@@ -0,0 +1,152 @@
+package facet
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+type BoolFieldResult struct {
+	True  uint64 `json:"true"`
+	False uint64 `json:"false"`
+}
+
+func (b *BoolFieldResult) HasValues() bool {
+	return b.True > 0 && b.False > 0
+}
+
+// ParseBoolValue reads booleans, numbers (non zero is true) and strings like
+// "true", "1" or "yes"
+func ParseBoolValue(data any) (bool, bool) {
+	switch value := data.(type) {
+	case bool:
+		return value, true
+	case int:
+		return value != 0, true
+	case int64:
+		return value != 0, true
+	case float64:
+		return value != 0, true
+	case []string:
+		if len(value) > 0 {
+			return ParseBoolValue(value[0])
+		}
+	case string:
+		s := strings.ToLower(strings.TrimSpace(value))
+		switch s {
+		case "yes", "ja", "y":
+			return true, true
+		case "no", "nej", "n":
+			return false, true
+		}
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, true
+		}
+	}
+	return false, false
+}
+
+type BoolField struct {
+	*types.BaseField
+	True  *types.ItemList
+	False *types.ItemList
+}
+
+func (f *BoolField) IsExcludedFromFacets() bool {
+	return f.HideFacet || f.BaseField.InternalOnly
+}
+
+func (f *BoolField) IsCategory() bool {
+	return false
+}
+
+func (BoolField) GetType() uint {
+	return types.FacetBoolType
+}
+
+func (f *BoolField) GetBaseField() *types.BaseField {
+	return f.BaseField
+}
+
+func (f *BoolField) UpdateBaseField(field *types.BaseField) {
+	f.BaseField.UpdateFrom(field)
+}
+
+func (f *BoolField) TotalCount() int {
+	return f.True.Len() + f.False.Len()
+}
+
+// AddValueLink moves the item to the side of the value, an item is never
+// in both lists
+func (f *BoolField) AddValueLink(data any, itemId types.ItemId) bool {
+	if !f.Searchable {
+		return false
+	}
+	value, ok := ParseBoolValue(data)
+	if !ok {
+		return false
+	}
+	id := uint32(itemId)
+	if value {
+		f.False.RemoveId(id)
+		f.True.AddId(id)
+	} else {
+		f.True.RemoveId(id)
+		f.False.AddId(id)
+	}
+	return true
+}
+
+func (f *BoolField) RemoveValueLink(_ any, itemId types.ItemId) {
+	f.True.RemoveId(uint32(itemId))
+	f.False.RemoveId(uint32(itemId))
+}
+
+func (f *BoolField) Match(input any) *types.ItemList {
+	ret := types.NewItemList()
+	var values types.StringFilterValue
+	switch value := input.(type) {
+	case bool:
+		values = types.StringFilterValue{strconv.FormatBool(value)}
+	default:
+		values, _ = types.AsKeyFilterValue(input)
+	}
+	for _, v := range values {
+		if value, ok := ParseBoolValue(v); ok {
+			if value {
+				ret.Merge(f.True)
+			} else {
+				ret.Merge(f.False)
+			}
+		}
+	}
+	return ret
+}
+
+// GetResult counts the true and false items, all items are counted when
+// baseIds is empty
+func (f *BoolField) GetResult(baseIds *types.ItemList) *BoolFieldResult {
+	if baseIds == nil || baseIds.Len() == 0 {
+		return &BoolFieldResult{
+			True:  f.True.Cardinality(),
+			False: f.False.Cardinality(),
+		}
+	}
+	return &BoolFieldResult{
+		True:  f.True.IntersectionLen(baseIds),
+		False: f.False.IntersectionLen(baseIds),
+	}
+}
+
+func (f *BoolField) GetValues() []any {
+	return []any{f.GetResult(nil)}
+}
+
+func EmptyBoolField(field *types.BaseField) *BoolField {
+	return &BoolField{
+		BaseField: field,
+		True:      types.NewItemList(),
+		False:     types.NewItemList(),
+	}
+}
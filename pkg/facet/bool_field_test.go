@@ -0,0 +1,36 @@
+package facet
+
+import (
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func TestBoolField(t *testing.T) {
+	f := EmptyBoolField(&types.BaseField{Id: 1, Searchable: true})
+	f.AddValueLink(true, 1)
+	f.AddValueLink("yes", 2)
+	f.AddValueLink(0.0, 3)
+	if f.AddValueLink("maybe", 4) {
+		t.Errorf("Expected unknown value to be ignored")
+	}
+	if r := f.GetResult(nil); r.True != 2 || r.False != 1 {
+		t.Errorf("Expected 2 true and 1 false got %+v", r)
+	}
+	// changing the value moves the item
+	f.AddValueLink(false, 1)
+	ids := f.Match(types.StringFilterValue{"false"})
+	if ids.Len() != 2 || !ids.Contains(1) || !ids.Contains(3) {
+		t.Errorf("Expected items 1 and 3 got %v", ids.ToSlice())
+	}
+	base := types.NewItemList()
+	base.AddId(2)
+	base.AddId(3)
+	if r := f.GetResult(base); r.True != 1 || r.False != 1 {
+		t.Errorf("Expected 1 true and 1 false got %+v", r)
+	}
+	f.RemoveValueLink(nil, 2)
+	if f.Match(true).Len() != 0 {
+		t.Errorf("Expected no true items after removal")
+	}
+}
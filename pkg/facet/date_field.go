@@ -0,0 +1,278 @@
+package facet
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+// date layouts accepted for string values, numbers are unix milliseconds
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"20060102",
+}
+
+func floatRef(v float64) *float64 {
+	return &v
+}
+
+// DefaultDateRanges are used by date facets without configured ranges
+var DefaultDateRanges = []types.NamedRange{
+	{Name: "last-7-days", Min: floatRef(-7), Max: floatRef(0)},
+	{Name: "last-30-days", Min: floatRef(-30), Max: floatRef(0)},
+	{Name: "upcoming", Min: floatRef(0)},
+}
+
+type DateFieldResult struct {
+	// Min and Max are unix milliseconds
+	Min    int64        `json:"min"`
+	Max    int64        `json:"max"`
+	Ranges []RangeCount `json:"ranges,omitempty"`
+}
+
+func (d *DateFieldResult) HasValues() bool {
+	if d.Min < d.Max {
+		return true
+	}
+	for _, r := range d.Ranges {
+		if r.Count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseDateValue reads unix milliseconds or a date string, zero and empty
+// values are treated as missing
+func ParseDateValue(data any) (time.Time, bool) {
+	switch value := data.(type) {
+	case int:
+		return fromUnixMilli(int64(value))
+	case int64:
+		return fromUnixMilli(value)
+	case float64:
+		return fromUnixMilli(int64(value))
+	case time.Time:
+		return value, !value.IsZero()
+	case []string:
+		if len(value) > 0 {
+			return ParseDateValue(value[0])
+		}
+	case string:
+		s := strings.TrimSpace(value)
+		if s == "" {
+			return time.Time{}, false
+		}
+		if ms, err := strconv.ParseInt(s, 10, 64); err == nil && len(s) > 8 {
+			return fromUnixMilli(ms)
+		}
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func fromUnixMilli(ms int64) (time.Time, bool) {
+	if ms == 0 {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// DateField indexes points in time with minute resolution, the named ranges of
+// the facet have bounds in days relative to now, -30 to 0 is the last 30 days
+type DateField struct {
+	*types.BaseField
+	minutes *IntegerField
+	now     func() time.Time
+}
+
+func toMinute(t time.Time) int {
+	return int(t.Unix() / 60)
+}
+
+func fromMinute(m int) int64 {
+	return int64(m) * 60_000
+}
+
+func (f *DateField) IsExcludedFromFacets() bool {
+	return f.HideFacet || f.BaseField.InternalOnly
+}
+
+func (f *DateField) IsCategory() bool {
+	return false
+}
+
+func (DateField) GetType() uint {
+	return types.FacetDateType
+}
+
+func (f *DateField) GetBaseField() *types.BaseField {
+	return f.BaseField
+}
+
+func (f *DateField) UpdateBaseField(field *types.BaseField) {
+	f.BaseField.UpdateFrom(field)
+}
+
+func (f *DateField) TotalCount() int {
+	return f.minutes.Count
+}
+
+func (f *DateField) AddValueLink(data any, itemId types.ItemId) bool {
+	if !f.Searchable {
+		return false
+	}
+	t, ok := ParseDateValue(data)
+	if !ok {
+		// a cleared date removes the item from the facet
+		f.RemoveValueLink(data, itemId)
+		return false
+	}
+	id := uint32(itemId)
+	minute := toMinute(t)
+	if existing := f.minutes.ValueForItemId(id); existing != nil {
+		if *existing == minute {
+			return true
+		}
+		f.minutes.removeValueLink(*existing, id)
+	}
+	f.minutes.addValueLink(minute, id)
+	return true
+}
+
+func (f *DateField) RemoveValueLink(_ any, itemId types.ItemId) {
+	id := uint32(itemId)
+	if existing := f.minutes.ValueForItemId(id); existing != nil {
+		f.minutes.removeValueLink(*existing, id)
+	}
+}
+
+// matchMinutes returns the items between the minutes, bounds included
+func (f *DateField) matchMinutes(minValue, maxValue int) *types.ItemList {
+	if f.minutes.Count == 0 || maxValue < f.minutes.Min || minValue > f.minutes.Max {
+		return types.NewItemList()
+	}
+	ids := f.minutes.MatchesRange(minValue, maxValue)
+	if ids == nil {
+		return allBucketIds(f.minutes.buckets)
+	}
+	return ids
+}
+
+func (f *DateField) ranges() []types.NamedRange {
+	if len(f.Ranges) > 0 {
+		return f.Ranges
+	}
+	return DefaultDateRanges
+}
+
+func (f *DateField) matchNamedRange(r types.NamedRange) *types.ItemList {
+	now := toMinute(f.now())
+	minValue, maxValue := math.MinInt, math.MaxInt
+	if r.Min != nil {
+		minValue = now + int(*r.Min*24*60)
+	}
+	if r.Max != nil {
+		maxValue = now + int(*r.Max*24*60)
+	}
+	return f.matchMinutes(minValue, maxValue)
+}
+
+// MatchRangeNames returns the items in any of the relative ranges
+func (f *DateField) MatchRangeNames(names []string) *types.ItemList {
+	ret := types.NewItemList()
+	for _, name := range names {
+		for _, r := range f.ranges() {
+			if r.Name == name {
+				ret.Merge(f.matchNamedRange(r))
+			}
+		}
+	}
+	return ret
+}
+
+// GetRangeCounts counts the items in each of the relative ranges
+func (f *DateField) GetRangeCounts(baseIds *types.ItemList) []RangeCount {
+	return countNamedRanges(f.ranges(), f.matchNamedRange, baseIds)
+}
+
+// Match takes range names or a range filter with unix milliseconds or date
+// strings as bounds, a missing bound is open ended
+func (f *DateField) Match(input any) *types.ItemList {
+	switch value := input.(type) {
+	case types.StringFilterValue:
+		return f.MatchRangeNames(value)
+	case types.RangeFilter:
+		minValue, maxValue := math.MinInt, math.MaxInt
+		from, hasFrom, ok := dateBound(value.Min, value.MinText)
+		if !ok {
+			return types.NewItemList()
+		}
+		to, hasTo, ok := dateBound(value.Max, value.MaxText)
+		if !ok {
+			return types.NewItemList()
+		}
+		// exclusive bounds leave out the whole minute
+		if hasFrom {
+			minValue = toMinute(from)
+			if value.MinExclusive {
+				minValue++
+			}
+		}
+		if hasTo {
+			maxValue = toMinute(to)
+			if value.MaxExclusive {
+				maxValue--
+			}
+		}
+		return f.matchMinutes(minValue, maxValue)
+	}
+	return types.NewItemList()
+}
+
+// dateBound reads a range bound in unix milliseconds or as a date string, ok
+// is false when the date string is invalid
+func dateBound(ms *float64, text string) (t time.Time, set bool, ok bool) {
+	if ms != nil {
+		return time.UnixMilli(floatToInt64(*ms)), true, true
+	}
+	if text == "" {
+		return time.Time{}, false, true
+	}
+	t, ok = ParseDateValue(text)
+	return t, ok, ok
+}
+
+// GetExtents returns the first and last date of the matching items
+func (f *DateField) GetExtents(matchIds *types.ItemList) *DateFieldResult {
+	if f.minutes.Count == 0 {
+		return &DateFieldResult{}
+	}
+	r := f.minutes.GetExtents(matchIds)
+	return &DateFieldResult{
+		Min: fromMinute(r.Min),
+		Max: fromMinute(r.Max),
+	}
+}
+
+func (f *DateField) GetValues() []any {
+	return []any{f.GetExtents(nil)}
+}
+
+func EmptyDateField(field *types.BaseField) *DateField {
+	return &DateField{
+		BaseField: field,
+		minutes:   EmptyIntegerField(field),
+		now:       time.Now,
+	}
+}
@@ -0,0 +1,137 @@
+package facet
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func TestParseDateValue(t *testing.T) {
+	expected := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, input := range []any{"2025-03-01", "2025-03-01T00:00:00Z", "20250301", expected.UnixMilli(), float64(expected.UnixMilli())} {
+		value, ok := ParseDateValue(input)
+		if !ok || !value.Equal(expected) {
+			t.Errorf("Expected %v for %v got %v (%v)", expected, input, value, ok)
+		}
+	}
+	for _, input := range []any{"", int64(0), "not a date", true} {
+		if _, ok := ParseDateValue(input); ok {
+			t.Errorf("Expected %v to be missing", input)
+		}
+	}
+}
+
+func makeDateField(now time.Time) *DateField {
+	f := EmptyDateField(&types.BaseField{Id: 1, Searchable: true})
+	f.now = func() time.Time { return now }
+	f.AddValueLink(now.AddDate(0, 0, -3).UnixMilli(), 1)
+	f.AddValueLink(now.AddDate(0, 0, -20).UnixMilli(), 2)
+	f.AddValueLink(now.AddDate(0, 0, -100).UnixMilli(), 3)
+	f.AddValueLink(now.AddDate(0, 0, 10).Format("2006-01-02"), 4)
+	return f
+}
+
+func TestDateFieldRelativeRanges(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	f := makeDateField(now)
+	tests := []struct {
+		name     string
+		expected []uint32
+	}{
+		{"last-7-days", []uint32{1}},
+		{"last-30-days", []uint32{1, 2}},
+		{"upcoming", []uint32{4}},
+	}
+	for _, test := range tests {
+		ids := f.Match(types.StringFilterValue{test.name})
+		if ids.Len() != len(test.expected) {
+			t.Errorf("%s: expected %v got %v", test.name, test.expected, ids.ToSlice())
+		}
+		for _, id := range test.expected {
+			if !ids.Contains(id) {
+				t.Errorf("%s: expected %d in %v", test.name, id, ids.ToSlice())
+			}
+		}
+	}
+	counts := f.GetRangeCounts(nil)
+	if len(counts) != 3 || counts[0].Count != 1 || counts[1].Count != 2 || counts[2].Count != 1 {
+		t.Errorf("Unexpected range counts %+v", counts)
+	}
+}
+
+func TestDateFieldRangeFilter(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	f := makeDateField(now)
+	ids := f.Match(types.NewRangeFilter(1, float64(now.AddDate(0, 0, -50).UnixMilli()), float64(now.UnixMilli())))
+	if ids.Len() != 2 || !ids.Contains(1) || !ids.Contains(2) {
+		t.Errorf("Expected items 1 and 2 got %v", ids.ToSlice())
+	}
+	from := float64(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC).UnixMilli())
+	open := f.Match(types.RangeFilter{Id: 1, Min: &from})
+	if open.Len() != 2 || !open.Contains(1) || !open.Contains(4) {
+		t.Errorf("Expected items 1 and 4 got %v", open.ToSlice())
+	}
+	var dates types.RangeFilter
+	if err := json.Unmarshal([]byte(`{"id":1,"min":"2025-06-01"}`), &dates); err != nil {
+		t.Fatal(err)
+	}
+	if ids := f.Match(dates); ids.Len() != 2 || !ids.Contains(1) || !ids.Contains(4) {
+		t.Errorf("Expected items 1 and 4 from the date string got %v", ids.ToSlice())
+	}
+	if ids := f.Match(types.RangeFilter{Id: 1, MinText: "yesterday"}); ids.Len() != 0 {
+		t.Errorf("Expected no items for an invalid date got %v", ids.ToSlice())
+	}
+}
+
+func TestDateFieldUpdateValue(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	f := makeDateField(now)
+	f.AddValueLink(now.AddDate(0, 0, 5).UnixMilli(), 1)
+	if ids := f.Match(types.StringFilterValue{"upcoming"}); ids.Len() != 2 || !ids.Contains(1) {
+		t.Errorf("Expected moved item in upcoming got %v", ids.ToSlice())
+	}
+	f.AddValueLink("", 1)
+	if ids := f.Match(types.StringFilterValue{"upcoming"}); ids.Contains(1) {
+		t.Errorf("Expected cleared item to be removed got %v", ids.ToSlice())
+	}
+}
+
+type propertyItem struct {
+	*types.MockItem
+	properties map[string]any
+}
+
+func (p propertyItem) GetPropertyValue(name string) any {
+	return p.properties[name]
+}
+
+func TestPropertyFacets(t *testing.T) {
+	h := NewFacetItemHandler([]types.StorageFacet{
+		{BaseField: &types.BaseField{Id: 100, Searchable: true, Property: "Buyable"}, Type: types.FacetBoolType},
+		{BaseField: &types.BaseField{Id: 101, Searchable: true, Property: "ReleaseDate"}, Type: types.FacetDateType},
+	}, nil)
+	wg := &sync.WaitGroup{}
+	h.HandleItem(propertyItem{
+		MockItem:   &types.MockItem{Id: 1},
+		properties: map[string]any{"Buyable": true, "ReleaseDate": "2025-03-01"},
+	}, wg)
+	h.HandleItem(propertyItem{
+		MockItem:   &types.MockItem{Id: 2},
+		properties: map[string]any{"Buyable": false},
+	}, wg)
+	wg.Wait()
+
+	bf, _ := h.GetFacet(100)
+	if r := bf.(*BoolField).GetResult(nil); r.True != 1 || r.False != 1 {
+		t.Errorf("Expected one buyable and one not buyable got %+v", r)
+	}
+	df, _ := h.GetFacet(101)
+	from := float64(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli())
+	to := float64(time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC).UnixMilli())
+	if ids := df.Match(types.NewRangeFilter(101, from, to)); ids.Len() != 1 || !ids.Contains(1) {
+		t.Errorf("Expected item 1 from the release date got %v", ids.ToSlice())
+	}
+}
@@ -23,6 +23,9 @@ type FacetItemHandler struct {
 	Facets       map[types.FacetId]types.Facet
 	ItemFieldIds map[types.ItemId]*types.ItemList
 	AllFacets    *types.ItemList
+
+	// propertyFacets read their values from item properties, see BaseField.Property
+	propertyFacets []types.Facet
 }
 
 func (h *FacetItemHandler) HandleFieldChanges(items []types.FieldChange) {
@@ -83,6 +86,15 @@ func NewFacetItemHandler(facets []types.StorageFacet, overrides *types.SortOverr
 		case 2:
 			r.AddDecimalField(f.BaseField)
 
+		case types.FacetTreeType:
+			r.AddTreeField(f.BaseField)
+
+		case types.FacetDateType:
+			r.AddDateField(f.BaseField)
+
+		case types.FacetBoolType:
+			r.AddBoolField(f.BaseField)
+
 		default:
 			log.Printf("Unknown field type %d", f.Type)
 			continue
@@ -92,11 +104,24 @@ func NewFacetItemHandler(facets []types.StorageFacet, overrides *types.SortOverr
 		}
 	}
 
+	r.updatePropertyFacets()
 	r.updateSortMap()
 
 	return r
 }
 
+// updatePropertyFacets collects the facets with values from item properties,
+// the caller holds the lock when facets can change concurrently
+func (h *FacetItemHandler) updatePropertyFacets() {
+	ret := make([]types.Facet, 0)
+	for _, f := range h.Facets {
+		if f.GetBaseField().Property != "" {
+			ret = append(ret, f)
+		}
+	}
+	h.propertyFacets = ret
+}
+
 func (h *FacetItemHandler) updateSortMap() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -162,6 +187,14 @@ func (h *FacetItemHandler) HandleItem(item types.Item, wg *sync.WaitGroup) {
 					f.RemoveValueLink(fieldValue, itemId)
 				}
 			}
+			for fieldId, fieldValues := range item.GetNumberListFields() {
+				if f, ok := h.Facets[fieldId]; ok {
+					f.RemoveValueLink(fieldValues, itemId)
+				}
+			}
+			for _, f := range h.propertyFacets {
+				f.RemoveValueLink(item.GetPropertyValue(f.GetBaseField().Property), itemId)
+			}
 
 		} else {
 			fid, ok := h.ItemFieldIds[itemId]
@@ -190,6 +223,24 @@ func (h *FacetItemHandler) HandleItem(item types.Item, wg *sync.WaitGroup) {
 					}
 				}
 			}
+			for fieldId, fieldValues := range item.GetNumberListFields() {
+				if f, ok := h.Facets[fieldId]; ok {
+					b := f.GetBaseField()
+					if b.Searchable && f.AddValueLink(fieldValues, itemId) {
+						if !b.HideFacet {
+							fid.AddId(uint32(fieldId))
+						}
+					}
+				}
+			}
+			for _, f := range h.propertyFacets {
+				b := f.GetBaseField()
+				if b.Searchable && f.AddValueLink(item.GetPropertyValue(b.Property), itemId) {
+					if !b.HideFacet {
+						fid.AddId(uint32(b.Id))
+					}
+				}
+			}
 		}
 	})
 
@@ -208,6 +259,27 @@ func (h *FacetItemHandler) AddIntegerField(field *types.BaseField) {
 	h.Facets[field.Id] = EmptyIntegerField(field)
 }
 
+func (h *FacetItemHandler) AddTreeField(field *types.BaseField) {
+	h.Facets[field.Id] = EmptyTreeValueField(field)
+}
+
+func (h *FacetItemHandler) AddDateField(field *types.BaseField) {
+	h.Facets[field.Id] = EmptyDateField(field)
+}
+
+func (h *FacetItemHandler) AddBoolField(field *types.BaseField) {
+	h.Facets[field.Id] = EmptyBoolField(field)
+}
+
+func (h *FacetItemHandler) GetTreeFacet(id types.FacetId) (*TreeField, bool) {
+	if f, ok := h.Facets[id]; ok {
+		if tf, isTree := f.(*TreeField); isTree {
+			return tf, true
+		}
+	}
+	return nil, false
+}
+
 func (h *FacetItemHandler) GetKeyFacet(id types.FacetId) (*KeyField, bool) {
 	if f, ok := h.Facets[id]; ok {
 		switch tf := f.(type) {
@@ -237,6 +309,12 @@ func (h *FacetItemHandler) UpdateFields(changes []types.FieldChange) {
 				h.AddIntegerField(change.BaseField)
 			case 2:
 				h.AddDecimalField(change.BaseField)
+			case types.FacetTreeType:
+				h.AddTreeField(change.BaseField)
+			case types.FacetDateType:
+				h.AddDateField(change.BaseField)
+			case types.FacetBoolType:
+				h.AddBoolField(change.BaseField)
 			default:
 				log.Printf("Unknown field type %d", change.FieldType)
 			}
@@ -251,6 +329,7 @@ func (h *FacetItemHandler) UpdateFields(changes []types.FieldChange) {
 			}
 		}
 	}
+	h.updatePropertyFacets()
 }
 
 func getFacetResult(f types.Facet, baseIds *types.ItemList, c chan *JsonFacet, wg *sync.WaitGroup, selected any) {
@@ -287,9 +366,7 @@ func getFacetResult(f types.Facet, baseIds *types.ItemList, c chan *JsonFacet, w
 		c <- &JsonFacet{
 			BaseField: baseField,
 			Selected:  selected,
-			Result: &KeyFieldResult{
-				Values: r,
-			},
+			Result:    NewKeyFieldResult(baseField, r),
 		}
 
 	case *IntegerField:
@@ -298,8 +375,10 @@ func getFacetResult(f types.Facet, baseIds *types.ItemList, c chan *JsonFacet, w
 				BaseField: baseField,
 				Selected:  selected,
 				Result: &IntegerFieldResult{
-					Min: field.Min,
-					Max: field.Max,
+					Min:       field.Min,
+					Max:       field.Max,
+					Histogram: field.GetHistogram(nil),
+					Ranges:    field.GetRangeCounts(nil),
 				},
 			}
 		} else {
@@ -307,6 +386,8 @@ func getFacetResult(f types.Facet, baseIds *types.ItemList, c chan *JsonFacet, w
 			if r == nil {
 				return
 			}
+			r.Histogram = field.GetHistogram(baseIds)
+			r.Ranges = field.GetRangeCounts(baseIds)
 			c <- &JsonFacet{
 				BaseField: baseField,
 				Selected:  selected,
@@ -320,18 +401,59 @@ func getFacetResult(f types.Facet, baseIds *types.ItemList, c chan *JsonFacet, w
 				BaseField: baseField,
 				Selected:  selected,
 				Result: &DecimalFieldResult{
-					Min: field.Min,
-					Max: field.Max,
+					Min:       field.Min,
+					Max:       field.Max,
+					Histogram: field.GetHistogram(nil),
+					Ranges:    field.GetRangeCounts(nil),
 				},
 			}
 		} else {
 			r := field.GetExtents(baseIds)
+			if r != nil {
+				r.Histogram = field.GetHistogram(baseIds)
+				r.Ranges = field.GetRangeCounts(baseIds)
+			}
 			c <- &JsonFacet{
 				BaseField: baseField,
 				Selected:  selected,
 				Result:    r,
 			}
 		}
+	case *DateField:
+		r := field.GetExtents(baseIds)
+		r.Ranges = field.GetRangeCounts(baseIds)
+		if !r.HasValues() && selected == nil {
+			return
+		}
+		c <- &JsonFacet{
+			BaseField: baseField,
+			Selected:  selected,
+			Result:    r,
+		}
+	case *BoolField:
+		r := field.GetResult(baseIds)
+		if r.True+r.False == 0 {
+			return
+		}
+		c <- &JsonFacet{
+			BaseField: baseField,
+			Selected:  selected,
+			Result:    r,
+		}
+	case *TreeField:
+		var path []string
+		if pf, ok := selected.(types.PathFilter); ok {
+			path = pf.Path
+		}
+		r := field.GetResult(baseIds, path)
+		if r == nil || !r.HasValues() {
+			return
+		}
+		c <- &JsonFacet{
+			BaseField: baseField,
+			Selected:  selected,
+			Result:    r,
+		}
 	default:
 		log.Printf("unknown field type %T", field)
 	}
@@ -372,6 +494,22 @@ func (ws *FacetItemHandler) GetSearchedFacets(ctx context.Context, baseIds *type
 
 		}
 	}
+	for _, p := range sr.PathFilter {
+		if sr.IsIgnored(p.Id) {
+			continue
+		}
+		f, facetExists := ws.Facets[p.Id]
+		if facetExists && !f.IsExcludedFromFacets() {
+			wg.Add(1)
+			go func(otherFilters *types.Filters) {
+				matchIds := &types.ItemList{}
+				qm := makeQm(matchIds)
+				ws.Match(otherFilters, qm)
+				qm.Wait()
+				getFacetResult(f, matchIds, ch, wg, p)
+			}(sr.WithOut(p.Id, false))
+		}
+	}
 	for _, r := range sr.RangeFilter {
 		var f types.Facet
 		var facetExists bool
@@ -411,7 +549,9 @@ func (ws *FacetItemHandler) SortJsonFacets(facets []*JsonFacet) {
 	})
 }
 
-func (ws *FacetItemHandler) GetOtherFacets(baseIds *types.ItemList, sr *types.FacetRequest, ch chan *JsonFacet, wg *sync.WaitGroup) {
+// GetOtherFacets returns the facets of the matching items that are not
+// filtered on, the facets of the profile are picked first
+func (ws *FacetItemHandler) GetOtherFacets(baseIds *types.ItemList, sr *types.FacetRequest, profile *types.FacetProfile, ch chan *JsonFacet, wg *sync.WaitGroup) {
 
 	fieldIds := roaring.Bitmap{}
 	limit := 30
@@ -431,18 +571,18 @@ func (ws *FacetItemHandler) GetOtherFacets(baseIds *types.ItemList, sr *types.Fa
 		fieldIds = *ws.AllFacets.Bitmap()
 	}
 
-	for uid := range ws.sortValues.SortBitmap(fieldIds) {
-		id := types.FacetId(uid)
-		if count > limit {
-			break
+	added := roaring.Bitmap{}
+	addFacet := func(id types.FacetId) {
+		if added.Contains(uint32(id)) {
+			return
 		}
-
 		if !sr.Filters.HasField(id) && !sr.IsIgnored(id) {
 
 			f, facetExists := ws.Facets[id]
 
 			if facetExists && !f.IsExcludedFromFacets() {
 
+				added.Add(uint32(id))
 				wg.Add(1)
 				count++
 				go getFacetResult(f, baseIds, ch, wg, nil)
@@ -451,4 +591,26 @@ func (ws *FacetItemHandler) GetOtherFacets(baseIds *types.ItemList, sr *types.Fa
 		}
 	}
 
+	if profile != nil {
+		for _, id := range profile.FacetIds {
+			if count > limit {
+				break
+			}
+			if fieldIds.Contains(uint32(id)) {
+				addFacet(id)
+			}
+		}
+	}
+
+	for uid := range ws.sortValues.SortBitmap(fieldIds) {
+		id := types.FacetId(uid)
+		if count > limit {
+			break
+		}
+		if profile != nil && profile.IsHidden(id) {
+			continue
+		}
+		addFacet(id)
+	}
+
 }
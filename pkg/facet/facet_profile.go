@@ -0,0 +1,106 @@
+package facet
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+// DominantCategoryShare is the part of the result a category needs to cover for
+// its facet profile to be used without a category filter
+const DominantCategoryShare = 0.5
+
+// GetFacetProfile selects the facet profile from the deepest active category
+// filter, or from the category dominating the matching items
+func (h *FacetItemHandler) GetFacetProfile(sr *types.FacetRequest, ids *types.ItemList) *types.FacetProfile {
+	profiles := types.CurrentSettings.GetFacetProfiles()
+	if len(profiles) == 0 {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var selected *types.FacetProfile
+	level := -1
+	for _, filter := range sr.StringFilter {
+		if filter.Not {
+			continue
+		}
+		f, ok := h.Facets[filter.Id]
+		if !ok || !f.IsCategory() || f.GetBaseField().CategoryLevel <= level {
+			continue
+		}
+		for _, value := range filter.Value {
+			if p, found := types.CurrentSettings.FindFacetProfile(filter.Id, value); found {
+				selected = p
+				level = f.GetBaseField().CategoryLevel
+				break
+			}
+		}
+	}
+	if selected != nil || ids == nil || ids.IsEmpty() {
+		return selected
+	}
+
+	total := ids.Cardinality()
+	var best uint64
+	for i := range profiles {
+		p := &profiles[i]
+		for id, f := range h.Facets {
+			if !f.IsCategory() || (p.FacetId != 0 && p.FacetId != id) {
+				continue
+			}
+			keyField, ok := f.(*KeyField)
+			if !ok {
+				continue
+			}
+			if values, ok := keyField.Keys[p.Category]; ok {
+				if count := values.IntersectionLen(ids); count > best {
+					best = count
+					selected = p
+				}
+			}
+		}
+	}
+	if float64(best) < float64(total)*DominantCategoryShare {
+		return nil
+	}
+	return selected
+}
+
+// ApplyFacetProfile removes the facets hidden by the profile, applies the
+// facet defaults and orders the facets by the profile, facets not in the
+// profile follow in the global order. Facets with a selection are never hidden
+func (h *FacetItemHandler) ApplyFacetProfile(profile *types.FacetProfile, facets []*JsonFacet) []*JsonFacet {
+	h.SortJsonFacets(facets)
+	if profile == nil {
+		return facets
+	}
+	ret := slices.DeleteFunc(facets, func(f *JsonFacet) bool {
+		return f.Selected == nil && profile.IsHidden(f.Id)
+	})
+	for _, f := range ret {
+		defaults, ok := profile.Defaults[f.Id]
+		if !ok {
+			continue
+		}
+		f.BaseField = defaults.Apply(f.BaseField)
+		if keyResult, isKey := f.Result.(*KeyFieldResult); isKey {
+			f.Result = NewKeyFieldResult(f.BaseField, keyResult.AllValues())
+		}
+	}
+	slices.SortStableFunc(ret, func(a, b *JsonFacet) int {
+		ai, bi := profile.Position(a.Id), profile.Position(b.Id)
+		switch {
+		case ai >= 0 && bi >= 0:
+			return cmp.Compare(ai, bi)
+		case ai >= 0:
+			return -1
+		case bi >= 0:
+			return 1
+		}
+		return 0
+	})
+	return ret
+}
@@ -0,0 +1,103 @@
+package facet
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func makeProfileHandler() *FacetItemHandler {
+	h := NewFacetItemHandler([]types.StorageFacet{
+		{BaseField: &types.BaseField{Id: 10, Searchable: true, CategoryLevel: 1}, Type: types.FacetKeyType},
+		{BaseField: &types.BaseField{Id: 11, Searchable: true, CategoryLevel: 2}, Type: types.FacetKeyType},
+		{BaseField: &types.BaseField{Id: 20, Searchable: true, Priority: 10}, Type: types.FacetKeyType},
+		{BaseField: &types.BaseField{Id: 21, Searchable: true, Priority: 5}, Type: types.FacetKeyType},
+	}, nil)
+	cat, _ := h.GetKeyFacet(10)
+	sub, _ := h.GetKeyFacet(11)
+	for id := range types.ItemId(10) {
+		cat.AddValueLink("Vitvaror", id)
+		if id < 7 {
+			sub.AddValueLink("Tvättmaskiner", id)
+		} else {
+			sub.AddValueLink("Diskmaskiner", id)
+		}
+	}
+	return h
+}
+
+func withProfiles(t *testing.T, profiles []types.FacetProfile) {
+	types.CurrentSettings.Lock()
+	previous := types.CurrentSettings.FacetProfiles
+	types.CurrentSettings.FacetProfiles = profiles
+	types.CurrentSettings.Unlock()
+	t.Cleanup(func() {
+		types.CurrentSettings.Lock()
+		types.CurrentSettings.FacetProfiles = previous
+		types.CurrentSettings.Unlock()
+	})
+}
+
+func TestGetFacetProfile(t *testing.T) {
+	withProfiles(t, []types.FacetProfile{
+		{Category: "Vitvaror", FacetIds: []types.FacetId{20}},
+		{FacetId: 11, Category: "Tvättmaskiner", FacetIds: []types.FacetId{21}},
+		{FacetId: 11, Category: "Diskmaskiner", FacetIds: []types.FacetId{21, 20}},
+	})
+	h := makeProfileHandler()
+
+	// the deepest category filter wins
+	sr := &types.FacetRequest{Filters: &types.Filters{StringFilter: []types.StringFilter{
+		{Id: 10, Value: types.StringFilterValue{"Vitvaror"}},
+		{Id: 11, Value: types.StringFilterValue{"Diskmaskiner"}},
+	}}}
+	if p := h.GetFacetProfile(sr, nil); p == nil || p.Category != "Diskmaskiner" {
+		t.Errorf("Expected the Diskmaskiner profile got %+v", p)
+	}
+
+	// without filters the dominant category of the result is used
+	empty := &types.FacetRequest{Filters: &types.Filters{}}
+	ids := types.NewItemList()
+	for _, id := range []uint32{0, 1, 2, 7} {
+		ids.AddId(id)
+	}
+	p := h.GetFacetProfile(empty, ids)
+	if p == nil || p.Category != "Vitvaror" {
+		t.Errorf("Expected the Vitvaror profile covering all items got %+v", p)
+	}
+}
+
+func TestApplyFacetProfile(t *testing.T) {
+	h := makeProfileHandler()
+	alpha := types.ValueSortAlpha
+	profile := &types.FacetProfile{
+		Category: "Vitvaror",
+		FacetIds: []types.FacetId{21},
+		Hidden:   []types.FacetId{11},
+		Defaults: map[types.FacetId]types.FacetDefaults{21: {ValueSorting: &alpha, ValueLimit: 1}},
+	}
+	field20, _ := h.GetFacet(20)
+	field21, _ := h.GetFacet(21)
+	field11, _ := h.GetFacet(11)
+	facets := []*JsonFacet{
+		{BaseField: field20.GetBaseField(), Result: &KeyFieldResult{}},
+		{BaseField: field11.GetBaseField(), Result: &KeyFieldResult{}},
+		{BaseField: field21.GetBaseField(), Result: &KeyFieldResult{Values: map[string]uint64{"b": 5, "a": 1}}},
+	}
+	ret := h.ApplyFacetProfile(profile, facets)
+	ids := make([]types.FacetId, len(ret))
+	for i, f := range ret {
+		ids[i] = f.Id
+	}
+	if !slices.Equal(ids, []types.FacetId{21, 20}) {
+		t.Fatalf("Expected facets 21, 20 got %v", ids)
+	}
+	kr := ret[0].Result.(*KeyFieldResult)
+	if len(kr.Sorted) != 1 || kr.Sorted[0].Value != "a" || kr.More != 1 {
+		t.Errorf("Expected the defaults to sort alphabetically with limit 1 got %+v", kr)
+	}
+	if field21.GetBaseField().ValueLimit != 0 {
+		t.Errorf("Expected the shared field to be left unchanged")
+	}
+}
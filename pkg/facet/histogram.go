@@ -0,0 +1,147 @@
+package facet
+
+import (
+	"math"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// Histogram modes selectable per facet with BaseField.Histogram
+const (
+	HistogramEqualWidth = "equal"
+	HistogramQuantile   = "quantile"
+	HistogramNice       = "nice"
+
+	DefaultHistogramBins = 10
+)
+
+type HistogramBin struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count uint64  `json:"count"`
+}
+
+type valueCount struct {
+	value int64
+	count uint64
+}
+
+// collectValueCounts returns the distinct values in the bucket span with the
+// number of items matching filterBM, all items are counted when filterBM is nil
+func collectValueCounts(buckets map[int]*ValueBucket, startBucket, endBucket int, filterBM *roaring.Bitmap) []valueCount {
+	ret := make([]valueCount, 0)
+	for bId := startBucket; bId <= endBucket; bId++ {
+		b, ok := buckets[bId]
+		if !ok || b == nil || b.merged == nil || b.merged.IsEmpty() {
+			continue
+		}
+		if filterBM != nil && !b.merged.Intersects(filterBM) {
+			continue
+		}
+		for _, ve := range b.entries {
+			var c uint64
+			if filterBM == nil {
+				c = ve.ids.GetCardinality()
+			} else {
+				c = ve.ids.AndCardinality(filterBM)
+			}
+			if c > 0 {
+				ret = append(ret, valueCount{value: ve.value, count: c})
+			}
+		}
+	}
+	return ret
+}
+
+// buildHistogram bins sorted value counts, scale converts the stored integer
+// values back to the facet domain (100 for decimal cents)
+func buildHistogram(values []valueCount, mode string, bins int, scale float64) []HistogramBin {
+	if len(values) == 0 {
+		return nil
+	}
+	if bins <= 0 {
+		bins = DefaultHistogramBins
+	}
+	minValue := float64(values[0].value) / scale
+	maxValue := float64(values[len(values)-1].value) / scale
+	if minValue == maxValue {
+		total := uint64(0)
+		for _, v := range values {
+			total += v.count
+		}
+		return []HistogramBin{{Min: minValue, Max: maxValue, Count: total}}
+	}
+	switch mode {
+	case HistogramQuantile:
+		return quantileBins(values, bins, scale)
+	case HistogramNice:
+		// steps smaller than the stored resolution would only create empty bins
+		step := max(niceNumber((maxValue-minValue)/float64(bins)), 1/scale)
+		start := math.Floor(minValue/step) * step
+		end := math.Ceil(maxValue/step) * step
+		return fixedWidthBins(values, start, step, int(math.Round((end-start)/step)), scale)
+	default:
+		step := (maxValue - minValue) / float64(bins)
+		return fixedWidthBins(values, minValue, step, bins, scale)
+	}
+}
+
+func fixedWidthBins(values []valueCount, start, step float64, bins int, scale float64) []HistogramBin {
+	ret := make([]HistogramBin, bins)
+	for i := range ret {
+		ret[i].Min = start + float64(i)*step
+		ret[i].Max = start + float64(i+1)*step
+	}
+	for _, v := range values {
+		idx := int((float64(v.value)/scale - start) / step)
+		// the max value belongs to the last bin
+		idx = max(0, min(idx, bins-1))
+		ret[idx].Count += v.count
+	}
+	return ret
+}
+
+// quantileBins splits the values in bins with roughly the same number of
+// items, a single value is never split over two bins
+func quantileBins(values []valueCount, bins int, scale float64) []HistogramBin {
+	total := uint64(0)
+	for _, v := range values {
+		total += v.count
+	}
+	ret := make([]HistogramBin, 0, bins)
+	per := float64(total) / float64(bins)
+	acc := uint64(0)
+	current := HistogramBin{Min: float64(values[0].value) / scale}
+	for i, v := range values {
+		current.Count += v.count
+		current.Max = float64(v.value) / scale
+		acc += v.count
+		isLast := i == len(values)-1
+		if !isLast && float64(acc) >= per*float64(len(ret)+1) && len(ret) < bins-1 {
+			ret = append(ret, current)
+			current = HistogramBin{Min: float64(values[i+1].value) / scale}
+		}
+	}
+	return append(ret, current)
+}
+
+// niceNumber rounds value to a 1, 2 or 5 * 10^n step (Heckbert)
+func niceNumber(value float64) float64 {
+	if value <= 0 {
+		return 1
+	}
+	exp := math.Floor(math.Log10(value))
+	fraction := value / math.Pow(10, exp)
+	var nice float64
+	switch {
+	case fraction < 1.5:
+		nice = 1
+	case fraction < 3:
+		nice = 2
+	case fraction < 7:
+		nice = 5
+	default:
+		nice = 10
+	}
+	return nice * math.Pow(10, exp)
+}
@@ -0,0 +1,91 @@
+package facet
+
+import (
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func histogramTestField(mode string, bins int) *IntegerField {
+	field := EmptyIntegerField(&types.BaseField{Id: 1, Searchable: true, Histogram: mode, HistogramBins: bins})
+	for i := 1; i <= 100; i++ {
+		field.AddValueLink(i, types.ItemId(i))
+	}
+	// skewed tail
+	for i := 101; i <= 110; i++ {
+		field.AddValueLink(1000, types.ItemId(i))
+	}
+	return field
+}
+
+func histogramTotal(bins []HistogramBin) uint64 {
+	total := uint64(0)
+	for _, b := range bins {
+		total += b.Count
+	}
+	return total
+}
+
+func TestHistogramEqualWidth(t *testing.T) {
+	field := histogramTestField(HistogramEqualWidth, 4)
+	bins := field.GetHistogram(nil)
+	if len(bins) != 4 {
+		t.Fatalf("Expected 4 bins, got %v", bins)
+	}
+	if bins[0].Min != 1 || bins[3].Max != 1000 {
+		t.Errorf("Unexpected bin edges %v", bins)
+	}
+	if bins[0].Count != 100 || bins[3].Count != 10 {
+		t.Errorf("Unexpected counts %v", bins)
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	field := histogramTestField(HistogramQuantile, 4)
+	bins := field.GetHistogram(nil)
+	if len(bins) != 4 {
+		t.Fatalf("Expected 4 bins, got %v", bins)
+	}
+	if histogramTotal(bins) != 110 {
+		t.Errorf("Expected all 110 items counted, got %d", histogramTotal(bins))
+	}
+	for _, b := range bins[:3] {
+		if b.Count < 20 || b.Count > 30 {
+			t.Errorf("Expected roughly equal bins, got %v", bins)
+		}
+	}
+}
+
+func TestHistogramNice(t *testing.T) {
+	field := histogramTestField(HistogramNice, 5)
+	bins := field.GetHistogram(nil)
+	for _, b := range bins {
+		width := b.Max - b.Min
+		if width != 200 {
+			t.Errorf("Expected nice width of 200, got %v", b)
+		}
+	}
+	if bins[0].Min != 0 || bins[len(bins)-1].Max != 1000 {
+		t.Errorf("Unexpected nice edges %v", bins)
+	}
+	if histogramTotal(bins) != 110 {
+		t.Errorf("Expected all 110 items counted, got %d", histogramTotal(bins))
+	}
+}
+
+func TestHistogramFiltered(t *testing.T) {
+	field := EmptyDecimalField(&types.BaseField{Id: 2, Searchable: true, Histogram: HistogramEqualWidth, HistogramBins: 2})
+	field.AddValueLink(10.0, 1)
+	field.AddValueLink(20.0, 2)
+	field.AddValueLink(30.0, 3)
+	ids := types.NewItemList()
+	ids.AddId(1)
+	ids.AddId(2)
+	bins := field.GetHistogram(ids)
+	if len(bins) != 2 || bins[0].Min != 10 || bins[1].Max != 20 {
+		t.Fatalf("Expected 2 bins between 10 and 20, got %v", bins)
+	}
+	if histogramTotal(bins) != 2 {
+		t.Errorf("Expected only matching items counted, got %v", bins)
+	}
+}
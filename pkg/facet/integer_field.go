@@ -2,7 +2,7 @@ package facet
 
 import (
 	"log"
-	"strconv"
+	"slices"
 
 	"github.com/RoaringBitmap/roaring/v2"
 	"github.com/matst80/slask-finder/pkg/types"
@@ -10,13 +10,16 @@ import (
 
 type IntegerFieldResult struct {
 	//	Count   uint   `json:"count,omitempty"`
-	Min     int    `json:"min"`
-	Max     int    `json:"max"`
-	Buckets []uint `json:"buckets,omitempty"`
+	Min       int            `json:"min"`
+	Max       int            `json:"max"`
+	Buckets   []uint         `json:"buckets,omitempty"`
+	Histogram []HistogramBin `json:"histogram,omitempty"`
+	Ranges    []RangeCount   `json:"ranges,omitempty"`
+	Stats     *NumericStats  `json:"stats,omitempty"`
 }
 
 func (k *IntegerFieldResult) HasValues() bool {
-	return k.Min < k.Max
+	return k.Min < k.Max || len(k.Ranges) > 0
 }
 
 const (
@@ -64,8 +67,10 @@ type IntegerField struct {
 	*types.BaseField
 	*NumberRange[int]
 	buckets   map[int]*ValueBucket // optimized bucket structure (integer domain already)
-	AllValues map[uint32]int
-	Count     int `json:"count"`
+	AllValues map[uint32][]int
+	// items are the items with at least one value, Count is the number of values
+	items *roaring.Bitmap
+	Count int `json:"count"`
 }
 
 func (f *IntegerField) IsExcludedFromFacets() bool {
@@ -85,15 +90,14 @@ func (f *IntegerField) GetExtents(matchIds *types.ItemList) *IntegerFieldResult
 	if matchIds == nil || matchIds.Len() == 0 {
 		return &IntegerFieldResult{Min: f.Min, Max: f.Max}
 	}
-	// Full coverage (all known value items included)
-	if int(matchIds.Cardinality()) == f.Count {
-		return &IntegerFieldResult{Min: f.Min, Max: f.Max}
-	}
-
 	bm := matchIds.Bitmap()
 	if bm == nil || bm.IsEmpty() {
 		return &IntegerFieldResult{Min: 0, Max: 0}
 	}
+	// Full coverage (all items with values included)
+	if coversAll(bm, f.items) {
+		return &IntegerFieldResult{Min: f.Min, Max: f.Max}
+	}
 
 	startBucket := GetBucket(f.Min)
 	endBucket := GetBucket(f.Max)
@@ -110,8 +114,24 @@ func (f *IntegerField) GetExtents(matchIds *types.ItemList) *IntegerFieldResult
 	return &IntegerFieldResult{Min: int(minValue), Max: int(maxValue)}
 }
 
+// GetHistogram bins the values of the matching items using the histogram mode
+// of the facet, nil or empty matchIds counts all items
+func (f *IntegerField) GetHistogram(matchIds *types.ItemList) []HistogramBin {
+	if f.Count == 0 || f.Histogram == "" {
+		return nil
+	}
+	var bm *roaring.Bitmap
+	if matchIds != nil && matchIds.Len() > 0 {
+		bm = matchIds.Bitmap()
+	}
+	values := collectValueCounts(f.buckets, GetBucket(f.Min), GetBucket(f.Max), bm)
+	return buildHistogram(values, f.Histogram, f.HistogramBins, 1)
+}
+
+// ValueForItemId returns the lowest value of the item
 func (f *IntegerField) ValueForItemId(id uint32) *int {
-	if v, ok := f.AllValues[id]; ok {
+	if values, ok := f.AllValues[id]; ok && len(values) > 0 {
+		v := slices.Min(values)
 		return &v
 	}
 	return nil
@@ -151,6 +171,9 @@ func (f *IntegerField) MatchesRange(minValue int, maxValue int) *types.ItemList
 	if maxValue > f.Max {
 		maxValue = f.Max
 	}
+	if minValue > maxValue {
+		return types.NewItemList()
+	}
 
 	minBucket := GetBucket(minValue)
 	maxBucket := GetBucket(maxValue)
@@ -203,14 +226,16 @@ func (f *IntegerField) MatchesRange(minValue int, maxValue int) *types.ItemList
 }
 
 func (f *IntegerField) Match(input any) *types.ItemList {
-	value, ok := input.(types.RangeFilter)
-	if ok {
-		min, minOk := value.Min.(float64)
-		max, maxOk := value.Max.(float64)
-
-		if minOk && maxOk {
-			return f.MatchesRange(int(min), int(max))
+	if names, ok := input.(types.StringFilterValue); ok {
+		return f.MatchRangeNames(names)
+	}
+	if value, ok := input.(types.RangeFilter); ok {
+		ids := f.MatchRangeFilter(value)
+		if ids == nil && value.Not {
+			// the negation needs the actual items to exclude
+			return allBucketIds(f.buckets)
 		}
+		return ids
 	}
 
 	return types.NewItemList()
@@ -237,6 +262,9 @@ func (f *IntegerField) GetValues() []any {
 }
 
 func (f *IntegerField) addValueLink(value int, itemId uint32) {
+	if slices.Contains(f.AllValues[itemId], value) {
+		return
+	}
 	if f.Count == 0 {
 		f.Min, f.Max = value, value
 	} else {
@@ -248,7 +276,8 @@ func (f *IntegerField) addValueLink(value int, itemId uint32) {
 		}
 	}
 	f.Count++
-	f.AllValues[itemId] = value
+	f.AllValues[itemId] = append(f.AllValues[itemId], value)
+	f.items.Add(itemId)
 	bId := GetBucket(value)
 	b, ok := f.buckets[bId]
 	if !ok {
@@ -258,70 +287,65 @@ func (f *IntegerField) addValueLink(value int, itemId uint32) {
 	b.AddValue(int64(value), itemId)
 }
 
+// AddValueLink indexes all values of the item and removes the values it no
+// longer has, a range filter matches the item when any of its values is in the range
 func (f *IntegerField) AddValueLink(data any, itemId types.ItemId) bool {
 	if !f.Searchable {
 		return false
 	}
-	id := uint32(itemId)
-	switch value := data.(type) {
-	case int:
-		f.addValueLink(value, id)
-		return true
-	case float64:
-		f.addValueLink(int(value), id)
-		return true
-	case string:
-		intValue, err := strconv.Atoi(value)
-		if err == nil {
-			f.addValueLink(intValue, id)
-			return true
+	values, ok := numericValues(data)
+	if !ok {
+		switch data.(type) {
+		case string, []string:
+		default:
+			log.Printf("'%v': AddValueLink: %T %d (%s)", data, data, f.Id, f.Name)
 		}
-	case []string:
-		if len(value) > 0 {
-			first := value[0]
-			intValue, err := strconv.Atoi(first)
-			if err == nil {
-				f.addValueLink(intValue, id)
-				return true
-			}
+		return false
+	}
+	id := uint32(itemId)
+	next := make([]int, len(values))
+	for i, value := range values {
+		next[i] = int(value)
+	}
+	for _, value := range slices.Clone(f.AllValues[id]) {
+		if !slices.Contains(next, value) {
+			f.removeValueLink(value, id)
 		}
-	default:
-		log.Printf("'%v': AddValueLink: %T %d (%s)", data, value, f.Id, f.Name)
 	}
-
-	return false
+	for _, value := range next {
+		f.addValueLink(value, id)
+	}
+	return true
 }
 
 func (f *IntegerField) removeValueLink(value int, id uint32) {
-	delete(f.AllValues, id)
-	bId := GetBucket(value)
-	if b, ok := f.buckets[bId]; ok {
+	values := f.AllValues[id]
+	idx := slices.Index(values, value)
+	if idx == -1 {
+		return
+	}
+	if values = slices.Delete(values, idx, idx+1); len(values) == 0 {
+		delete(f.AllValues, id)
+		f.items.Remove(id)
+	} else {
+		f.AllValues[id] = values
+	}
+	if b, ok := f.buckets[GetBucket(value)]; ok {
 		b.RemoveValue(int64(value), id)
-		if f.Count > 0 {
-			f.Count--
-		}
-		// Lazy: not rebuilding merged; acceptable for low deletion rate.
+	}
+	if f.Count > 0 {
+		f.Count--
 	}
 }
 
 func (f *IntegerField) RemoveValueLink(data any, itemId types.ItemId) {
+	values, ok := numericValues(data)
+	if !ok {
+		return
+	}
 	id := uint32(itemId)
-	switch value := data.(type) {
-	case int:
-		f.removeValueLink(value, id)
-	case float64:
+	for _, value := range values {
 		f.removeValueLink(int(value), id)
-	case string:
-		intValue, err := strconv.Atoi(value)
-		if err == nil {
-			f.removeValueLink(intValue, id)
-		}
-	case []string:
-		if len(value) > 0 {
-			if intValue, err := strconv.Atoi(value[0]); err == nil {
-				f.removeValueLink(intValue, id)
-			}
-		}
 	}
 }
 
@@ -336,7 +360,8 @@ func (IntegerField) GetType() uint {
 func EmptyIntegerField(field *types.BaseField) *IntegerField {
 	return &IntegerField{
 		BaseField:   field,
-		AllValues:   map[uint32]int{},
+		AllValues:   map[uint32][]int{},
+		items:       roaring.NewBitmap(),
 		NumberRange: &NumberRange[int]{Min: 0, Max: 0},
 		buckets:     map[int]*ValueBucket{},
 	}
@@ -3,6 +3,7 @@ package facet
 import (
 	"fmt"
 	"log"
+	"slices"
 	"strings"
 
 	"github.com/matst80/slask-finder/pkg/types"
@@ -11,6 +12,11 @@ import (
 type KeyField struct {
 	*types.BaseField
 	Keys map[string]*types.ItemList
+	// folded maps lower case values to the indexed spelling for CaseFold
+	folded map[string]string
+	// indexed maps the trimmed raw values to the keys they were indexed as,
+	// removals use them so items are not left behind when the rules change
+	indexed map[string][]string
 }
 
 func (f KeyField) GetType() uint {
@@ -62,6 +68,11 @@ func (f *KeyField) match(value string) *types.ItemList {
 	if ok {
 		return ids
 	}
+	if normalized := f.normalize(value); normalized != value {
+		if ids, ok = f.Keys[normalized]; ok {
+			return ids
+		}
+	}
 
 	return &types.ItemList{}
 }
@@ -135,23 +146,48 @@ func (f KeyField) GetBaseField() *types.BaseField {
 	return f.BaseField
 }
 
-func (f *KeyField) addString(value string, id types.ItemId) {
-	v := strings.TrimSpace(value)
+// normalize applies the value rules of the field, values differing only by
+// case resolve to the first indexed spelling when folded
+func (f *KeyField) normalize(value string) string {
+	v := f.Normalize.Apply(value)
+	if v == "" || f.Normalize == nil || f.Normalize.Case != types.CaseFold {
+		return v
+	}
+	if existing, ok := f.folded[strings.ToLower(v)]; ok {
+		return existing
+	}
+	return v
+}
+
+// indexKey is the key a raw value is indexed as
+func (f *KeyField) indexKey(value string) string {
+	v := f.normalize(value)
 	if v == "" {
-		return
+		return ""
 	}
 	if f.Type == "stock" {
 		if v == "0" {
-			return
+			return ""
 		}
-		v = "Ja"
+		return "Ja"
 	} else if f.Type == "bool" {
 		low := strings.ToLower(v)
 		if low == "no" || low == "nej" || low == "" || low == "false" || low == "x" || low == "saknas" {
-			v = "Nej"
-		} else {
-			v = "Ja"
+			return "Nej"
 		}
+		return "Ja"
+	}
+	return v
+}
+
+func (f *KeyField) addString(value string, id types.ItemId) {
+	v := f.indexKey(value)
+	if v == "" {
+		return
+	}
+	raw := strings.TrimSpace(value)
+	if keys := f.indexed[raw]; !slices.Contains(keys, v) {
+		f.indexed[raw] = append(keys, v)
 	}
 
 	if k, ok := f.Keys[v]; ok {
@@ -160,21 +196,33 @@ func (f *KeyField) addString(value string, id types.ItemId) {
 		k := types.NewItemList()
 		k.AddId(uint32(id))
 		f.Keys[v] = k
+		if f.Normalize != nil && f.Normalize.Case == types.CaseFold {
+			f.folded[strings.ToLower(v)] = v
+		}
 	}
 
 }
 
+// removeString removes the item from the keys the value has been indexed as
+// with the current and earlier rules
 func (f *KeyField) removeString(value string, id types.ItemId) {
-	v := strings.TrimSpace(value)
-	if v == "" {
-		return
+	keys, ok := f.indexed[strings.TrimSpace(value)]
+	if !ok {
+		keys = []string{f.indexKey(value)}
 	}
-
-	if k, ok := f.Keys[v]; ok {
-		k.RemoveId(uint32(id))
-
-		if k.IsEmpty() {
-			delete(f.Keys, v)
+	for _, v := range keys {
+		if v == "" {
+			continue
+		}
+		if k, ok := f.Keys[v]; ok {
+			k.RemoveId(uint32(id))
+
+			if k.IsEmpty() {
+				delete(f.Keys, v)
+				if f.folded[strings.ToLower(v)] == v {
+					delete(f.folded, strings.ToLower(v))
+				}
+			}
 		}
 	}
 }
@@ -194,6 +242,11 @@ func (f KeyField) AddValueLink(data any, itemId types.ItemId) bool {
 	case int64:
 		f.addString(fmt.Sprintf("%d", typed), itemId)
 		return true
+	case []float64:
+		for _, v := range typed {
+			f.addString(fmt.Sprintf("%f", v), itemId)
+		}
+		return true
 	case []string:
 
 		for _, v := range typed {
@@ -229,6 +282,10 @@ func (f KeyField) RemoveValueLink(data any, id types.ItemId) {
 		f.addString(fmt.Sprintf("%d", typed), id)
 	case int64:
 		f.removeString(fmt.Sprintf("%d", typed), id)
+	case []float64:
+		for _, v := range typed {
+			f.removeString(fmt.Sprintf("%f", v), id)
+		}
 	case []any:
 		for _, v := range typed {
 			if str, ok := v.(string); ok {
@@ -268,5 +325,7 @@ func EmptyKeyValueField(field *types.BaseField) *KeyField {
 	return &KeyField{
 		BaseField: field,
 		Keys:      map[string]*types.ItemList{},
+		folded:    map[string]string{},
+		indexed:   map[string][]string{},
 	}
 }
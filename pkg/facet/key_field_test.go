@@ -0,0 +1,83 @@
+package facet
+
+import (
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func TestKeyFieldNormalization(t *testing.T) {
+	f := EmptyKeyValueField(&types.BaseField{
+		Id:         1,
+		Searchable: true,
+		Normalize:  &types.ValueNormalization{Case: types.CaseFold, Units: true},
+	})
+	f.AddValueLink("Samsung", 1)
+	f.AddValueLink("SAMSUNG ", 2)
+	f.AddValueLink("samsung", 3)
+	f.AddValueLink("1000 GB", 4)
+	f.AddValueLink("1TB", 5)
+
+	if len(f.Keys) != 2 {
+		t.Fatalf("Expected 2 values got %v", f.Keys)
+	}
+	if ids, ok := f.Keys["Samsung"]; !ok || ids.Len() != 3 {
+		t.Errorf("Expected the first spelling to hold all items")
+	}
+	if ids, ok := f.Keys["1 TB"]; !ok || ids.Len() != 2 {
+		t.Errorf("Expected units to be normalized")
+	}
+	if f.Match("samsung").Len() != 3 {
+		t.Errorf("Expected filter values to be normalized")
+	}
+
+	for _, id := range []types.ItemId{1, 2, 3} {
+		f.RemoveValueLink("SAMSUNG", id)
+	}
+	if _, ok := f.Keys["Samsung"]; ok {
+		t.Errorf("Expected the value to be removed")
+	}
+	f.AddValueLink("samsung", 6)
+	if _, ok := f.Keys["samsung"]; !ok {
+		t.Errorf("Expected the next spelling to be used once the value is removed")
+	}
+}
+
+func TestKeyFieldDisplayNames(t *testing.T) {
+	field := &types.BaseField{
+		Id:           1,
+		ValueSorting: types.ValueSortAlpha,
+		ValueNames:   map[string]string{"A1": "Zebra", "B2": "Apple"},
+	}
+	result := NewKeyFieldResult(field, map[string]uint64{"A1": 1, "B2": 2})
+	if result.Sorted[0].Value != "B2" || result.Sorted[0].Name != "Apple" {
+		t.Errorf("Expected values sorted by display name got %+v", result.Sorted)
+	}
+	search := SearchValues(field, map[string]uint64{"A1": 1, "B2": 2}, "zeb", 0, 10)
+	if search.Total != 1 || search.Values[0].Value != "A1" {
+		t.Errorf("Expected value search to match display names got %+v", search.Values)
+	}
+}
+
+func TestKeyFieldRemoveAfterRuleChange(t *testing.T) {
+	field := &types.BaseField{
+		Id:         1,
+		Searchable: true,
+		Normalize:  &types.ValueNormalization{Case: types.CaseLower},
+	}
+	f := EmptyKeyValueField(field)
+	f.AddValueLink("Samsung Electronics", 1)
+	f.UpdateBaseField(&types.BaseField{
+		Searchable: true,
+		Normalize:  &types.ValueNormalization{Aliases: map[string]string{"Samsung Electronics": "Samsung"}},
+	})
+	f.AddValueLink("Samsung Electronics", 2)
+	if _, ok := f.Keys["Samsung"]; !ok {
+		t.Fatalf("Expected the new rules to be used got %v", f.Keys)
+	}
+	f.RemoveValueLink("Samsung Electronics", 1)
+	f.RemoveValueLink("Samsung Electronics", 2)
+	if len(f.Keys) != 0 {
+		t.Errorf("Expected the items to be removed from the old and new keys got %v", f.Keys)
+	}
+}
@@ -25,6 +25,11 @@ func (i *FacetItemHandler) RemoveDuplicateCategoryFilters(stringFilters []types.
 		if fld.Value == nil {
 			continue
 		}
+		switch i.Facets[fld.Id].(type) {
+		case *IntegerField, *DecimalField, *DateField, *BoolField:
+			// named range and boolean filters, matched separately
+			continue
+		}
 
 		if f, ok := i.GetKeyFacet(fld.Id); ok {
 
@@ -88,11 +93,52 @@ func (i *FacetItemHandler) Match(search *types.Filters, qm *types.QueryMerger) {
 		}
 	}
 
+	// numeric and date facets are filtered by the names of their predefined
+	// ranges, boolean facets by true or false
+	for _, fld := range search.StringFilter {
+		f, ok := i.Facets[fld.Id]
+		if !ok || len(fld.Value) == 0 {
+			continue
+		}
+		switch f.(type) {
+		case *IntegerField, *DecimalField, *DateField, *BoolField:
+			if fld.Not {
+				qm.Exclude(func() *types.ItemList {
+					return f.Match(fld.Value)
+				})
+			} else {
+				qm.Add(SpannedFetcher(func() *types.ItemList {
+					return f.Match(fld.Value)
+				}, "Match named range"))
+			}
+		}
+	}
+
+	for _, fld := range search.PathFilter {
+		if f, ok := i.GetTreeFacet(fld.Id); ok {
+			if fld.Not {
+				qm.Exclude(func() *types.ItemList {
+					return f.Match(fld.Path)
+				})
+			} else {
+				qm.Add(SpannedFetcher(func() *types.ItemList {
+					return f.Match(fld.Path)
+				}, "Match path filter"))
+			}
+		}
+	}
+
 	for _, fld := range search.RangeFilter {
 		if f, ok := i.Facets[fld.Id]; ok && f != nil {
-			qm.Add(SpannedFetcher(func() *types.ItemList {
-				return f.Match(fld)
-			}, "Match range filter"))
+			if fld.Not {
+				qm.Exclude(func() *types.ItemList {
+					return f.Match(fld)
+				})
+			} else {
+				qm.Add(SpannedFetcher(func() *types.ItemList {
+					return f.Match(fld)
+				}, "Match range filter"))
+			}
 		}
 	}
 
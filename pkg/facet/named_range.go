@@ -0,0 +1,117 @@
+package facet
+
+import (
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+type RangeCount struct {
+	Name  string   `json:"name"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+	Count uint64   `json:"count"`
+}
+
+// allBucketIds returns every item with a value in the buckets
+func allBucketIds(buckets map[int]*ValueBucket) *types.ItemList {
+	bitmaps := make([]*roaring.Bitmap, 0, len(buckets))
+	for _, b := range buckets {
+		if b.merged != nil && !b.merged.IsEmpty() {
+			bitmaps = append(bitmaps, b.merged)
+		}
+	}
+	return types.FromBitmap(roaring.FastOr(bitmaps...))
+}
+
+// countNamedRanges counts the matching items in each configured range, all
+// items are counted when baseIds is empty
+func countNamedRanges(ranges []types.NamedRange, match func(types.NamedRange) *types.ItemList, baseIds *types.ItemList) []RangeCount {
+	if len(ranges) == 0 {
+		return nil
+	}
+	returnAll := baseIds == nil || baseIds.Len() == 0
+	ret := make([]RangeCount, 0, len(ranges))
+	for _, r := range ranges {
+		ids := match(r)
+		var count uint64
+		if returnAll {
+			count = ids.Cardinality()
+		} else {
+			count = ids.IntersectionLen(baseIds)
+		}
+		ret = append(ret, RangeCount{
+			Name:  r.Name,
+			Min:   r.Min,
+			Max:   r.Max,
+			Count: count,
+		})
+	}
+	return ret
+}
+
+// matchNamedRanges returns the items in any of the named ranges
+func matchNamedRanges(field *types.BaseField, names []string, match func(types.NamedRange) *types.ItemList) *types.ItemList {
+	ret := types.NewItemList()
+	for _, name := range names {
+		if r, ok := field.GetNamedRange(name); ok {
+			ret.Merge(match(r))
+		}
+	}
+	return ret
+}
+
+func (f *IntegerField) matchNamedRange(r types.NamedRange) *types.ItemList {
+	if f.Count == 0 {
+		return types.NewItemList()
+	}
+	minValue, maxValue := f.Min, f.Max
+	if r.Min != nil {
+		minValue = int(*r.Min)
+	}
+	if r.Max != nil {
+		maxValue = int(*r.Max)
+	}
+	ids := f.MatchesRange(minValue, maxValue)
+	if ids == nil {
+		return allBucketIds(f.buckets)
+	}
+	return ids
+}
+
+// GetRangeCounts counts the items in each of the named ranges of the facet
+func (f *IntegerField) GetRangeCounts(baseIds *types.ItemList) []RangeCount {
+	return countNamedRanges(f.Ranges, f.matchNamedRange, baseIds)
+}
+
+// MatchRangeNames returns the items in any of the named ranges
+func (f *IntegerField) MatchRangeNames(names []string) *types.ItemList {
+	return matchNamedRanges(f.BaseField, names, f.matchNamedRange)
+}
+
+func (f *DecimalField) matchNamedRange(r types.NamedRange) *types.ItemList {
+	if f.Count == 0 {
+		return types.NewItemList()
+	}
+	minValue, maxValue := f.Min, f.Max
+	if r.Min != nil {
+		minValue = *r.Min
+	}
+	if r.Max != nil {
+		maxValue = *r.Max
+	}
+	ids := f.MatchesRange(minValue, maxValue)
+	if ids == nil {
+		return allBucketIds(f.buckets)
+	}
+	return ids
+}
+
+// GetRangeCounts counts the items in each of the named ranges of the facet
+func (f *DecimalField) GetRangeCounts(baseIds *types.ItemList) []RangeCount {
+	return countNamedRanges(f.Ranges, f.matchNamedRange, baseIds)
+}
+
+// MatchRangeNames returns the items in any of the named ranges
+func (f *DecimalField) MatchRangeNames(names []string) *types.ItemList {
+	return matchNamedRanges(f.BaseField, names, f.matchNamedRange)
+}
@@ -0,0 +1,61 @@
+package facet
+
+import (
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+func TestIntegerFieldNamedRanges(t *testing.T) {
+	field := EmptyIntegerField(&types.BaseField{Id: 1, Searchable: true, Ranges: []types.NamedRange{
+		{Name: "0-999", Max: ptr(999)},
+		{Name: "1000-4999", Min: ptr(1000), Max: ptr(4999)},
+		{Name: "5000+", Min: ptr(5000)},
+	}})
+	field.AddValueLink(500, 1)
+	field.AddValueLink(999, 2)
+	field.AddValueLink(1000, 3)
+	field.AddValueLink(7000, 4)
+
+	counts := field.GetRangeCounts(nil)
+	expected := []uint64{2, 1, 1}
+	for i, c := range counts {
+		if c.Count != expected[i] {
+			t.Errorf("Range %s: expected %d got %d", c.Name, expected[i], c.Count)
+		}
+	}
+
+	base := types.NewItemList()
+	base.AddId(1)
+	base.AddId(4)
+	counts = field.GetRangeCounts(base)
+	if counts[0].Count != 1 || counts[1].Count != 0 || counts[2].Count != 1 {
+		t.Errorf("Unexpected filtered counts %v", counts)
+	}
+
+	ids := field.Match(types.StringFilterValue{"0-999", "5000+", "missing"})
+	if ids.Len() != 3 || ids.Contains(3) {
+		t.Errorf("Expected items 1, 2 and 4, got %v", ids.ToSlice())
+	}
+}
+
+func TestDecimalFieldNamedRanges(t *testing.T) {
+	field := EmptyDecimalField(&types.BaseField{Id: 2, Searchable: true, Ranges: []types.NamedRange{
+		{Name: "small", Max: ptr(9.99)},
+		{Name: "all"},
+	}})
+	field.AddValueLink(5.5, 1)
+	field.AddValueLink(10.0, 2)
+
+	counts := field.GetRangeCounts(nil)
+	if counts[0].Count != 1 || counts[1].Count != 2 {
+		t.Errorf("Unexpected counts %v", counts)
+	}
+	if ids := field.MatchRangeNames([]string{"small"}); ids.Len() != 1 || !ids.Contains(1) {
+		t.Errorf("Expected item 1, got %v", ids.ToSlice())
+	}
+}
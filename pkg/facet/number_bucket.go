@@ -96,7 +96,8 @@ func (b *ValueBucket) AddValue(valueCents int64, itemId uint32) {
 	b.merged.Add(itemId)
 }
 
-// RemoveValue removes (valueCents, itemId) from the bucket (lazy merged maintenance)
+// RemoveValue removes (valueCents, itemId) from the bucket, the item is kept in
+// merged while it has other values in the bucket
 func (b *ValueBucket) RemoveValue(valueCents int64, itemId uint32) {
 	i := sort.Search(len(b.entries), func(i int) bool {
 		return b.entries[i].value >= valueCents
@@ -105,8 +106,12 @@ func (b *ValueBucket) RemoveValue(valueCents int64, itemId uint32) {
 		return
 	}
 	b.entries[i].ids.Remove(itemId)
-	// NOTE: merged is not decremented (lazy). If high deletion accuracy is needed,
-	// rebuild merged when many removals accumulate.
+	for _, ve := range b.entries {
+		if ve.ids.Contains(itemId) {
+			return
+		}
+	}
+	b.merged.Remove(itemId)
 }
 
 // RangeUnion unions all ids whose value lies in [minCents, maxCents] (inclusive) into acc.
@@ -3,7 +3,7 @@ package facet
 import (
 	"log"
 	"math"
-	"strconv"
+	"slices"
 
 	"github.com/RoaringBitmap/roaring/v2"
 	"github.com/matst80/slask-finder/pkg/types"
@@ -17,18 +17,23 @@ type DecimalField struct {
 	*types.BaseField
 	*NumberRange[float64]                      // Public min/max in original float domain
 	buckets               map[int]*ValueBucket // Coarse bucket -> sorted value entries (integer cents)
-	AllValuesCents        map[uint32]int64     // Raw value per item in integer cents
-	Count                 int                  `json:"count"`
+	AllValuesCents        map[uint32][]int64   // Raw values per item in integer cents
+	// items are the items with at least one value, Count is the number of values
+	items *roaring.Bitmap
+	Count int `json:"count"`
 }
 
 type DecimalFieldResult struct {
 	//Count uint    `json:"count,omitempty"`
-	Min float64 `json:"min"`
-	Max float64 `json:"max"`
+	Min       float64        `json:"min"`
+	Max       float64        `json:"max"`
+	Histogram []HistogramBin `json:"histogram,omitempty"`
+	Ranges    []RangeCount   `json:"ranges,omitempty"`
+	Stats     *NumericStats  `json:"stats,omitempty"`
 }
 
 func (k *DecimalFieldResult) HasValues() bool {
-	return k.Min < k.Max
+	return k.Min < k.Max || len(k.Ranges) > 0
 }
 
 func (f *DecimalField) GetExtents(matchIds *types.ItemList) *DecimalFieldResult {
@@ -44,15 +49,14 @@ func (f *DecimalField) GetExtents(matchIds *types.ItemList) *DecimalFieldResult
 	if matchIds.Len() == 0 {
 		return &DecimalFieldResult{Min: 0, Max: 0}
 	}
-	// Full coverage (all items that have a value)
-	if int(matchIds.Cardinality()) == f.Count {
-		return &DecimalFieldResult{Min: f.Min, Max: f.Max}
-	}
-
 	bm := matchIds.Bitmap()
 	if bm == nil || bm.IsEmpty() {
 		return &DecimalFieldResult{Min: 0, Max: 0}
 	}
+	// Full coverage (all items that have a value)
+	if coversAll(bm, f.items) {
+		return &DecimalFieldResult{Min: f.Min, Max: f.Max}
+	}
 
 	// Recover bucket span using the stored min/max (convert to the same cents representation used in buckets)
 	minCentsField := int64(math.Round(f.Min * 100.0))
@@ -76,6 +80,22 @@ func (f *DecimalField) GetExtents(matchIds *types.ItemList) *DecimalFieldResult
 	}
 }
 
+// GetHistogram bins the values of the matching items using the histogram mode
+// of the facet, nil or empty matchIds counts all items
+func (f *DecimalField) GetHistogram(matchIds *types.ItemList) []HistogramBin {
+	if f.Count == 0 || f.Histogram == "" {
+		return nil
+	}
+	var bm *roaring.Bitmap
+	if matchIds != nil && matchIds.Len() > 0 {
+		bm = matchIds.Bitmap()
+	}
+	startBucket := GetBucketFromCents(int64(math.Round(f.Min * 100.0)))
+	endBucket := GetBucketFromCents(int64(math.Round(f.Max * 100.0)))
+	values := collectValueCounts(f.buckets, startBucket, endBucket, bm)
+	return buildHistogram(values, f.Histogram, f.HistogramBins, 100)
+}
+
 func (f *DecimalField) IsExcludedFromFacets() bool {
 	return f.HideFacet || f.BaseField.InternalOnly
 }
@@ -88,20 +108,27 @@ func (f *DecimalField) MatchesRange(minValue float64, maxValue float64) *types.I
 	if minValue > maxValue || f.Count == 0 {
 		return types.NewItemList()
 	}
+	// Inclusive outward rounding to cents (matching previous logic)
+	minC := floatToInt64(math.Floor(minValue*100.0 + 0.0000001))
+	maxC := floatToInt64(math.Ceil(maxValue*100.0 - 0.0000001))
+	return f.matchCents(minC, maxC)
+}
+
+// matchCents returns the items with values between the inclusive bounds in
+// cents, nil when the bounds cover all values
+func (f *DecimalField) matchCents(minC, maxC int64) *types.ItemList {
+	if minC > maxC || f.Count == 0 {
+		return types.NewItemList()
+	}
+	storedMin := int64(math.Round(f.Min * 100.0))
+	storedMax := int64(math.Round(f.Max * 100.0))
 	// Full range shortcut (return nil sentinel meaning "all")
-	if minValue <= f.Min && maxValue >= f.Max {
+	if minC <= storedMin && maxC >= storedMax {
 		return nil
 	}
 	// Clamp to stored extents to avoid unnecessary bucket scans
-	if minValue < f.Min {
-		minValue = f.Min
-	}
-	if maxValue > f.Max {
-		maxValue = f.Max
-	}
-	// Inclusive outward rounding to cents (matching previous logic)
-	minC := int64(math.Floor(minValue*100.0 + 0.0000001))
-	maxC := int64(math.Ceil(maxValue*100.0 - 0.0000001))
+	minC = max(minC, storedMin)
+	maxC = min(maxC, storedMax)
 	if minC > maxC {
 		return types.NewItemList()
 	}
@@ -156,13 +183,16 @@ func (f *DecimalField) MatchesRange(minValue float64, maxValue float64) *types.I
 }
 
 func (f *DecimalField) Match(input any) *types.ItemList {
-	value, ok := input.(types.RangeFilter)
-	if ok {
-		min, minOk := value.Min.(float64)
-		max, maxOk := value.Max.(float64)
-		if minOk && maxOk {
-			return f.MatchesRange(min, max)
+	if names, ok := input.(types.StringFilterValue); ok {
+		return f.MatchRangeNames(names)
+	}
+	if value, ok := input.(types.RangeFilter); ok {
+		ids := f.MatchRangeFilter(value)
+		if ids == nil && value.Not {
+			// the negation needs the actual items to exclude
+			return allBucketIds(f.buckets)
 		}
+		return ids
 	}
 	return types.NewItemList()
 }
@@ -204,6 +234,9 @@ func (f *DecimalField) addValueLink(val float64, itemId uint32) bool {
 	}
 
 	cents := int64(math.Round(val * 100.0))
+	if slices.Contains(f.AllValuesCents[itemId], cents) {
+		return true
+	}
 
 	if f.Count == 0 {
 		f.Min, f.Max = val, val
@@ -215,7 +248,8 @@ func (f *DecimalField) addValueLink(val float64, itemId uint32) bool {
 		}
 	}
 	f.Count++
-	f.AllValuesCents[itemId] = cents
+	f.AllValuesCents[itemId] = append(f.AllValuesCents[itemId], cents)
+	f.items.Add(itemId)
 
 	bId := GetBucketFromCents(cents)
 	b, ok := f.buckets[bId]
@@ -228,57 +262,68 @@ func (f *DecimalField) addValueLink(val float64, itemId uint32) bool {
 	return true
 }
 
+// AddValueLink indexes all values of the item and removes the values it no
+// longer has, a range filter matches the item when any of its values is in the range
 func (f *DecimalField) AddValueLink(data any, itemId types.ItemId) bool {
 	if !f.Searchable {
 		return false
 	}
-	id := uint32(itemId)
-	switch value := data.(type) {
-	case int:
-		f.addValueLink(float64(value), id)
-		return true
-	case float64:
-		f.addValueLink(value, id)
-		return true
-	case string:
-		floatValue, err := strconv.ParseFloat(value, 64)
-		if err == nil {
-			f.addValueLink(float64(floatValue), id)
-			return true
+	values, ok := numericValues(data)
+	if !ok {
+		switch data.(type) {
+		case string, []string:
+		default:
+			log.Printf("'%v': AddValueLink: %T %d (%s)", data, data, f.Id, f.Name)
 		}
-	case []string:
-		if len(value) > 0 {
-			first := value[0]
-			floatValue, err := strconv.ParseFloat(first, 64)
-			if err == nil {
-				f.addValueLink(float64(floatValue), id)
-				return true
-			}
+		return false
+	}
+	id := uint32(itemId)
+	next := make([]int64, len(values))
+	for i, value := range values {
+		next[i] = int64(math.Round(value * 100.0))
+	}
+	for _, cents := range slices.Clone(f.AllValuesCents[id]) {
+		if !slices.Contains(next, cents) {
+			f.removeCents(cents, id)
 		}
-	default:
-		log.Printf("'%v': AddValueLink: %T %d (%s)", data, value, f.Id, f.Name)
 	}
-	return false
+	for _, value := range values {
+		f.addValueLink(value, id)
+	}
+	return true
 }
 
 func (f *DecimalField) RemoveValueLink(data any, itemId types.ItemId) {
-	val, ok := data.(float64)
+	values, ok := numericValues(data)
 	if !ok {
 		return
 	}
-	f.removeValueLink(val, uint32(itemId))
+	for _, value := range values {
+		f.removeValueLink(value, uint32(itemId))
+	}
 }
 
 func (f *DecimalField) removeValueLink(val float64, id uint32) {
-	cents := int64(math.Round(val * 100.0))
+	f.removeCents(int64(math.Round(val*100.0)), id)
+}
 
-	delete(f.AllValuesCents, id)
-	bId := GetBucketFromCents(cents)
-	if b, ok := f.buckets[bId]; ok {
+func (f *DecimalField) removeCents(cents int64, id uint32) {
+	values := f.AllValuesCents[id]
+	idx := slices.Index(values, cents)
+	if idx == -1 {
+		return
+	}
+	if values = slices.Delete(values, idx, idx+1); len(values) == 0 {
+		delete(f.AllValuesCents, id)
+		f.items.Remove(id)
+	} else {
+		f.AllValuesCents[id] = values
+	}
+	if b, ok := f.buckets[GetBucketFromCents(cents)]; ok {
 		b.RemoveValue(cents, id)
-		if f.Count > 0 {
-			f.Count--
-		}
+	}
+	if f.Count > 0 {
+		f.Count--
 	}
 }
 
@@ -299,7 +344,8 @@ func EmptyDecimalField(field *types.BaseField) *DecimalField {
 		BaseField:      field,
 		NumberRange:    &NumberRange[float64]{Min: 0, Max: 0},
 		buckets:        make(map[int]*ValueBucket),
-		AllValuesCents: make(map[uint32]int64),
+		AllValuesCents: make(map[uint32][]int64),
+		items:          roaring.NewBitmap(),
 		Count:          0,
 	}
 }
@@ -2,6 +2,7 @@ package facet
 
 import (
 	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/matst80/slask-finder/pkg/types"
@@ -136,3 +137,75 @@ func BenchmarkIntegerFieldGetExtents(b *testing.B) {
 		}
 	})
 }
+
+func TestIntegerFieldMultipleValues(t *testing.T) {
+	field := EmptyIntegerField(&types.BaseField{Id: 1, Name: "number", Searchable: true})
+	field.AddValueLink([]int{10, 50}, 1)
+	field.AddValueLink(30, 2)
+	field.AddValueLink(40, 3)
+	field.AddValueLink(40, 3)
+
+	if field.Count != 4 {
+		t.Errorf("expected 4 values, got %d", field.Count)
+	}
+	// as many ids as values but not all items with values
+	ext := field.GetExtents(types.FromSlice([]uint{2, 3, 99, 100}))
+	if ext.Min != 30 || ext.Max != 40 {
+		t.Errorf("expected extents 30-40, got %d-%d", ext.Min, ext.Max)
+	}
+	ext = field.GetExtents(types.FromSlice([]uint{1, 2, 3}))
+	if ext.Min != 10 || ext.Max != 50 {
+		t.Errorf("expected extents 10-50, got %d-%d", ext.Min, ext.Max)
+	}
+	if v := field.ValueForItemId(1); v == nil || *v != 10 {
+		t.Errorf("expected lowest value 10, got %v", v)
+	}
+
+	field.RemoveValueLink(10, 1)
+	if v := field.ValueForItemId(1); v == nil || *v != 50 {
+		t.Errorf("expected remaining value 50, got %v", v)
+	}
+	field.RemoveValueLink(50, 1)
+	if v := field.ValueForItemId(1); v != nil {
+		t.Errorf("expected no value, got %d", *v)
+	}
+
+	// an updated item keeps only its new values
+	field.AddValueLink(100, 2)
+	field.AddValueLink(200, 2)
+	if v := field.ValueForItemId(2); v == nil || *v != 200 {
+		t.Errorf("expected updated value 200, got %v", v)
+	}
+	if field.Count != 2 {
+		t.Errorf("expected 2 values after the update, got %d", field.Count)
+	}
+}
+
+func TestDecimalFieldMultipleValues(t *testing.T) {
+	field := EmptyDecimalField(&types.BaseField{Id: 1, Name: "number", Searchable: true})
+	field.AddValueLink([]float64{1.5, 9.25}, 1)
+	field.AddValueLink(4.0, 2)
+
+	ext := field.GetExtents(types.FromSlice([]uint{2, 99, 100}))
+	if ext.Min != 4 || ext.Max != 4 {
+		t.Errorf("expected extents 4-4, got %v-%v", ext.Min, ext.Max)
+	}
+	field.RemoveValueLink(1.5, 1)
+	if values := field.AllValuesCents[1]; len(values) != 1 || values[0] != 925 {
+		t.Errorf("expected 925 to remain, got %v", values)
+	}
+	if field.Count != 2 {
+		t.Errorf("expected 2 values, got %d", field.Count)
+	}
+	field.AddValueLink([]float64{9.25, 3.5}, 1)
+	if values := field.AllValuesCents[1]; !slices.Equal(values, []int64{925, 350}) {
+		t.Errorf("expected the updated values, got %v", values)
+	}
+	field.AddValueLink(5.0, 1)
+	if values := field.AllValuesCents[1]; !slices.Equal(values, []int64{500}) {
+		t.Errorf("expected only the new value, got %v", values)
+	}
+	if field.Count != 2 {
+		t.Errorf("expected 2 values after the update, got %d", field.Count)
+	}
+}
@@ -1,6 +1,9 @@
 package facet
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/RoaringBitmap/roaring/v2"
 )
 
@@ -129,3 +132,41 @@ func ScanMinMax(
 	}
 	return minV, maxV, true
 }
+
+// numericValues reads the values of a numeric facet, multi-valued facets arrive
+// as number slices, string slices or ";" separated strings. Values that can't
+// be parsed are skipped
+func numericValues(data any) ([]float64, bool) {
+	switch value := data.(type) {
+	case int:
+		return []float64{float64(value)}, true
+	case float64:
+		return []float64{value}, true
+	case []float64:
+		return value, len(value) > 0
+	case []int:
+		ret := make([]float64, len(value))
+		for i, v := range value {
+			ret[i] = float64(v)
+		}
+		return ret, len(ret) > 0
+	case string:
+		return numericValues(strings.Split(value, ";"))
+	case []string:
+		ret := make([]float64, 0, len(value))
+		for _, v := range value {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				ret = append(ret, f)
+			}
+		}
+		return ret, len(ret) > 0
+	}
+	return nil, false
+}
+
+// coversAll reports if filterBM contains every item of items, an empty items
+// bitmap is never covered so the caller scans the buckets.
+func coversAll(filterBM, items *roaring.Bitmap) bool {
+	n := items.GetCardinality()
+	return n > 0 && filterBM.AndCardinality(items) == n
+}
@@ -0,0 +1,78 @@
+package facet
+
+import (
+	"math"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+// floatToInt64 truncates the value and saturates outside the int64 domain
+func floatToInt64(v float64) int64 {
+	switch {
+	case v >= math.MaxInt64:
+		return math.MaxInt64
+	case v <= math.MinInt64:
+		return math.MinInt64
+	}
+	return int64(v)
+}
+
+// integerBounds converts the filter to inclusive integer bounds, open ends
+// become the int extremes
+func integerBounds(r types.RangeFilter) (int, int) {
+	minValue, maxValue := math.MinInt, math.MaxInt
+	if r.Min != nil {
+		v := math.Ceil(*r.Min)
+		if r.MinExclusive && v == *r.Min {
+			v++
+		}
+		minValue = int(floatToInt64(v))
+	}
+	if r.Max != nil {
+		v := math.Floor(*r.Max)
+		if r.MaxExclusive && v == *r.Max {
+			v--
+		}
+		maxValue = int(floatToInt64(v))
+	}
+	return minValue, maxValue
+}
+
+// centBounds converts the filter to inclusive bounds in cents, inclusive
+// bounds round outwards like MatchesRange
+func centBounds(r types.RangeFilter) (int64, int64) {
+	minC, maxC := int64(math.MinInt64), int64(math.MaxInt64)
+	if r.Min != nil {
+		minC = floatToInt64(math.Floor(*r.Min*100.0 + 0.0000001))
+		if r.MinExclusive && minC < math.MaxInt64 {
+			minC++
+		}
+	}
+	if r.Max != nil {
+		maxC = floatToInt64(math.Ceil(*r.Max*100.0 - 0.0000001))
+		if r.MaxExclusive && maxC > math.MinInt64 {
+			maxC--
+		}
+	}
+	return minC, maxC
+}
+
+// MatchRangeFilter returns the items in the range, nil when it covers every
+// item with a value
+func (f *IntegerField) MatchRangeFilter(r types.RangeFilter) *types.ItemList {
+	if r.HasTextBounds() {
+		return types.NewItemList()
+	}
+	minValue, maxValue := integerBounds(r)
+	return f.MatchesRange(minValue, maxValue)
+}
+
+// MatchRangeFilter returns the items in the range, nil when it covers every
+// item with a value
+func (f *DecimalField) MatchRangeFilter(r types.RangeFilter) *types.ItemList {
+	if r.HasTextBounds() {
+		return types.NewItemList()
+	}
+	minC, maxC := centBounds(r)
+	return f.matchCents(minC, maxC)
+}
@@ -0,0 +1,141 @@
+package facet
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func ref(v float64) *float64 {
+	return &v
+}
+
+func idsOf(l *types.ItemList) []uint32 {
+	ret := make([]uint32, 0)
+	l.ForEach(func(id uint32) bool {
+		ret = append(ret, id)
+		return true
+	})
+	slices.Sort(ret)
+	return ret
+}
+
+func TestIntegerRangeFilter(t *testing.T) {
+	f := EmptyIntegerField(&types.BaseField{Id: 1, Searchable: true})
+	for i := 1; i <= 5; i++ {
+		f.AddValueLink(i*1000, types.ItemId(i))
+	}
+	tests := []struct {
+		filter   types.RangeFilter
+		expected []uint32
+	}{
+		{types.RangeFilter{Min: ref(3000)}, []uint32{3, 4, 5}},
+		{types.RangeFilter{Min: ref(3000), MinExclusive: true}, []uint32{4, 5}},
+		{types.RangeFilter{Max: ref(2000), MaxExclusive: true}, []uint32{1}},
+		{types.RangeFilter{Min: ref(1500.5), Max: ref(4000)}, []uint32{2, 3, 4}},
+		{types.RangeFilter{Min: ref(2000), Max: ref(2000), MinExclusive: true}, []uint32{}},
+		{types.RangeFilter{Min: ref(9000)}, []uint32{}},
+	}
+	for _, test := range tests {
+		ids := f.Match(test.filter)
+		if ids == nil {
+			t.Errorf("%+v: expected items got all", test.filter)
+			continue
+		}
+		if got := idsOf(ids); !slices.Equal(got, test.expected) {
+			t.Errorf("%+v: expected %v got %v", test.filter, test.expected, got)
+		}
+	}
+	// a negated range covering everything has to return the items to exclude
+	all := f.Match(types.RangeFilter{Min: ref(0), Not: true})
+	if all == nil || all.Len() != 5 {
+		t.Errorf("Expected all items for a negated full range")
+	}
+}
+
+func TestDecimalRangeFilter(t *testing.T) {
+	f := EmptyDecimalField(&types.BaseField{Id: 1, Searchable: true})
+	f.AddValueLink(1.5, 1)
+	f.AddValueLink(2.0, 2)
+	f.AddValueLink(2.01, 3)
+	f.AddValueLink(10.0, 4)
+	tests := []struct {
+		filter   types.RangeFilter
+		expected []uint32
+	}{
+		{types.RangeFilter{Max: ref(2), MaxExclusive: true}, []uint32{1}},
+		{types.RangeFilter{Max: ref(2)}, []uint32{1, 2}},
+		{types.RangeFilter{Min: ref(2), MinExclusive: true}, []uint32{3, 4}},
+		{types.RangeFilter{Min: ref(2), Max: ref(10), MinExclusive: true, MaxExclusive: true}, []uint32{3}},
+	}
+	for _, test := range tests {
+		ids := f.Match(test.filter)
+		if got := idsOf(ids); !slices.Equal(got, test.expected) {
+			t.Errorf("%+v: expected %v got %v", test.filter, test.expected, got)
+		}
+	}
+}
+
+func TestNegatedRangeFilterMatch(t *testing.T) {
+	h := NewFacetItemHandler([]types.StorageFacet{
+		{BaseField: &types.BaseField{Id: 4, Searchable: true}, Type: types.FacetIntegerType},
+		{BaseField: &types.BaseField{Id: 5, Searchable: true}, Type: types.FacetKeyType},
+	}, nil)
+	price, _ := h.GetFacet(4)
+	brand, _ := h.GetFacet(5)
+	for i := 1; i <= 4; i++ {
+		price.AddValueLink(i*100, types.ItemId(i))
+		brand.AddValueLink("a", types.ItemId(i))
+	}
+	result := &types.ItemList{}
+	qm := types.NewQueryMerger(t.Context(), result)
+	h.Match(&types.Filters{
+		StringFilter: []types.StringFilter{{Id: 5, Value: types.StringFilterValue{"a"}}},
+		RangeFilter:  []types.RangeFilter{{Id: 4, Min: ref(200), Max: ref(300), Not: true}},
+	}, qm)
+	qm.Wait()
+	if got := idsOf(result); !slices.Equal(got, []uint32{1, 4}) {
+		t.Errorf("Expected items 1 and 4 got %v", got)
+	}
+}
+
+func TestMultiValuedNumericFacets(t *testing.T) {
+	h := NewFacetItemHandler([]types.StorageFacet{
+		{BaseField: &types.BaseField{Id: 1, Searchable: true}, Type: types.FacetIntegerType},
+		{BaseField: &types.BaseField{Id: 2, Searchable: true}, Type: types.FacetNumberType},
+	}, nil)
+	items := []*types.MockItem{
+		{Id: 1, NumberLists: map[types.FacetId][]float64{1: {720, 1080}, 2: {1.5, 2.5}}},
+		{Id: 2, NumberLists: map[types.FacetId][]float64{1: {2160}}, NumberFields: map[types.FacetId]float64{2: 10}},
+		{Id: 3, StringFields: map[types.FacetId]string{1: "480;720"}},
+	}
+	wg := &sync.WaitGroup{}
+	for _, item := range items {
+		h.HandleItem(item, wg)
+	}
+	wg.Wait()
+
+	resolution, _ := h.GetFacet(1)
+	if got := idsOf(resolution.Match(types.RangeFilter{Min: ref(1000), Max: ref(1100)})); !slices.Equal(got, []uint32{1}) {
+		t.Errorf("Expected any value to match got %v", got)
+	}
+	if got := idsOf(resolution.Match(types.RangeFilter{Min: ref(700), Max: ref(800)})); !slices.Equal(got, []uint32{1, 3}) {
+		t.Errorf("Expected split string values to match got %v", got)
+	}
+	size, _ := h.GetFacet(2)
+	if got := idsOf(size.Match(types.RangeFilter{Min: ref(2), Max: ref(3)})); !slices.Equal(got, []uint32{1}) {
+		t.Errorf("Expected decimal list values to match got %v", got)
+	}
+
+	items[0].Deleted = true
+	h.HandleItem(items[0], wg)
+	wg.Wait()
+	if got := idsOf(resolution.Match(types.RangeFilter{Min: ref(700)})); !slices.Equal(got, []uint32{2, 3}) {
+		t.Errorf("Expected all values of the deleted item to be removed got %v", got)
+	}
+	if got := idsOf(size.Match(types.RangeFilter{Max: ref(5)})); len(got) != 0 {
+		t.Errorf("Expected no decimal values left below 5 got %v", got)
+	}
+}
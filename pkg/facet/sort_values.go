@@ -0,0 +1,76 @@
+package facet
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+// bucketSortValues lists the items of the buckets ordered by value, ties are
+// ordered by id. Multi-valued items are placed by their lowest value ascending
+// and their highest value descending, scale converts the stored values back to
+// the facet domain
+func bucketSortValues(buckets map[int]*ValueBucket, scale float64, ascending bool) (types.ByValue, *types.ItemList) {
+	bucketIds := slices.Sorted(maps.Keys(buckets))
+	if !ascending {
+		slices.Reverse(bucketIds)
+	}
+	seen := roaring.NewBitmap()
+	ret := make(types.ByValue, 0)
+	add := func(ve valueEntry) {
+		it := ve.ids.Iterator()
+		for it.HasNext() {
+			id := it.Next()
+			if seen.CheckedAdd(id) {
+				ret = append(ret, types.Lookup{Id: id, Value: float64(ve.value) / scale})
+			}
+		}
+	}
+	for _, bId := range bucketIds {
+		b := buckets[bId]
+		if b == nil || len(b.entries) == 0 {
+			continue
+		}
+		if ascending {
+			for _, ve := range b.entries {
+				add(ve)
+			}
+		} else {
+			for i := len(b.entries) - 1; i >= 0; i-- {
+				add(b.entries[i])
+			}
+		}
+	}
+	return ret, types.FromBitmap(seen)
+}
+
+// IsSortable tells if the facet can be used to sort items
+func (h *FacetItemHandler) IsSortable(id types.FacetId) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	facet, ok := h.Facets[id]
+	return ok && facet.GetBaseField().Sortable
+}
+
+// GetSortValues returns the items of a numeric facet ordered by value and the
+// items having a value, false when the facet is missing, not numeric or not
+// marked sortable
+func (h *FacetItemHandler) GetSortValues(id types.FacetId, ascending bool) (types.ByValue, *types.ItemList, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	facet, ok := h.Facets[id]
+	if !ok || !facet.GetBaseField().Sortable {
+		return nil, nil, false
+	}
+	switch field := facet.(type) {
+	case *IntegerField:
+		values, ids := bucketSortValues(field.buckets, 1, ascending)
+		return values, ids, true
+	case *DecimalField:
+		values, ids := bucketSortValues(field.buckets, 100, ascending)
+		return values, ids, true
+	}
+	return nil, nil, false
+}
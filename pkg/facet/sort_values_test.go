@@ -0,0 +1,59 @@
+package facet
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func lookupIds(values types.ByValue) []uint32 {
+	ret := make([]uint32, len(values))
+	for i, v := range values {
+		ret[i] = v.Id
+	}
+	return ret
+}
+
+func TestGetSortValues(t *testing.T) {
+	h := NewFacetItemHandler([]types.StorageFacet{
+		{BaseField: &types.BaseField{Id: 1, Searchable: true, Sortable: true}, Type: types.FacetIntegerType},
+		{BaseField: &types.BaseField{Id: 2, Searchable: true, Sortable: true}, Type: types.FacetNumberType},
+		{BaseField: &types.BaseField{Id: 3, Searchable: true, Sortable: true}, Type: types.FacetKeyType},
+		{BaseField: &types.BaseField{Id: 4, Searchable: true}, Type: types.FacetIntegerType},
+	}, nil)
+	capacity, _ := h.GetFacet(1)
+	capacity.AddValueLink(2000, 1)
+	capacity.AddValueLink(64, 2)
+	capacity.AddValueLink([]float64{128, 4000}, 3)
+	capacity.AddValueLink(64, 4)
+
+	asc, withValues, ok := h.GetSortValues(1, true)
+	if !ok || !slices.Equal(lookupIds(asc), []uint32{2, 4, 3, 1}) {
+		t.Errorf("Expected ascending order got %v", asc)
+	}
+	if withValues.Len() != 4 {
+		t.Errorf("Expected 4 items with values got %d", withValues.Len())
+	}
+	desc, _, _ := h.GetSortValues(1, false)
+	if !slices.Equal(lookupIds(desc), []uint32{3, 1, 2, 4}) {
+		t.Errorf("Expected descending order by the highest value got %v", desc)
+	}
+
+	size, _ := h.GetFacet(2)
+	size.AddValueLink(55.5, 1)
+	size.AddValueLink(6.1, 2)
+	sizes, _, _ := h.GetSortValues(2, true)
+	if len(sizes) != 2 || sizes[0].Value != 6.1 || sizes[1].Value != 55.5 {
+		t.Errorf("Expected decimal values got %v", sizes)
+	}
+
+	if _, _, ok = h.GetSortValues(3, true); ok {
+		t.Errorf("Expected key facets not to be sortable")
+	}
+	other, _ := h.GetFacet(4)
+	other.AddValueLink(10, 1)
+	if _, _, ok = h.GetSortValues(4, true); ok {
+		t.Errorf("Expected facets not marked sortable not to be sortable")
+	}
+}
@@ -0,0 +1,147 @@
+package facet
+
+import (
+	"math"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+type NumericStats struct {
+	Count uint64  `json:"count"`
+	Sum   float64 `json:"sum"`
+	Mean  float64 `json:"mean"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	P25   float64 `json:"p25"`
+	P50   float64 `json:"p50"`
+	P75   float64 `json:"p75"`
+	P95   float64 `json:"p95"`
+}
+
+// buildStats computes the statistics from value counts sorted by value, the
+// percentiles use the nearest rank
+func buildStats(values []valueCount, scale float64) *NumericStats {
+	if len(values) == 0 {
+		return nil
+	}
+	stats := &NumericStats{
+		Min: float64(values[0].value) / scale,
+		Max: float64(values[len(values)-1].value) / scale,
+	}
+	for _, v := range values {
+		stats.Count += v.count
+		stats.Sum += float64(v.value) * float64(v.count) / scale
+	}
+	stats.Mean = stats.Sum / float64(stats.Count)
+
+	percentiles := []struct {
+		p      float64
+		target *float64
+	}{
+		{0.25, &stats.P25},
+		{0.50, &stats.P50},
+		{0.75, &stats.P75},
+		{0.95, &stats.P95},
+	}
+	var acc uint64
+	next := 0
+	for _, v := range values {
+		acc += v.count
+		for next < len(percentiles) {
+			rank := uint64(math.Ceil(percentiles[next].p * float64(stats.Count)))
+			if acc < rank {
+				break
+			}
+			*percentiles[next].target = float64(v.value) / scale
+			next++
+		}
+	}
+	return stats
+}
+
+// statsFilter returns the bitmap to filter the values by, nil includes all
+// items and ok is false when no items match
+func statsFilter(matchIds *types.ItemList) (bm *roaring.Bitmap, ok bool) {
+	if matchIds == nil {
+		return nil, true
+	}
+	if matchIds.Len() == 0 {
+		return nil, false
+	}
+	return matchIds.Bitmap(), true
+}
+
+// GetStats computes the statistics of the matching items, nil matchIds
+// includes all items and empty matchIds has no statistics
+func (f *IntegerField) GetStats(matchIds *types.ItemList) *NumericStats {
+	bm, ok := statsFilter(matchIds)
+	if f.Count == 0 || !ok {
+		return nil
+	}
+	values := collectValueCounts(f.buckets, GetBucket(f.Min), GetBucket(f.Max), bm)
+	return buildStats(values, 1)
+}
+
+// GetStats computes the statistics of the matching items, nil matchIds
+// includes all items and empty matchIds has no statistics
+func (f *DecimalField) GetStats(matchIds *types.ItemList) *NumericStats {
+	bm, ok := statsFilter(matchIds)
+	if f.Count == 0 || !ok {
+		return nil
+	}
+	startBucket := GetBucketFromCents(int64(math.Round(f.Min * 100.0)))
+	endBucket := GetBucketFromCents(int64(math.Round(f.Max * 100.0)))
+	values := collectValueCounts(f.buckets, startBucket, endBucket, bm)
+	return buildStats(values, 100)
+}
+
+// AddStats sets the statistics requested for numeric facets on the facet
+// results, facets missing from the result are added with their extents
+func (h *FacetItemHandler) AddStats(ids []types.FacetId, matchIds *types.ItemList, facets []*JsonFacet) []*JsonFacet {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, id := range ids {
+		f, ok := h.Facets[id]
+		if !ok {
+			continue
+		}
+		var result FieldResult
+		switch field := f.(type) {
+		case *IntegerField:
+			stats := field.GetStats(matchIds)
+			if stats == nil {
+				continue
+			}
+			result = &IntegerFieldResult{Min: int(stats.Min), Max: int(stats.Max), Stats: stats}
+		case *DecimalField:
+			stats := field.GetStats(matchIds)
+			if stats == nil {
+				continue
+			}
+			result = &DecimalFieldResult{Min: stats.Min, Max: stats.Max, Stats: stats}
+		default:
+			continue
+		}
+		found := false
+		for _, jf := range facets {
+			if jf.Id != id {
+				continue
+			}
+			found = true
+			switch r := jf.Result.(type) {
+			case *IntegerFieldResult:
+				r.Stats = result.(*IntegerFieldResult).Stats
+			case *DecimalFieldResult:
+				r.Stats = result.(*DecimalFieldResult).Stats
+			}
+		}
+		if !found {
+			facets = append(facets, &JsonFacet{
+				BaseField: f.GetBaseField(),
+				Result:    result,
+			})
+		}
+	}
+	return facets
+}
@@ -0,0 +1,70 @@
+package facet
+
+import (
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func TestIntegerFieldStats(t *testing.T) {
+	f := EmptyIntegerField(&types.BaseField{Id: 1, Searchable: true})
+	for i := 1; i <= 100; i++ {
+		f.AddValueLink(i*10, types.ItemId(i))
+	}
+	stats := f.GetStats(nil)
+	if stats.Count != 100 || stats.Min != 10 || stats.Max != 1000 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+	if stats.Sum != 50500 || stats.Mean != 505 {
+		t.Errorf("Expected sum 50500 and mean 505 got %+v", stats)
+	}
+	if stats.P25 != 250 || stats.P50 != 500 || stats.P75 != 750 || stats.P95 != 950 {
+		t.Errorf("Unexpected percentiles %+v", stats)
+	}
+
+	ids := types.NewItemList()
+	for _, id := range []uint32{1, 2, 3, 100} {
+		ids.AddId(id)
+	}
+	filtered := f.GetStats(ids)
+	if filtered.Count != 4 || filtered.Min != 10 || filtered.Max != 1000 || filtered.P50 != 20 {
+		t.Errorf("Unexpected filtered stats %+v", filtered)
+	}
+	if empty := f.GetStats(types.NewItemList()); empty != nil {
+		t.Errorf("Expected no stats without matching items got %+v", empty)
+	}
+}
+
+func TestDecimalFieldStats(t *testing.T) {
+	f := EmptyDecimalField(&types.BaseField{Id: 1, Searchable: true})
+	f.AddValueLink(9.5, 1)
+	f.AddValueLink(10.5, 2)
+	f.AddValueLink(10.5, 3)
+	stats := f.GetStats(nil)
+	if stats.Count != 3 || stats.Min != 9.5 || stats.Max != 10.5 || stats.Sum != 30.5 || stats.P50 != 10.5 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestAddStats(t *testing.T) {
+	h := NewFacetItemHandler([]types.StorageFacet{
+		{BaseField: &types.BaseField{Id: 4, Searchable: true}, Type: types.FacetIntegerType},
+		{BaseField: &types.BaseField{Id: 5, Searchable: true}, Type: types.FacetIntegerType},
+	}, nil)
+	price, _ := h.GetFacet(4)
+	weight, _ := h.GetFacet(5)
+	price.AddValueLink(100, 1)
+	weight.AddValueLink(7, 1)
+	existing := &IntegerFieldResult{Min: 100, Max: 100}
+	facets := []*JsonFacet{{BaseField: price.GetBaseField(), Result: existing}}
+	facets = h.AddStats([]types.FacetId{4, 5}, nil, facets)
+	if existing.Stats == nil || existing.Stats.Mean != 100 {
+		t.Errorf("Expected stats on the existing result got %+v", existing.Stats)
+	}
+	if len(facets) != 2 || facets[1].Id != 5 {
+		t.Fatalf("Expected the missing facet to be added got %d facets", len(facets))
+	}
+	if r := facets[1].Result.(*IntegerFieldResult); r.Stats == nil || r.Stats.Count != 1 {
+		t.Errorf("Expected stats for the added facet got %+v", r)
+	}
+}
@@ -2,6 +2,7 @@ package facet
 
 import (
 	"log"
+	"strings"
 
 	"github.com/matst80/slask-finder/pkg/types"
 )
@@ -63,6 +64,10 @@ func (t *TreeField) match(value []string) *types.ItemList {
 
 func (t *TreeField) Match(data any) *types.ItemList {
 	switch v := data.(type) {
+	case string:
+		return t.match(types.SplitTreePath(v))
+	case types.PathFilter:
+		return t.match(v.Path)
 	case []string:
 		return t.match(v)
 	case []interface{}:
@@ -114,6 +119,15 @@ func (t *TreeField) addValue(value []string, id types.ItemId) bool {
 
 func (t *TreeField) AddValueLink(value any, id types.ItemId) bool {
 	switch v := value.(type) {
+	case string:
+		// multiple paths are joined with ; like other multi value fields
+		added := false
+		for _, path := range strings.Split(v, ";") {
+			if t.addValue(types.SplitTreePath(path), id) {
+				added = true
+			}
+		}
+		return added
 	case []string:
 		return t.addValue(v, id)
 	case []interface{}:
@@ -132,19 +146,104 @@ func (t *TreeField) AddValueLink(value any, id types.ItemId) bool {
 	return false
 }
 
+func removeFromTree(children map[string]*Tree, path []string, id types.ItemId) {
+	if len(path) == 0 {
+		return
+	}
+	node, ok := children[path[0]]
+	if !ok {
+		return
+	}
+	node.ids.RemoveId(uint32(id))
+	removeFromTree(node.Children, path[1:], id)
+	if node.ids.IsEmpty() {
+		delete(children, path[0])
+	}
+}
+
 func (t *TreeField) RemoveValueLink(value any, id types.ItemId) {
+	switch v := value.(type) {
+	case string:
+		for _, path := range strings.Split(v, ";") {
+			removeFromTree(t.Children, types.SplitTreePath(path), id)
+		}
+	case []string:
+		removeFromTree(t.Children, v, id)
+	}
+}
+
+// node returns the children below the path and the nodes along it
+func (t *TreeField) node(path []string) (map[string]*Tree, []*Tree, bool) {
+	children := t.Children
+	nodes := make([]*Tree, 0, len(path))
+	for _, value := range path {
+		node, ok := children[value]
+		if !ok {
+			return nil, nodes, false
+		}
+		nodes = append(nodes, node)
+		children = node.Children
+	}
+	return children, nodes, true
+}
+
+// GetResult counts the selected path and the children of the selected node,
+// all items are counted when baseIds is empty
+func (t *TreeField) GetResult(baseIds *types.ItemList, path []string) *TreeFieldResult {
+	returnAll := baseIds == nil || baseIds.IsEmpty()
+	count := func(ids *types.ItemList) uint64 {
+		if returnAll {
+			return ids.Cardinality()
+		}
+		return ids.IntersectionLen(baseIds)
+	}
+	children, nodes, ok := t.node(path)
+	if !ok {
+		return nil
+	}
+	result := &TreeFieldResult{
+		Path:   make([]TreeNodeResult, 0, len(nodes)),
+		Values: make(map[string]uint64, len(children)),
+	}
+	for _, node := range nodes {
+		result.Path = append(result.Path, TreeNodeResult{
+			Value: node.Value,
+			Count: count(node.ids),
+		})
+	}
+	for value, child := range children {
+		if c := count(child.ids); c > 0 {
+			result.Values[value] = c
+		}
+	}
+	return result
 }
 
 func (t *TreeField) UpdateBaseField(data *types.BaseField) {
 	t.BaseField.UpdateFrom(data)
 }
 
+func appendTreeValues(ret []any, prefix string, children map[string]*Tree) []any {
+	for value, child := range children {
+		path := value
+		if prefix != "" {
+			path = prefix + " " + types.TreePathSeparator + " " + value
+		}
+		ret = append(ret, ValueWithCount{
+			Value: path,
+			Count: int(child.ids.Cardinality()),
+		})
+		ret = appendTreeValues(ret, path, child.Children)
+	}
+	return ret
+}
+
 func (t *TreeField) GetValues() []any {
-	return nil
+	return appendTreeValues(make([]any, 0, len(t.Children)), "", t.Children)
 }
 
 func (t *TreeField) IsExcludedFromFacets() bool {
-	return false
+	return t.BaseField.HideFacet || t.BaseField.InternalOnly
 }
 
 func (t *TreeField) IsCategory() bool {
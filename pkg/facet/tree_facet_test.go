@@ -95,3 +95,54 @@ func containsAll(list *types.ItemList, ids []types.ItemId) bool {
 	}
 	return true
 }
+
+func TestTreeFacetPathString(t *testing.T) {
+	field := EmptyTreeValueField(&types.BaseField{Id: 1, Name: "tree", Searchable: true}).(*TreeField)
+
+	field.AddValueLink("Computers > Laptops > Gaming", 1)
+	field.AddValueLink("Computers > Laptops;Computers > Desktops", 2)
+	field.AddValueLink("Phones", 3)
+
+	if r := field.Match("Computers > Laptops"); r.Len() != 2 {
+		t.Errorf("Expected 2 laptops, got %v", r.ToSlice())
+	}
+	if r := field.Match(types.PathFilter{Id: 1, Path: []string{"Computers", "Desktops"}}); !r.Contains(2) || r.Len() != 1 {
+		t.Errorf("Expected item 2 in desktops, got %v", r.ToSlice())
+	}
+
+	field.RemoveValueLink("Computers > Laptops > Gaming", 1)
+	if _, ok := field.Children["Computers"].Children["Laptops"].Children["Gaming"]; ok {
+		t.Error("Expected empty node to be removed")
+	}
+	if r := field.Match("Computers"); r.Len() != 1 {
+		t.Errorf("Expected 1 computer after remove, got %v", r.ToSlice())
+	}
+}
+
+func TestTreeFacetGetResult(t *testing.T) {
+	field := EmptyTreeValueField(&types.BaseField{Id: 1, Name: "tree", Searchable: true}).(*TreeField)
+	field.AddValueLink("a > b > c", 1)
+	field.AddValueLink("a > b > d", 2)
+	field.AddValueLink("a > e", 3)
+	field.AddValueLink("x", 4)
+
+	root := field.GetResult(nil, nil)
+	if root.Values["a"] != 3 || root.Values["x"] != 1 || len(root.Path) != 0 {
+		t.Errorf("Unexpected root result %+v", root)
+	}
+
+	base := types.NewItemList()
+	base.AddId(1)
+	base.AddId(3)
+	r := field.GetResult(base, []string{"a", "b"})
+	if len(r.Path) != 2 || r.Path[0].Count != 2 || r.Path[1].Count != 1 {
+		t.Errorf("Unexpected path counts %+v", r.Path)
+	}
+	if len(r.Values) != 1 || r.Values["c"] != 1 {
+		t.Errorf("Expected only c with count 1, got %v", r.Values)
+	}
+
+	if field.GetResult(base, []string{"missing"}) != nil {
+		t.Error("Expected nil result for missing path")
+	}
+}
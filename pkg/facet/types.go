@@ -9,13 +9,49 @@ type FieldResult interface {
 }
 
 type KeyFieldResult struct {
+	// Values has the counts of the sorted values
 	Values map[string]uint64 `json:"values,omitempty"`
+	// Sorted has the values in the facet sort order, limited by the value limit,
+	// only set when the field has a value order or display names
+	Sorted []KeyValueCount `json:"sorted,omitempty"`
+	// More is the number of values left out of Sorted
+	More int `json:"more,omitempty"`
+	// counts has all values, also the ones left out by the value limit
+	counts map[string]uint64
 }
 
 func (k *KeyFieldResult) HasValues() bool {
+	if k.counts != nil {
+		return len(k.counts) > 1
+	}
 	return len(k.Values) > 1
 }
 
+// AllValues returns the counts of all values, also the ones left out by the
+// value limit
+func (k *KeyFieldResult) AllValues() map[string]uint64 {
+	if k.counts != nil {
+		return k.counts
+	}
+	return k.Values
+}
+
+type TreeNodeResult struct {
+	Value string `json:"value"`
+	Count uint64 `json:"count"`
+}
+
+// TreeFieldResult has the counts for each level of the selected path and the
+// children of the selected node, or the root level when nothing is selected
+type TreeFieldResult struct {
+	Path   []TreeNodeResult  `json:"path,omitempty"`
+	Values map[string]uint64 `json:"values,omitempty"`
+}
+
+func (t *TreeFieldResult) HasValues() bool {
+	return len(t.Values) > 0 || len(t.Path) > 0
+}
+
 type JsonFacet struct {
 	*types.BaseField
 	Selected any         `json:"selected,omitempty"`
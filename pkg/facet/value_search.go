@@ -0,0 +1,150 @@
+package facet
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/matst80/slask-finder/pkg/search"
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+// Scores for how well a facet value matches a value search query
+const (
+	valueMatchNone = iota
+	valueMatchFuzzy
+	valueMatchWordPrefix
+	valueMatchPrefix
+)
+
+type ValueSearchResult struct {
+	Values   []KeyValueCount `json:"values"`
+	Total    int             `json:"total"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"pageSize"`
+}
+
+// CountValues counts the items for each value, all items are counted when
+// baseIds is nil, values without matching items are left out
+func (f *KeyField) CountValues(baseIds *types.ItemList) map[string]uint64 {
+	ret := make(map[string]uint64, len(f.Keys))
+	for value, ids := range f.Keys {
+		var count uint64
+		if baseIds == nil {
+			count = ids.Cardinality()
+		} else {
+			count = ids.IntersectionLen(baseIds)
+		}
+		if count > 0 {
+			ret[value] = count
+		}
+	}
+	return ret
+}
+
+// maxEdits is the number of typos allowed for a query of the given length
+func maxEdits(length int) int {
+	switch {
+	case length >= 8:
+		return 2
+	case length >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// prefixDistance returns the smallest edit distance between the query and any
+// prefix of the word, so a partially typed word with a typo still matches
+func prefixDistance(query, word []rune) int {
+	prev := make([]int, len(word)+1)
+	curr := make([]int, len(word)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(query); i++ {
+		curr[0] = i
+		for j := 1; j <= len(word); j++ {
+			cost := 1
+			if query[i-1] == word[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	// prev[j] is the distance between the query and the first j runes
+	best := len(query)
+	for j := range prev {
+		best = min(best, prev[j])
+	}
+	return best
+}
+
+// matchValue scores a facet value against a normalized query
+func matchValue(value string, query search.Token) int {
+	if len(query) == 0 {
+		return valueMatchPrefix
+	}
+	q := string(query)
+	if strings.HasPrefix(string(search.NormalizeWord(value)), q) {
+		return valueMatchPrefix
+	}
+	words := make([]search.Token, 0)
+	for _, word := range strings.Fields(value) {
+		if w := search.NormalizeWord(word); len(w) > 0 {
+			if strings.HasPrefix(string(w), q) {
+				return valueMatchWordPrefix
+			}
+			words = append(words, w)
+		}
+	}
+	edits := maxEdits(len([]rune(q)))
+	if edits == 0 {
+		return valueMatchNone
+	}
+	qr := []rune(q)
+	for _, w := range words {
+		// the first rune is rarely misspelled and rules out most words
+		wr := []rune(string(w))
+		if wr[0] != qr[0] {
+			continue
+		}
+		if prefixDistance(qr, wr) <= edits {
+			return valueMatchFuzzy
+		}
+	}
+	return valueMatchNone
+}
+
+// SearchValues returns a page of the values matching the query, ordered by how
+// well the value or its display name match and then by the sorting of the
+// field. The query is normalized the same way as the free text index
+func SearchValues(field *types.BaseField, values map[string]uint64, query string, page, pageSize int) *ValueSearchResult {
+	normalized := search.NormalizeWord(query)
+	sorted := SortValues(field, values)
+	matching := make([]KeyValueCount, 0, len(sorted))
+	scores := make(map[string]int, len(sorted))
+	for _, v := range sorted {
+		score := matchValue(v.Value, normalized)
+		if v.Name != "" {
+			score = max(score, matchValue(v.Name, normalized))
+		}
+		if score != valueMatchNone {
+			matching = append(matching, v)
+			scores[v.Value] = score
+		}
+	}
+	if len(normalized) > 0 {
+		slices.SortStableFunc(matching, func(a, b KeyValueCount) int {
+			return scores[b.Value] - scores[a.Value]
+		})
+	}
+	start := min(page*pageSize, len(matching))
+	end := min(start+pageSize, len(matching))
+	return &ValueSearchResult{
+		Values:   matching[start:end],
+		Total:    len(matching),
+		Page:     page,
+		PageSize: pageSize,
+	}
+}
@@ -0,0 +1,85 @@
+package facet
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func TestSearchValues(t *testing.T) {
+	field := &types.BaseField{Id: 1}
+	values := map[string]uint64{
+		"Samsung":        10,
+		"Sony":           8,
+		"Bang & Olufsen": 2,
+		"Jbl":            5,
+		"Sonos":          3,
+		"Höganäs":        1,
+	}
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"so", []string{"Sony", "Sonos"}},
+		{"SAM", []string{"Samsung"}},
+		{"oluf", []string{"Bang & Olufsen"}},
+		{"hoga", []string{"Höganäs"}},
+		{"samsyng", []string{"Samsung"}},
+		{"xyz", []string{}},
+	}
+	for _, test := range tests {
+		result := SearchValues(field, values, test.query, 0, 10)
+		got := sortedValues(result.Values)
+		if !slices.Equal(got, test.expected) {
+			t.Errorf("query %q expected %v got %v", test.query, test.expected, got)
+		}
+		if result.Total != len(test.expected) {
+			t.Errorf("query %q expected total %d got %d", test.query, len(test.expected), result.Total)
+		}
+	}
+}
+
+func TestSearchValuesPrefixBeforeFuzzy(t *testing.T) {
+	field := &types.BaseField{Id: 1}
+	values := map[string]uint64{"Asus": 1, "Apple": 2, "Apppa": 20}
+	result := SearchValues(field, values, "appl", 0, 10)
+	expected := []string{"Apple", "Apppa"}
+	if got := sortedValues(result.Values); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v got %v", expected, got)
+	}
+}
+
+func TestSearchValuesPaging(t *testing.T) {
+	field := &types.BaseField{Id: 1}
+	values := map[string]uint64{"a": 5, "b": 4, "c": 3, "d": 2, "e": 1}
+	result := SearchValues(field, values, "", 1, 2)
+	expected := []string{"c", "d"}
+	if got := sortedValues(result.Values); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v got %v", expected, got)
+	}
+	if result.Total != 5 {
+		t.Errorf("Expected total 5 got %d", result.Total)
+	}
+	result = SearchValues(field, values, "", 3, 2)
+	if len(result.Values) != 0 {
+		t.Errorf("Expected empty page got %v", result.Values)
+	}
+}
+
+func TestKeyFieldCountValues(t *testing.T) {
+	field := EmptyKeyValueField(&types.BaseField{Id: 1, Searchable: true})
+	field.AddValueLink("a", 1)
+	field.AddValueLink("a", 2)
+	field.AddValueLink("b", 3)
+	all := field.CountValues(nil)
+	if all["a"] != 2 || all["b"] != 1 {
+		t.Errorf("Expected a=2 b=1 got %v", all)
+	}
+	base := types.NewItemList()
+	base.AddId(2)
+	filtered := field.CountValues(base)
+	if len(filtered) != 1 || filtered["a"] != 1 {
+		t.Errorf("Expected only a=1 got %v", filtered)
+	}
+}
@@ -0,0 +1,143 @@
+package facet
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+type KeyValueCount struct {
+	Value string `json:"value"`
+	// Name is the display name of the value when it has one
+	Name  string `json:"name,omitempty"`
+	Count uint64 `json:"count"`
+}
+
+func (k KeyValueCount) label() string {
+	if k.Name != "" {
+		return k.Name
+	}
+	return k.Value
+}
+
+// NaturalCompare compares strings with digit runs compared by numeric value,
+// so "4 GB" sorts before "16 GB"
+func NaturalCompare(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	i, j := 0, 0
+	for i < len(ar) && j < len(br) {
+		if unicode.IsDigit(ar[i]) && unicode.IsDigit(br[j]) {
+			si, sj := i, j
+			for i < len(ar) && unicode.IsDigit(ar[i]) {
+				i++
+			}
+			for j < len(br) && unicode.IsDigit(br[j]) {
+				j++
+			}
+			na := strings.TrimLeft(string(ar[si:i]), "0")
+			nb := strings.TrimLeft(string(br[sj:j]), "0")
+			if c := cmp.Compare(len(na), len(nb)); c != 0 {
+				return c
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			continue
+		}
+		ca, cb := unicode.ToLower(ar[i]), unicode.ToLower(br[j])
+		if ca != cb {
+			return cmp.Compare(ca, cb)
+		}
+		i++
+		j++
+	}
+	return cmp.Compare(len(ar)-i, len(br)-j)
+}
+
+func byCountDesc(a, b KeyValueCount) int {
+	if c := cmp.Compare(b.Count, a.Count); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Value, b.Value)
+}
+
+// SortValues orders the values by the sorting mode of the field, alphabetical
+// and natural sorting use the display names
+func SortValues(field *types.BaseField, values map[string]uint64) []KeyValueCount {
+	ret := make([]KeyValueCount, 0, len(values))
+	for value, count := range values {
+		ret = append(ret, KeyValueCount{Value: value, Name: field.ValueNames[value], Count: count})
+	}
+	switch field.ValueSorting {
+	case types.ValueSortAlpha:
+		slices.SortFunc(ret, func(a, b KeyValueCount) int {
+			if c := strings.Compare(strings.ToLower(a.label()), strings.ToLower(b.label())); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Value, b.Value)
+		})
+	case types.ValueSortNatural:
+		slices.SortFunc(ret, func(a, b KeyValueCount) int {
+			if c := NaturalCompare(a.label(), b.label()); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Value, b.Value)
+		})
+	case types.ValueSortCustom:
+		order := make(map[string]int, len(field.ValueOrder))
+		for i, v := range field.ValueOrder {
+			order[v] = i
+		}
+		slices.SortFunc(ret, func(a, b KeyValueCount) int {
+			ia, aPinned := order[a.Value]
+			ib, bPinned := order[b.Value]
+			switch {
+			case aPinned && bPinned:
+				return cmp.Compare(ia, ib)
+			case aPinned:
+				return -1
+			case bPinned:
+				return 1
+			}
+			return byCountDesc(a, b)
+		})
+	default:
+		slices.SortFunc(ret, byCountDesc)
+	}
+	return ret
+}
+
+// keepsValueOrder tells if the field orders or names its values, otherwise
+// the count order of the sorted values can be read from the values
+func keepsValueOrder(field *types.BaseField) bool {
+	return field.ValueSorting != types.ValueSortCount || len(field.ValueNames) > 0
+}
+
+// NewKeyFieldResult creates a result with the values ordered and limited by
+// the field settings, the values left out are not returned. The sorted values
+// are only returned when the field has a value order or display names
+func NewKeyFieldResult(field *types.BaseField, values map[string]uint64) *KeyFieldResult {
+	sorted := SortValues(field, values)
+	more := 0
+	limited := values
+	if field.ValueLimit > 0 && len(sorted) > field.ValueLimit {
+		more = len(sorted) - field.ValueLimit
+		sorted = sorted[:field.ValueLimit]
+		limited = make(map[string]uint64, len(sorted))
+		for _, v := range sorted {
+			limited[v.Value] = v.Count
+		}
+	}
+	if !keepsValueOrder(field) {
+		sorted = nil
+	}
+	return &KeyFieldResult{
+		Values: limited,
+		Sorted: sorted,
+		More:   more,
+		counts: values,
+	}
+}
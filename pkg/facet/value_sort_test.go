@@ -0,0 +1,71 @@
+package facet
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func sortedValues(result []KeyValueCount) []string {
+	ret := make([]string, len(result))
+	for i, v := range result {
+		ret[i] = v.Value
+	}
+	return ret
+}
+
+func TestNaturalCompare(t *testing.T) {
+	values := []string{"16 GB", "4 GB", "128 GB", "8 GB", "1 TB"}
+	slices.SortFunc(values, NaturalCompare)
+	expected := []string{"1 TB", "4 GB", "8 GB", "16 GB", "128 GB"}
+	if !slices.Equal(values, expected) {
+		t.Errorf("Expected %v got %v", expected, values)
+	}
+}
+
+func TestSortValues(t *testing.T) {
+	values := map[string]uint64{"16 GB": 5, "4 GB": 10, "8 GB": 1, "32 GB": 3}
+	tests := []struct {
+		field    types.BaseField
+		expected []string
+	}{
+		{types.BaseField{ValueSorting: types.ValueSortCount}, []string{"4 GB", "16 GB", "32 GB", "8 GB"}},
+		{types.BaseField{ValueSorting: types.ValueSortAlpha}, []string{"16 GB", "32 GB", "4 GB", "8 GB"}},
+		{types.BaseField{ValueSorting: types.ValueSortNatural}, []string{"4 GB", "8 GB", "16 GB", "32 GB"}},
+		{types.BaseField{ValueSorting: types.ValueSortCustom, ValueOrder: []string{"8 GB", "missing", "32 GB"}}, []string{"8 GB", "32 GB", "4 GB", "16 GB"}},
+	}
+	for _, test := range tests {
+		result := sortedValues(SortValues(&test.field, values))
+		if !slices.Equal(result, test.expected) {
+			t.Errorf("Sorting %d: expected %v got %v", test.field.ValueSorting, test.expected, result)
+		}
+	}
+}
+
+func TestKeyFieldResultLimit(t *testing.T) {
+	values := map[string]uint64{"a": 5, "b": 4, "c": 3}
+	result := NewKeyFieldResult(&types.BaseField{ValueLimit: 2}, values)
+	if result.Sorted != nil || result.More != 1 {
+		t.Errorf("Expected no sorted values for count sorting and 1 more, got %v (%d)", result.Sorted, result.More)
+	}
+	if len(result.Values) != 2 || result.Values["c"] != 0 {
+		t.Errorf("Expected only the sorted values in the map, got %v", result.Values)
+	}
+	if len(result.AllValues()) != 3 || !result.HasValues() {
+		t.Errorf("Expected all values to be kept, got %v", result.AllValues())
+	}
+	if b, _ := json.Marshal(result); strings.Contains(string(b), `"c"`) {
+		t.Errorf("Expected the limited values to be left out, got %s", b)
+	}
+
+	alpha := NewKeyFieldResult(&types.BaseField{ValueSorting: types.ValueSortAlpha, ValueLimit: 2}, values)
+	if len(alpha.Sorted) != 2 || alpha.Sorted[0].Value != "a" || alpha.Sorted[1].Value != "b" {
+		t.Errorf("Expected 2 sorted values for alphabetical sorting, got %v", alpha.Sorted)
+	}
+	if b, _ := json.Marshal(result); strings.Contains(string(b), `"sorted"`) {
+		t.Errorf("Expected the sorted values to be left out for count sorting, got %s", b)
+	}
+}
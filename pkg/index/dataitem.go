@@ -213,6 +213,10 @@ func (m *DataItem) GetNumberFields() map[types.FacetId]float64 {
 	return m.Fields.GetNumberFields()
 }
 
+func (m *DataItem) GetNumberListFields() map[types.FacetId][]float64 {
+	return m.Fields.GetNumberListFields()
+}
+
 func (m *DataItem) GetStringFieldValue(id types.FacetId) (string, bool) {
 	return m.Fields.GetStringFieldValue(id)
 }
@@ -224,6 +228,10 @@ func (m *DataItem) GetNumberFieldValue(id types.FacetId) (float64, bool) {
 	return m.Fields.GetNumberFieldValue(id)
 }
 
+func (m *DataItem) GetNumbersFieldValue(id types.FacetId) ([]float64, bool) {
+	return m.Fields.GetNumbersFieldValue(id)
+}
+
 func (item *DataItem) GetRating() (int, int) {
 	average, ok := item.GetNumberFieldValue(6)
 	if !ok {
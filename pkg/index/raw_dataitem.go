@@ -112,6 +112,10 @@ func (item *RawDataItem) GetNumberFields() map[types.FacetId]float64 {
 	return item.getItem().GetNumberFields()
 }
 
+func (item *RawDataItem) GetNumberListFields() map[types.FacetId][]float64 {
+	return item.getItem().GetNumberListFields()
+}
+
 func (item *RawDataItem) GetStringFieldValue(id types.FacetId) (string, bool) {
 	return item.getItem().GetStringFieldValue(id)
 }
@@ -124,6 +128,10 @@ func (item *RawDataItem) GetNumberFieldValue(id types.FacetId) (float64, bool) {
 	return item.getItem().GetNumberFieldValue(id)
 }
 
+func (item *RawDataItem) GetNumbersFieldValue(id types.FacetId) ([]float64, bool) {
+	return item.getItem().GetNumbersFieldValue(id)
+}
+
 func (item *RawDataItem) GetRating() (int, int) {
 	return item.getItem().GetRating()
 }
@@ -0,0 +1,104 @@
+package sorting
+
+import (
+	"fmt"
+	"iter"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+// ItemSource provides the items to score again when a campaign starts or ends
+type ItemSource interface {
+	GetAllItems() iter.Seq[types.Item]
+}
+
+// SetItemSource enables re-scoring of the popular sorts when campaigns change
+func (h *SortingItemHandler) SetItemSource(source ItemSource) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.itemSource = source
+}
+
+// campaignRules returns the campaigns of the popularity rules and the
+// experiment variants keyed by their position and name, so the keys stay the
+// same when the settings are loaded again. Callers hold the lock
+func (h *SortingItemHandler) campaignRules() map[string]*types.CampaignRule {
+	ret := make(map[string]*types.CampaignRule)
+	add := func(prefix string, rules []types.ItemPopularityRule) {
+		for idx, c := range types.FindCampaigns(rules...) {
+			ret[fmt.Sprintf("%s/%d/%s", prefix, idx, c.Name)] = c
+		}
+	}
+	types.CurrentSettings.RLock()
+	rules := types.CurrentSettings.PopularityRules
+	types.CurrentSettings.RUnlock()
+	if rules != nil {
+		add("popular", *rules)
+	}
+	if h.experiment != nil {
+		for i := range h.experiment.Variants {
+			if rules, ok := h.experiment.Variants[i].Rules(); ok {
+				add("variant-"+h.experiment.Variants[i].Id, rules)
+			}
+		}
+	}
+	return ret
+}
+
+// checkCampaigns starts re-scoring the popular sorts when a campaign window
+// opens or closes, or an active campaign is removed. Callers hold the lock
+func (h *SortingItemHandler) checkCampaigns(now time.Time) {
+	campaigns := h.campaignRules()
+	state := make(map[string]bool, len(campaigns))
+	var changed []string
+	for key, c := range campaigns {
+		active := c.IsActive(now)
+		state[key] = active
+		if was, seen := h.campaigns[key]; (seen && was != active) || (!seen && active) {
+			changed = append(changed, key)
+		}
+	}
+	for key, was := range h.campaigns {
+		if _, found := state[key]; !found && was {
+			changed = append(changed, key)
+		}
+	}
+	if h.campaigns == nil || h.itemSource == nil {
+		h.campaigns = state
+		return
+	}
+	if len(changed) == 0 {
+		h.campaigns = state
+		return
+	}
+	// the state is kept until the items can be scored again
+	if !h.rescoring.CompareAndSwap(false, true) {
+		return
+	}
+	h.campaigns = state
+	for _, key := range changed {
+		if state[key] {
+			log.Printf("Campaign %s started", key)
+		} else {
+			log.Printf("Campaign %s ended", key)
+		}
+	}
+	sorters := slices.DeleteFunc(slices.Clone(h.Sorters), func(s Sorter) bool {
+		return s.Name() != "popular" && !slices.Contains(h.experimentSorters, s)
+	})
+	go h.rescore(sorters, h.itemSource)
+}
+
+// rescore processes all items with the sorters, only the items with a
+// changed score are moved
+func (h *SortingItemHandler) rescore(sorters []Sorter, source ItemSource) {
+	defer h.rescoring.Store(false)
+	for item := range source.GetAllItems() {
+		for _, s := range sorters {
+			s.ProcessItem(item)
+		}
+	}
+}
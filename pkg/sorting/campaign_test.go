@@ -0,0 +1,82 @@
+package sorting
+
+import (
+	"iter"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+type itemSlice []types.Item
+
+func (s itemSlice) GetAllItems() iter.Seq[types.Item] {
+	return slices.Values(s)
+}
+
+func waitForRescore(t *testing.T, h *SortingItemHandler) {
+	deadline := time.Now().Add(5 * time.Second)
+	for h.rescoring.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("Rescoring did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCampaignRescoresPopular(t *testing.T) {
+	now := time.Now()
+	campaign := &types.CampaignRule{
+		Name:      "sale",
+		ValidFrom: now.Add(time.Hour).UnixMilli(),
+		Rules:     types.JsonTypes{&types.ExpressionRule{Expression: "price"}},
+	}
+	previous := types.CurrentSettings.PopularityRules
+	types.CurrentSettings.PopularityRules = &types.ItemPopularityRules{campaign}
+	defer func() {
+		types.CurrentSettings.PopularityRules = previous
+	}()
+
+	items := itemSlice{newMockItem(1, 100), newMockItem(2, 300), newMockItem(3, 200)}
+	popular := NewPopularitySorter()
+	h := &SortingItemHandler{
+		Sorters:    []Sorter{popular},
+		itemSource: items,
+	}
+	for _, item := range items {
+		popular.ProcessItem(item)
+	}
+	h.checkCampaigns(now)
+	if h.rescoring.Load() {
+		t.Fatal("Expected no rescoring before the campaign starts")
+	}
+
+	campaign.ValidFrom = now.Add(-time.Minute).UnixMilli()
+	h.checkCampaigns(now)
+	waitForRescore(t, h)
+	got := sortIds(h.GetSort("popular"))
+	if !slices.Equal(got, []uint32{2, 3, 1}) {
+		t.Errorf("Expected campaign order got %v", got)
+	}
+
+	// reloading the settings keeps the state of the campaign
+	reloaded := *campaign
+	types.CurrentSettings.PopularityRules = &types.ItemPopularityRules{&reloaded}
+	h.checkCampaigns(now)
+	if h.rescoring.Load() {
+		t.Fatal("Expected no rescoring when the settings are reloaded")
+	}
+
+	// the campaign ends in the settings loaded next
+	ended := reloaded
+	ended.ValidTo = now.Add(-time.Second).UnixMilli()
+	types.CurrentSettings.PopularityRules = &types.ItemPopularityRules{&ended}
+	h.checkCampaigns(now)
+	waitForRescore(t, h)
+	for _, v := range h.GetSort("popular") {
+		if v.Value != 0 {
+			t.Errorf("Expected no campaign score after end, got %v", v)
+		}
+	}
+}
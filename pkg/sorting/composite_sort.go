@@ -0,0 +1,141 @@
+package sorting
+
+import (
+	"cmp"
+	"iter"
+	"log"
+	"math"
+	"slices"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+// CompositeSubsetLimit is the largest result sorted directly on all keys,
+// larger results walk the precomputed order of the first key and only sort
+// the items tied on it
+const CompositeSubsetLimit = 10_000
+
+type compositeEntry struct {
+	id     uint32
+	values []float64
+}
+
+// compareComposite orders by each key in turn, items without a value for a key
+// are placed after the items with one and ties are ordered by id
+func compareComposite(keys []types.SortKey) func(a, b compositeEntry) int {
+	return func(a, b compositeEntry) int {
+		for i, key := range keys {
+			av, bv := a.values[i], b.values[i]
+			aMissing, bMissing := math.IsNaN(av), math.IsNaN(bv)
+			switch {
+			case aMissing && bMissing:
+				continue
+			case aMissing:
+				return 1
+			case bMissing:
+				return -1
+			}
+			c := cmp.Compare(av, bv)
+			if key.Descending {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.id, b.id)
+	}
+}
+
+func makeEntry(id uint32, lookups []SortSnapshot) compositeEntry {
+	values := make([]float64, len(lookups))
+	for i, l := range lookups {
+		values[i] = l.Value(id)
+	}
+	return compositeEntry{id: id, values: values}
+}
+
+// getCompositeIterator yields the items ordered by the keys starting at start,
+// keys without a sort are ignored
+func (h *SortingItemHandler) getCompositeIterator(keys []types.SortKey, items *types.ItemList, start int) iter.Seq[types.ItemId] {
+	validKeys := make([]types.SortKey, 0, len(keys))
+	lookups := make([]SortSnapshot, 0, len(keys))
+	for _, key := range keys {
+		l, ok := h.getSnapshot(key.Name)
+		if !ok || l.Len() == 0 {
+			log.Printf("Unknown sort key %s", key.Name)
+			continue
+		}
+		validKeys = append(validKeys, key)
+		lookups = append(lookups, l)
+	}
+	if len(lookups) == 0 {
+		return h.GetSortedItemsIterator(0, "popular", items, start)
+	}
+	compare := compareComposite(validKeys)
+
+	return func(yield func(types.ItemId) bool) {
+		if items == nil || items.IsEmpty() {
+			return
+		}
+		c := 0
+		emit := func(entries []compositeEntry) bool {
+			for _, e := range entries {
+				if c < start {
+					c++
+					continue
+				}
+				if !yield(types.ItemId(e.id)) {
+					return false
+				}
+			}
+			return true
+		}
+		// items without a value for the first key are placed last
+		missing := func() []compositeEntry {
+			ret := make([]compositeEntry, 0)
+			items.ForEach(func(id uint32) bool {
+				if !lookups[0].Has(id) {
+					ret = append(ret, makeEntry(id, lookups))
+				}
+				return true
+			})
+			slices.SortFunc(ret, compare)
+			return ret
+		}
+
+		if items.Len() <= CompositeSubsetLimit {
+			entries := make([]compositeEntry, 0, items.Len())
+			items.ForEach(func(id uint32) bool {
+				entries = append(entries, makeEntry(id, lookups))
+				return true
+			})
+			slices.SortFunc(entries, compare)
+			emit(entries)
+			return
+		}
+
+		primary := lookups[0]
+		reverse := primary.isAscending() == validKeys[0].Descending
+		group := make([]compositeEntry, 0, 16)
+		groupValue := math.NaN()
+		flush := func() bool {
+			slices.SortFunc(group, compare)
+			ok := emit(group)
+			group = group[:0]
+			return ok
+		}
+		for id := range primary.Iterate(items.Bitmap(), 0, reverse) {
+			value := primary.Value(id)
+			if value != groupValue && len(group) > 0 && !flush() {
+				return
+			}
+			groupValue = value
+			group = append(group, makeEntry(id, lookups))
+		}
+		if len(group) > 0 && !flush() {
+			return
+		}
+		emit(missing())
+	}
+}
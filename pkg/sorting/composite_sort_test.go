@@ -0,0 +1,80 @@
+package sorting
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func compositeHandler(items int) *SortingItemHandler {
+	price := make(types.ByValue, 0, items)
+	popular := make(types.ByValue, 0, items)
+	for i := 1; i <= items; i++ {
+		// prices repeat every 10 items, popularity grows with the id
+		price = append(price, types.Lookup{Id: uint32(i), Value: float64(i%10) * 100})
+		popular = append(popular, types.Lookup{Id: uint32(i), Value: float64(i)})
+	}
+	SortByValuesOrder(price, true)
+	SortByValuesOrder(popular, false)
+	return &SortingItemHandler{
+		Sorters:      []Sorter{newStaticSorter("popular", popular), newStaticSorter("price", price)},
+		fieldSorters: map[string]*FieldSorter{},
+	}
+}
+
+func takeIds(it func(func(types.ItemId) bool), n int) []types.ItemId {
+	ret := make([]types.ItemId, 0, n)
+	for id := range it {
+		ret = append(ret, id)
+		if len(ret) == n {
+			break
+		}
+	}
+	return ret
+}
+
+func TestCompositeSortSubset(t *testing.T) {
+	h := compositeHandler(30)
+	items := types.NewItemList()
+	for _, id := range []uint32{1, 11, 21, 2, 12, 40} {
+		items.AddId(id)
+	}
+	got := takeIds(h.GetSortedItemsIterator(0, "price,-popular", items, 0), 10)
+	// id 40 is not in any sort and is placed last
+	if !slices.Equal(got, []types.ItemId{21, 11, 1, 12, 2, 40}) {
+		t.Errorf("Expected price then popularity got %v", got)
+	}
+	got = takeIds(h.GetSortedItemsIterator(0, "-price,popular", items, 1), 10)
+	if !slices.Equal(got, []types.ItemId{12, 1, 11, 21, 40}) {
+		t.Errorf("Expected reversed keys from the start got %v", got)
+	}
+	got = takeIds(h.GetSortedItemsIterator(0, "-unknown", items, 0), 10)
+	if !slices.Equal(got, []types.ItemId{21, 12, 11, 2, 1}) {
+		t.Errorf("Expected popular order for unknown keys got %v", got)
+	}
+}
+
+func TestCompositeSortLarge(t *testing.T) {
+	size := CompositeSubsetLimit + 100
+	h := compositeHandler(size)
+	items := types.NewItemList()
+	for i := 1; i <= size; i++ {
+		items.AddId(uint32(i))
+	}
+	items.AddId(uint32(size + 1))
+
+	got := takeIds(h.GetSortedItemsIterator(0, "price,-popular", items, 0), 3)
+	last := types.ItemId(size - size%10)
+	if !slices.Equal(got, []types.ItemId{last, last - 10, last - 20}) {
+		t.Errorf("Expected cheapest and most popular first got %v", got)
+	}
+	got = takeIds(h.GetSortedItemsIterator(0, "-price,popular", items, 5), 2)
+	if !slices.Equal(got, []types.ItemId{59, 69}) {
+		t.Errorf("Expected most expensive and least popular after the start got %v", got)
+	}
+	all := takeIds(h.GetSortedItemsIterator(0, "price,popular", items, 0), size+10)
+	if len(all) != size+1 || all[len(all)-1] != types.ItemId(size+1) {
+		t.Errorf("Expected all items with the unsorted item last, got %d items", len(all))
+	}
+}
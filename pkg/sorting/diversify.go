@@ -0,0 +1,61 @@
+package sorting
+
+import (
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+// Diversify re-ranks the items with maximal marginal relevance. The relevance
+// of an item falls with its position and the penalty is the largest share of
+// facet values it has in common with an item already placed, strength weighs
+// the penalty against the relevance
+func Diversify(items []types.Item, facetIds []types.FacetId, strength float64) []types.Item {
+	if strength <= 0 || len(facetIds) == 0 || len(items) < 3 {
+		return items
+	}
+	n := len(items)
+	values := make([][]string, n)
+	for i, item := range items {
+		values[i] = make([]string, len(facetIds))
+		for j, id := range facetIds {
+			if v, ok := item.GetStringFieldValue(id); ok {
+				values[i][j] = v
+			}
+		}
+	}
+	similarity := func(a, b int) float64 {
+		shared := 0
+		for j := range facetIds {
+			if values[a][j] != "" && values[a][j] == values[b][j] {
+				shared++
+			}
+		}
+		return float64(shared) / float64(len(facetIds))
+	}
+
+	ret := make([]types.Item, 0, n)
+	placed := make([]bool, n)
+	// the largest similarity to a placed item, updated as items are placed
+	penalty := make([]float64, n)
+	for range n {
+		best := -1
+		bestScore := 0.0
+		for i := range n {
+			if placed[i] {
+				continue
+			}
+			relevance := 1 - float64(i)/float64(n)
+			score := (1-strength)*relevance - strength*penalty[i]
+			if best == -1 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		placed[best] = true
+		ret = append(ret, items[best])
+		for i := range n {
+			if !placed[i] {
+				penalty[i] = max(penalty[i], similarity(i, best))
+			}
+		}
+	}
+	return ret
+}
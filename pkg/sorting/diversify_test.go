@@ -0,0 +1,45 @@
+package sorting
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func diversityItems(brands ...string) []types.Item {
+	ret := make([]types.Item, 0, len(brands))
+	for i, brand := range brands {
+		item := newMockItem(i+1, 0)
+		item.stringMap = map[types.FacetId]string{1: brand}
+		ret = append(ret, item)
+	}
+	return ret
+}
+
+func itemIds(items []types.Item) []types.ItemId {
+	ret := make([]types.ItemId, 0, len(items))
+	for _, item := range items {
+		ret = append(ret, item.GetId())
+	}
+	return ret
+}
+
+func TestDiversify(t *testing.T) {
+	items := diversityItems("a", "a", "a", "b", "a", "c")
+	if got := itemIds(Diversify(items, []types.FacetId{1}, 0)); !slices.Equal(got, []types.ItemId{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("Expected no change without strength got %v", got)
+	}
+	got := itemIds(Diversify(items, []types.FacetId{1}, 0.5))
+	if !slices.Equal(got, []types.ItemId{1, 4, 6, 2, 3, 5}) {
+		t.Errorf("Expected brands spread out got %v", got)
+	}
+	// a weak penalty only moves items a few positions
+	got = itemIds(Diversify(items, []types.FacetId{1}, 0.2))
+	if !slices.Equal(got, []types.ItemId{1, 2, 4, 3, 6, 5}) {
+		t.Errorf("Expected small adjustments got %v", got)
+	}
+	if got := itemIds(Diversify(items, nil, 0.5)); !slices.Equal(got, []types.ItemId{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("Expected no change without facets got %v", got)
+	}
+}
@@ -0,0 +1,109 @@
+package sorting
+
+import (
+	"iter"
+	"log"
+	"slices"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+// newVariantSorter builds the popular sort of an experiment variant
+func newVariantSorter(e *types.Experiment, v *types.ExperimentVariant) Sorter {
+	overrideKey := v.OverrideKey
+	if overrideKey == "" {
+		overrideKey = "popular"
+	}
+	rules, ok := v.Rules()
+	return NewBaseSorterWithCustomOverrideKey(e.SortName(v), func(item types.Item) float64 {
+		if ok {
+			return types.CollectPopularity(item, rules...)
+		}
+		return types.CollectPopularity(item, *types.CurrentSettings.PopularityRules...)
+	}, false, overrideKey)
+}
+
+func sameExperiment(a, b *types.Experiment) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Id == b.Id && a.Started == b.Started
+}
+
+// SetExperiment replaces the sorters of the running experiment, the items are
+// processed by the new sorters before they are used
+func (h *SortingItemHandler) SetExperiment(e *types.Experiment, items iter.Seq[types.Item]) {
+	h.experimentMu.Lock()
+	defer h.experimentMu.Unlock()
+	h.mu.Lock()
+	if sameExperiment(h.experiment, e) {
+		h.mu.Unlock()
+		return
+	}
+	h.Sorters = slices.DeleteFunc(h.Sorters, func(s Sorter) bool {
+		return slices.Contains(h.experimentSorters, s)
+	})
+	h.experiment = nil
+	h.experimentSorters = nil
+	if e == nil {
+		h.mu.Unlock()
+		log.Printf("Stopped experiment")
+		return
+	}
+	sorters := make([]Sorter, 0, len(e.Variants))
+	for i := range e.Variants {
+		s := newVariantSorter(e, &e.Variants[i])
+		applied := make(map[string]bool, len(h.overrides))
+		for key := range h.overrides {
+			if key == types.EventPopularityKey {
+				key = "popular"
+			}
+			if !applied[key] {
+				applied[key] = true
+				s.HandleOverride(types.SortOverrideUpdate{Key: key, Data: h.sorterOverride(key)})
+			}
+		}
+		sorters = append(sorters, s)
+	}
+	// the sorters get item changes while the existing items are processed
+	h.experimentSorters = sorters
+	h.Sorters = append(h.Sorters, sorters...)
+	h.mu.Unlock()
+
+	for item := range items {
+		for _, s := range sorters {
+			s.ProcessItem(item)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.experiment = e
+	log.Printf("Started experiment %s with %d variants", e.Id, len(sorters))
+}
+
+// popularSortFor returns the popular sort of the session's variant and the
+// variant, nil when the session gets the popular sort
+func (h *SortingItemHandler) popularSortFor(sessionId int) (string, *types.ExperimentAssignment) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	v, ok := h.experiment.Assign(sessionId)
+	if !ok {
+		return "popular", nil
+	}
+	name := h.experiment.SortName(v)
+	if _, found := h.sorterUnsafe(name); !found {
+		return "popular", nil
+	}
+	return name, &types.ExperimentAssignment{Experiment: h.experiment.Id, Variant: v.Id}
+}
+
+// ExperimentAssignment returns the experiment variant the items of the sort
+// are ordered by for the session, nil when no variant is used
+func (h *SortingItemHandler) ExperimentAssignment(sessionId int, sort string) *types.ExperimentAssignment {
+	if sort != "popular" || sessionId == 0 {
+		return nil
+	}
+	_, assignment := h.popularSortFor(sessionId)
+	return assignment
+}
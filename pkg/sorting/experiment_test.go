@@ -0,0 +1,109 @@
+package sorting
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func sessionFor(t *testing.T, e *types.Experiment, variant string) int {
+	for session := 1; session < 1000; session++ {
+		if v, ok := e.Assign(session); ok && v.Id == variant {
+			return session
+		}
+	}
+	t.Fatalf("No session for variant %s", variant)
+	return 0
+}
+
+func TestExperimentSorters(t *testing.T) {
+	e := &types.Experiment{}
+	err := json.Unmarshal([]byte(`{"id":"rank","started":1,"variants":[
+		{"id":"a","share":1,"popularityRules":[{"$type":"RatingRule","valueIfNoMatch":1}]},
+		{"id":"b","share":1,"overrideKey":"popular-b","popularityRules":[{"$type":"RatingRule"}]}
+	]}`), e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := compositeHandler(0)
+	h.Sorters = []Sorter{newStaticSorter("popular", types.ByValue{{Id: 4, Value: 4}, {Id: 3, Value: 3}, {Id: 2, Value: 2}, {Id: 1, Value: 1}})}
+	h.overrides = map[string]types.SortOverride{"popular-b": {3: 10}}
+	sorters := len(h.Sorters)
+	items := []types.Item{newMockItem(1, 0), newMockItem(2, 0), newMockItem(3, 0), newMockItem(4, 0)}
+	h.SetExperiment(e, slices.Values(items))
+
+	all := types.NewItemList()
+	for i := uint32(1); i <= 4; i++ {
+		all.AddId(i)
+	}
+	got := takeIds(h.GetSortedItemsIterator(sessionFor(t, e, "a"), "popular", all, 0), 10)
+	if !slices.Equal(got, []types.ItemId{1, 2, 3, 4}) {
+		t.Errorf("Expected variant a rules got %v", got)
+	}
+	got = takeIds(h.GetSortedItemsIterator(sessionFor(t, e, "b"), "popular", all, 0), 10)
+	if !slices.Equal(got, []types.ItemId{3, 1, 2, 4}) {
+		t.Errorf("Expected variant b override got %v", got)
+	}
+	sessionB := sessionFor(t, e, "b")
+	if a := h.ExperimentAssignment(sessionB, "popular"); a == nil || a.Experiment != "rank" || a.Variant != "b" {
+		t.Errorf("Expected variant b assignment got %v", a)
+	}
+	if a := h.ExperimentAssignment(sessionB, "price"); a != nil {
+		t.Errorf("Expected no assignment for other sorts got %v", a)
+	}
+	if a := h.ExperimentAssignment(0, "popular"); a != nil {
+		t.Errorf("Expected no assignment without session got %v", a)
+	}
+	popular := []types.ItemId{4, 3, 2, 1}
+	if got = takeIds(h.GetSortedItemsIterator(0, "popular", all, 0), 10); !slices.Equal(got, popular) {
+		t.Errorf("Expected popular without session got %v", got)
+	}
+
+	// variant sorters follow item changes
+	for _, s := range h.experimentSorters {
+		s.ProcessItem(&mockItem{id: 1, deleted: true})
+	}
+	got = takeIds(h.GetSortedItemsIterator(sessionFor(t, e, "a"), "popular", all, 0), 10)
+	if !slices.Equal(got, []types.ItemId{2, 3, 4}) {
+		t.Errorf("Expected deleted item to be removed got %v", got)
+	}
+
+	h.SetExperiment(nil, nil)
+	if len(h.Sorters) != sorters {
+		t.Errorf("Expected variant sorters to be removed got %d", len(h.Sorters))
+	}
+	if got = takeIds(h.GetSortedItemsIterator(sessionFor(t, e, "b"), "popular", all, 0), 10); !slices.Equal(got, popular) {
+		t.Errorf("Expected popular after the experiment got %v", got)
+	}
+	if a := h.ExperimentAssignment(sessionB, "popular"); a != nil {
+		t.Errorf("Expected no assignment after the experiment got %v", a)
+	}
+}
+
+func TestEventPopularityIsAddedToPopular(t *testing.T) {
+	popular := NewPopularitySorter()
+	h := &SortingItemHandler{
+		overrides: map[string]types.SortOverride{},
+		Sorters:   []Sorter{popular},
+	}
+	previous := types.CurrentSettings.PopularityRules
+	types.CurrentSettings.PopularityRules = &types.ItemPopularityRules{}
+	defer func() {
+		types.CurrentSettings.PopularityRules = previous
+	}()
+	for i := 1; i <= 3; i++ {
+		popular.ProcessItem(newMockItem(i, 0))
+	}
+	h.HandleSortOverrideUpdate(types.SortOverrideUpdate{Key: "popular", Data: types.SortOverride{1: 10, 2: 5}})
+	h.HandleSortOverrideUpdate(types.SortOverrideUpdate{Key: types.EventPopularityKey, Data: types.SortOverride{2: 6, 3: 1}})
+	if got := sortIds(popular.GetSort()); !slices.Equal(got, []uint32{2, 1, 3}) {
+		t.Errorf("Expected merged popular order got %v", got)
+	}
+	// a new popular override keeps the event popularity
+	h.HandleSortOverrideUpdate(types.SortOverrideUpdate{Key: "popular", Data: types.SortOverride{3: 20}})
+	if got := sortIds(popular.GetSort()); !slices.Equal(got, []uint32{3, 2, 1}) {
+		t.Errorf("Expected merged popular order got %v", got)
+	}
+}
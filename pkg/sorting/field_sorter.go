@@ -0,0 +1,156 @@
+package sorting
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+const (
+	// FieldSortTTL is how long a field sorter is kept without being used
+	FieldSortTTL = 30 * time.Minute
+	// MaxFieldSorters limits the field sorters kept, the least recently used is evicted
+	MaxFieldSorters = 32
+)
+
+// FieldSortSource provides the items of a numeric facet ordered by value and
+// the items having a value, only for facets that are marked sortable
+type FieldSortSource interface {
+	GetSortValues(id types.FacetId, ascending bool) (types.ByValue, *types.ItemList, bool)
+	IsSortable(id types.FacetId) bool
+}
+
+type FieldSort struct {
+	FacetId   types.FacetId
+	Ascending bool
+}
+
+// ParseFieldSort reads sort names like field:<facetId>:asc or field:<facetId>:desc,
+// the direction defaults to ascending
+func ParseFieldSort(name string) (FieldSort, bool) {
+	rest, ok := strings.CutPrefix(name, "field:")
+	if !ok {
+		return FieldSort{}, false
+	}
+	idString, direction, _ := strings.Cut(rest, ":")
+	id, err := strconv.ParseUint(idString, 10, 32)
+	if err != nil {
+		return FieldSort{}, false
+	}
+	ret := FieldSort{FacetId: types.FacetId(id)}
+	switch direction {
+	case "", "asc":
+		ret.Ascending = true
+	case "desc":
+	default:
+		return FieldSort{}, false
+	}
+	return ret, true
+}
+
+func (f FieldSort) Name() string {
+	if f.Ascending {
+		return fmt.Sprintf("field:%d:asc", f.FacetId)
+	}
+	return fmt.Sprintf("field:%d:desc", f.FacetId)
+}
+
+// FieldSorter orders items by the value of a numeric facet, the order is read
+// from the facet once and then kept ordered from the values of the processed
+// items, so it doesn't depend on the facet being updated first
+type FieldSorter struct {
+	FieldSort
+	mu       sync.Mutex
+	state    *sortState
+	dirty    atomic.Bool
+	lastUsed atomic.Int64
+}
+
+// NewFieldSorter builds the sorter from the source, false when the facet
+// can't be sorted on
+func NewFieldSorter(fieldSort FieldSort, source FieldSortSource) (*FieldSorter, bool) {
+	sort, _, ok := source.GetSortValues(fieldSort.FacetId, fieldSort.Ascending)
+	if !ok {
+		return nil, false
+	}
+	s := &FieldSorter{
+		FieldSort: fieldSort,
+		state:     newSortState(sort, LookupSortFunc(fieldSort.Ascending)),
+	}
+	s.touch()
+	return s, true
+}
+
+func (s *FieldSorter) touch() {
+	s.lastUsed.Store(time.Now().UnixNano())
+}
+
+func (s *FieldSorter) unusedFor(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, s.lastUsed.Load()))
+}
+
+// itemValue returns the value the item is sorted by, the lowest value
+// ascending and the highest descending like the facet order
+func (s *FieldSorter) itemValue(item types.Item) (float64, bool) {
+	values, ok := item.GetNumbersFieldValue(s.FacetId)
+	if !ok {
+		if text, found := item.GetStringFieldValue(s.FacetId); found {
+			for part := range strings.SplitSeq(text, ";") {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(part), 64); err == nil {
+					values = append(values, v)
+				}
+			}
+		}
+	}
+	if len(values) == 0 {
+		return 0, false
+	}
+	if s.Ascending {
+		return slices.Min(values), true
+	}
+	return slices.Max(values), true
+}
+
+// ProcessItem moves the item to the position of its value, deleted items and
+// items without a value are removed
+func (s *FieldSorter) ProcessItem(item types.Item) {
+	id := uint32(item.GetId())
+	value, hasValue := s.itemValue(item)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var changed bool
+	if hasValue && !item.IsDeleted() {
+		changed = s.state.set(types.Lookup{Id: id, Value: value})
+	} else {
+		changed = s.state.remove(id)
+	}
+	if changed {
+		s.dirty.Store(true)
+	}
+}
+
+// Snapshot returns the current order, later changes don't affect it
+func (s *FieldSorter) Snapshot() SortSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.snapshot()
+}
+
+func (s *FieldSorter) GetSort() types.ByValue {
+	s.dirty.Store(false)
+	return s.Snapshot().Values()
+}
+
+func (s *FieldSorter) IsDirty() bool {
+	return s.dirty.Load()
+}
+
+func (s *FieldSorter) HandleOverride(types.SortOverrideUpdate) {
+	// facet values can't be overridden
+}
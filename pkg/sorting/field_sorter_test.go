@@ -0,0 +1,128 @@
+package sorting
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+type staticFieldSource struct {
+	calls  int
+	values map[types.FacetId]types.ByValue
+}
+
+func (s *staticFieldSource) GetSortValues(id types.FacetId, ascending bool) (types.ByValue, *types.ItemList, bool) {
+	values, ok := s.values[id]
+	if !ok {
+		return nil, nil, false
+	}
+	s.calls++
+	ret := slices.Clone(values)
+	SortByValuesOrder(ret, ascending)
+	ids := types.NewItemList()
+	for _, v := range ret {
+		ids.AddId(v.Id)
+	}
+	return ret, ids, true
+}
+
+func (s *staticFieldSource) IsSortable(id types.FacetId) bool {
+	_, ok := s.values[id]
+	return ok
+}
+
+func TestParseFieldSort(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected FieldSort
+		ok       bool
+	}{
+		{"field:12:asc", FieldSort{FacetId: 12, Ascending: true}, true},
+		{"field:12:desc", FieldSort{FacetId: 12}, true},
+		{"field:12", FieldSort{FacetId: 12, Ascending: true}, true},
+		{"field:12:up", FieldSort{}, false},
+		{"field:abc:asc", FieldSort{}, false},
+		{"popular", FieldSort{}, false},
+	}
+	for _, test := range tests {
+		got, ok := ParseFieldSort(test.input)
+		if ok != test.ok || got != test.expected {
+			t.Errorf("%s: expected %+v (%v) got %+v (%v)", test.input, test.expected, test.ok, got, ok)
+		}
+	}
+}
+
+func iteratorIds(h *SortingItemHandler, sort string, items *types.ItemList, start int) []types.ItemId {
+	ret := make([]types.ItemId, 0)
+	for id := range h.GetSortedItemsIterator(0, sort, items, start) {
+		ret = append(ret, id)
+	}
+	return ret
+}
+
+func TestFieldSortIterator(t *testing.T) {
+	source := &staticFieldSource{values: map[types.FacetId]types.ByValue{
+		5: {{Id: 1, Value: 55}, {Id: 2, Value: 32}, {Id: 3, Value: 65}},
+	}}
+	h := &SortingItemHandler{
+		Sorters:      []Sorter{newStaticSorter("popular", types.ByValue{{Id: 4, Value: 10}, {Id: 3, Value: 9}, {Id: 5, Value: 8}, {Id: 1, Value: 7}})},
+		fieldSorters: map[string]*FieldSorter{},
+	}
+	h.SetFieldSource(source)
+	items := types.NewItemList()
+	for _, id := range []uint32{1, 2, 3, 4, 5} {
+		items.AddId(id)
+	}
+
+	if got := iteratorIds(h, "field:5:desc", items, 0); !slices.Equal(got, []types.ItemId{3, 1, 2, 4, 5}) {
+		t.Errorf("Expected items by value then popular got %v", got)
+	}
+	if got := iteratorIds(h, "field:5:asc", items, 2); !slices.Equal(got, []types.ItemId{3, 4, 5}) {
+		t.Errorf("Expected the start to span both parts got %v", got)
+	}
+	if got := iteratorIds(h, "field:9:asc", items, 0); !slices.Equal(got, []types.ItemId{4, 3, 5, 1}) {
+		t.Errorf("Expected popular order for a field that can't be sorted got %v", got)
+	}
+	if len(h.fieldSorters) != 2 {
+		t.Errorf("Expected a sorter per direction got %d", len(h.fieldSorters))
+	}
+
+	calls := source.calls
+	iteratorIds(h, "field:5:desc", items, 0)
+	if source.calls != calls {
+		t.Errorf("Expected the sorter to be reused")
+	}
+	items.AddId(6)
+	h.HandleItems(slices.Values([]types.Item{&types.MockItem{Id: 6, NumberFields: map[types.FacetId]float64{5: 40}}}))
+	if got := iteratorIds(h, "field:5:desc", items, 0); !slices.Equal(got, []types.ItemId{3, 1, 6, 2, 4, 5}) {
+		t.Errorf("Expected the item to be placed by its value got %v", got)
+	}
+	h.HandleItems(slices.Values([]types.Item{&types.MockItem{Id: 1, NumberFields: map[types.FacetId]float64{8: 1}}}))
+	if got := iteratorIds(h, "field:5:desc", items, 0); !slices.Equal(got, []types.ItemId{3, 6, 2, 4, 5, 1}) {
+		t.Errorf("Expected an item without the value to be sorted by popular got %v", got)
+	}
+	h.HandleItems(slices.Values([]types.Item{&types.MockItem{Id: 2, NumberLists: map[types.FacetId][]float64{5: {10, 70}}}}))
+	if got := iteratorIds(h, "field:5:desc", items, 0); !slices.Equal(got, []types.ItemId{2, 3, 6, 4, 5, 1}) {
+		t.Errorf("Expected the highest value to be used descending got %v", got)
+	}
+	if source.calls != calls {
+		t.Errorf("Expected the sort to be updated without the source")
+	}
+
+	h.fieldSorters["field:5:asc"].lastUsed.Store(time.Now().Add(-2 * FieldSortTTL).UnixNano())
+	h.UpdateSorts()
+	if _, ok := h.fieldSorters["field:5:asc"]; ok {
+		t.Errorf("Expected the unused sorter to be evicted")
+	}
+	if _, ok := h.fieldSorters["field:5:desc"]; !ok {
+		t.Errorf("Expected the used sorter to be kept")
+	}
+
+	delete(source.values, 5)
+	h.UpdateSorts()
+	if len(h.fieldSorters) != 0 {
+		t.Errorf("Expected sorters of fields no longer sortable to be evicted got %d", len(h.fieldSorters))
+	}
+}
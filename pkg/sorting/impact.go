@@ -0,0 +1,140 @@
+package sorting
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+const (
+	DefaultImpactTop = 50
+	MaxImpactTop     = 1000
+)
+
+// RankChange compares the popular rank and score of an item, ranks start at
+// 1 and Movement is positive when the item moves up
+type RankChange struct {
+	Id            types.ItemId `json:"id"`
+	Title         string       `json:"title"`
+	CurrentRank   int          `json:"currentRank"`
+	ProposedRank  int          `json:"proposedRank"`
+	Movement      int          `json:"movement"`
+	CurrentScore  float64      `json:"currentScore"`
+	ProposedScore float64      `json:"proposedScore"`
+	ScoreDelta    float64      `json:"scoreDelta"`
+}
+
+// RuleImpact is the difference between two sets of popularity rules
+type RuleImpact struct {
+	// Items is the number of items compared and Changed the ones with a new rank
+	Items   int `json:"items"`
+	Changed int `json:"changed"`
+	// Top is the proposed top with the current ranks
+	Top []RankChange `json:"top"`
+	// Dropped are the items in the current top that are not in the proposed top
+	Dropped []RankChange `json:"dropped"`
+	// Movers are the items in either top with the largest movement
+	Movers []RankChange `json:"movers"`
+}
+
+type impactItem struct {
+	item     types.Item
+	current  float64
+	proposed float64
+}
+
+// rankItems orders the items by score like the popular sorter and returns the
+// rank of each item
+func rankItems(items []impactItem, score func(impactItem) float64) map[types.ItemId]int {
+	order := make(types.ByValue, 0, len(items))
+	for _, item := range items {
+		order = append(order, types.Lookup{Id: uint32(item.item.GetId()), Value: score(item)})
+	}
+	SortByValuesOrder(order, false)
+	ranks := make(map[types.ItemId]int, len(order))
+	for idx, l := range order {
+		ranks[types.ItemId(l.Id)] = idx + 1
+	}
+	return ranks
+}
+
+// SimulateRuleImpact scores the items with the current and the proposed rules
+// and compares the top of the popular order, the override applies to both
+func SimulateRuleImpact(items iter.Seq[types.Item], current, proposed types.ItemPopularityRules, override types.SortOverride, top int) *RuleImpact {
+	if top <= 0 {
+		top = DefaultImpactTop
+	}
+	top = min(top, MaxImpactTop)
+	scored := make([]impactItem, 0)
+	for item := range items {
+		if item.IsDeleted() {
+			continue
+		}
+		boost := override[uint32(item.GetId())]
+		scored = append(scored, impactItem{
+			item:     item,
+			current:  types.CollectPopularity(item, current...) + boost,
+			proposed: types.CollectPopularity(item, proposed...) + boost,
+		})
+	}
+	currentRanks := rankItems(scored, func(i impactItem) float64 { return i.current })
+	proposedRanks := rankItems(scored, func(i impactItem) float64 { return i.proposed })
+
+	impact := &RuleImpact{
+		Items:   len(scored),
+		Top:     make([]RankChange, 0, min(top, len(scored))),
+		Dropped: make([]RankChange, 0),
+		Movers:  make([]RankChange, 0),
+	}
+	for _, i := range scored {
+		id := i.item.GetId()
+		change := RankChange{
+			Id:            id,
+			Title:         i.item.GetTitle(),
+			CurrentRank:   currentRanks[id],
+			ProposedRank:  proposedRanks[id],
+			Movement:      currentRanks[id] - proposedRanks[id],
+			CurrentScore:  i.current,
+			ProposedScore: i.proposed,
+			ScoreDelta:    i.proposed - i.current,
+		}
+		if change.Movement != 0 {
+			impact.Changed++
+		}
+		inCurrent, inProposed := change.CurrentRank <= top, change.ProposedRank <= top
+		if inProposed {
+			impact.Top = append(impact.Top, change)
+		} else if inCurrent {
+			impact.Dropped = append(impact.Dropped, change)
+		}
+		if (inCurrent || inProposed) && change.Movement != 0 {
+			impact.Movers = append(impact.Movers, change)
+		}
+	}
+	slices.SortFunc(impact.Top, func(a, b RankChange) int {
+		return cmp.Compare(a.ProposedRank, b.ProposedRank)
+	})
+	slices.SortFunc(impact.Dropped, func(a, b RankChange) int {
+		return cmp.Compare(a.CurrentRank, b.CurrentRank)
+	})
+	slices.SortFunc(impact.Movers, func(a, b RankChange) int {
+		return cmp.Or(
+			cmp.Compare(absInt(b.Movement), absInt(a.Movement)),
+			cmp.Compare(min(a.CurrentRank, a.ProposedRank), min(b.CurrentRank, b.ProposedRank)),
+			cmp.Compare(a.Id, b.Id),
+		)
+	})
+	if len(impact.Movers) > top {
+		impact.Movers = impact.Movers[:top]
+	}
+	return impact
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
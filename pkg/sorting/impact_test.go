@@ -0,0 +1,51 @@
+package sorting
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func rankChangeIds(changes []RankChange) []types.ItemId {
+	ret := make([]types.ItemId, 0, len(changes))
+	for _, c := range changes {
+		ret = append(ret, c.Id)
+	}
+	return ret
+}
+
+func TestSimulateRuleImpact(t *testing.T) {
+	items := []types.Item{
+		newMockItem(1, 100),
+		newMockItem(2, 400),
+		newMockItem(3, 300),
+		newMockItem(4, 200),
+		&mockItem{id: 5, price: 1000, deleted: true},
+	}
+	// cheap items first today, expensive items first proposed
+	current := types.ItemPopularityRules{&types.ExpressionRule{Expression: "1000 - price"}}
+	proposed := types.ItemPopularityRules{&types.ExpressionRule{Expression: "price"}}
+	override := types.SortOverride{1: 1000}
+
+	impact := SimulateRuleImpact(slices.Values(items), current, proposed, override, 2)
+	if impact.Items != 4 {
+		t.Errorf("Expected 4 items, got %d", impact.Items)
+	}
+	if got := rankChangeIds(impact.Top); !slices.Equal(got, []types.ItemId{1, 2}) {
+		t.Errorf("Expected proposed top [1 2], got %v", got)
+	}
+	if got := rankChangeIds(impact.Dropped); !slices.Equal(got, []types.ItemId{4}) {
+		t.Errorf("Expected [4] dropped, got %v", got)
+	}
+	second := impact.Top[1]
+	if second.CurrentRank != 4 || second.ProposedRank != 2 || second.Movement != 2 || second.ScoreDelta != -200 {
+		t.Errorf("Unexpected change for item 2 %+v", second)
+	}
+	if got := rankChangeIds(impact.Movers); !slices.Equal(got, []types.ItemId{2, 4}) {
+		t.Errorf("Expected movers [2 4], got %v", got)
+	}
+	if impact.Changed != 2 {
+		t.Errorf("Expected 2 changed ranks, got %d", impact.Changed)
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"encoding/json"
 	"iter"
 	"log"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/matst80/slask-finder/pkg/messaging"
@@ -23,6 +23,10 @@ var (
 	})
 )
 
+// maintenanceInterval is how often the sessions, campaigns and field sorters
+// are checked, the sorts themselves are read from snapshots as they change
+const maintenanceInterval = 10 * time.Second
+
 func NewPopularitySorter() Sorter {
 	return NewBaseSorter("popular", func(item types.Item) float64 {
 		return types.CollectPopularity(item, *types.CurrentSettings.PopularityRules...)
@@ -50,18 +54,31 @@ func NewLastUpdateSorter() Sorter {
 }
 
 type SortingItemHandler struct {
-	mu         sync.RWMutex
-	overrides  map[string]types.SortOverride
-	Sorters    []Sorter
-	sortValues map[string]types.ByValue
+	mu            sync.RWMutex
+	overrides     map[string]types.SortOverride
+	Sorters       []Sorter
+	fieldSource   FieldSortSource
+	fieldSorters  map[string]*FieldSorter
+	sessions      *sessionStore
+	sessionValues *sessionValues
+	// experiment is set once the sorters of its variants are ready
+	experimentMu      sync.Mutex
+	experiment        *types.Experiment
+	experimentSorters []Sorter
+	itemSource        ItemSource
+	// campaigns is the last seen state of the campaign rules
+	campaigns map[string]bool
+	rescoring atomic.Bool
 }
 
 func NewSortingItemHandler(itemPopularity *types.SortOverride) *SortingItemHandler {
 	popSorter := NewPopularitySorter()
 	handler := &SortingItemHandler{
-		mu:         sync.RWMutex{},
-		overrides:  make(map[string]types.SortOverride),
-		sortValues: make(map[string]types.ByValue, 3),
+		mu:            sync.RWMutex{},
+		overrides:     make(map[string]types.SortOverride),
+		fieldSorters:  make(map[string]*FieldSorter),
+		sessions:      newSessionStore(),
+		sessionValues: newSessionValues(),
 		Sorters: []Sorter{
 			popSorter,
 			NewLastUpdateSorter(),
@@ -75,7 +92,7 @@ func NewSortingItemHandler(itemPopularity *types.SortOverride) *SortingItemHandl
 			Data: *itemPopularity,
 		})
 	}
-	ticker := time.NewTicker(time.Minute * 2)
+	ticker := time.NewTicker(maintenanceInterval)
 	go func() {
 		for range ticker.C {
 			handler.UpdateSorts()
@@ -84,6 +101,63 @@ func NewSortingItemHandler(itemPopularity *types.SortOverride) *SortingItemHandl
 	return handler
 }
 
+// SetFieldSource enables sorting on numeric facets with field:<facetId>:asc|desc
+func (h *SortingItemHandler) SetFieldSource(source FieldSortSource) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fieldSource = source
+	clear(h.fieldSorters)
+}
+
+// getFieldSorter returns the sorter for a field sort name, the sorter is
+// created on first use and the least recently used is evicted when full
+func (h *SortingItemHandler) getFieldSorter(name string) (*FieldSorter, bool) {
+	fieldSort, ok := ParseFieldSort(name)
+	if !ok {
+		return nil, false
+	}
+	key := fieldSort.Name()
+	h.mu.RLock()
+	s, ok := h.fieldSorters[key]
+	source := h.fieldSource
+	h.mu.RUnlock()
+	if ok {
+		s.touch()
+		return s, true
+	}
+	if source == nil {
+		return nil, false
+	}
+	s, ok = NewFieldSorter(fieldSort, source)
+	if !ok {
+		return nil, false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if existing, found := h.fieldSorters[key]; found {
+		return existing, true
+	}
+	if len(h.fieldSorters) >= MaxFieldSorters {
+		h.evictFieldSorter()
+	}
+	h.fieldSorters[key] = s
+	log.Printf("Created field sort: %s, items: %d", key, s.Snapshot().Len())
+	return s, true
+}
+
+// evictFieldSorter removes the least recently used field sorter, callers hold the lock
+func (h *SortingItemHandler) evictFieldSorter() {
+	now := time.Now()
+	oldest := ""
+	var oldestAge time.Duration
+	for key, s := range h.fieldSorters {
+		if age := s.unusedFor(now); oldest == "" || age > oldestAge {
+			oldest, oldestAge = key, age
+		}
+	}
+	delete(h.fieldSorters, oldest)
+}
+
 func (h *SortingItemHandler) Connect(conn *amqp.Connection) {
 	ch, err := conn.Channel()
 	if err != nil {
@@ -106,22 +180,38 @@ func (h *SortingItemHandler) Connect(conn *amqp.Connection) {
 }
 
 func (h *SortingItemHandler) HandleSortOverrideUpdate(item types.SortOverrideUpdate) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	if strings.Contains(item.Key, "session-") {
-		// Session specific overrides are ignored for now
+	if sessionId, ok := ParseSessionKey(item.Key); ok {
+		h.HandleSessionOverride(sessionId, item.Data)
 		return
 	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	h.overrides[item.Key] = item.Data
 	log.Printf("Applied sort override: %s", item.Key)
+	if item.Key == types.EventPopularityKey {
+		item.Key = "popular"
+	}
+	item.Data = h.sorterOverride(item.Key)
 	for _, s := range h.Sorters {
 		s.HandleOverride(item)
 	}
 }
 
+// sorterOverride is the override applied to the sorters, the event popularity
+// is added to popular. Callers hold the lock
+func (h *SortingItemHandler) sorterOverride(key string) types.SortOverride {
+	events, ok := h.overrides[types.EventPopularityKey]
+	if key != "popular" || !ok {
+		return h.overrides[key]
+	}
+	return types.MergeSortOverrides(h.overrides["popular"], events)
+}
+
 func (h *SortingItemHandler) HandleItems(it iter.Seq[types.Item]) {
 	for item := range it {
+		h.mu.RLock()
 		h.handleItemUnsafe(item)
+		h.mu.RUnlock()
 	}
 }
 
@@ -135,64 +225,128 @@ func (h *SortingItemHandler) HandleItem(item types.Item, wg *sync.WaitGroup) {
 
 func (h *SortingItemHandler) handleItemUnsafe(item types.Item) {
 	for _, s := range h.Sorters {
-		go s.ProcessItem(item)
+		s.ProcessItem(item)
 	}
-}
-
-func (h *SortingItemHandler) updateSorter(s Sorter) {
-
-	if !s.IsDirty() {
-		return
+	for _, s := range h.fieldSorters {
+		s.ProcessItem(item)
 	}
-
-	name := s.Name()
-	sort := s.GetSort()
-
-	if len(sort) > 0 {
-		h.mu.Lock()
-		h.sortValues[name] = sort
-		if name == "popular" {
-			totalItems.Set(float64(len(sort)))
-		}
-		h.mu.Unlock()
-
-		log.Printf("Updated sort: %s, items: %d", name, len(sort))
+	if boost := types.CurrentSettings.GetSessionBoost(); boost != nil && len(boost.FacetIds) > 0 {
+		h.sessionValues.update(item, boost.FacetIds)
 	}
 }
 
 func (h *SortingItemHandler) UpdateSorts() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	if popular, ok := h.sorterUnsafe("popular"); ok {
+		totalItems.Set(float64(popular.Snapshot().Len()))
+	}
+	now := time.Now()
+	h.sessions.expire(now)
+	h.checkCampaigns(now)
+	for key, s := range h.fieldSorters {
+		if s.unusedFor(now) > FieldSortTTL {
+			delete(h.fieldSorters, key)
+			log.Printf("Evicted unused field sort: %s", key)
+		} else if h.fieldSource != nil && !h.fieldSource.IsSortable(s.FacetId) {
+			delete(h.fieldSorters, key)
+			log.Printf("Evicted field sort that is no longer sortable: %s", key)
+		}
+	}
+}
+
+// sorterUnsafe returns the sorter with the name, callers hold the lock
+func (h *SortingItemHandler) sorterUnsafe(name string) (Sorter, bool) {
 	for _, s := range h.Sorters {
-		go h.updateSorter(s)
+		if s.Name() == name {
+			return s, true
+		}
 	}
+	return nil, false
 }
 
-// Delegation methods for backward compatibility
-func (h *SortingItemHandler) GetSort(id string) types.ByValue {
+// getSnapshot returns the current order of a sort or field sort
+func (h *SortingItemHandler) getSnapshot(name string) (SortSnapshot, bool) {
 	h.mu.RLock()
-	defer h.mu.RUnlock()
-	if r, ok := h.sortValues[id]; ok {
-		return r
+	s, ok := h.sorterUnsafe(name)
+	h.mu.RUnlock()
+	if ok {
+		return s.Snapshot(), true
+	}
+	if fieldSorter, isField := h.getFieldSorter(name); isField {
+		return fieldSorter.Snapshot(), true
+	}
+	return SortSnapshot{}, false
+}
+
+// GetSort returns the items of the sort in order
+func (h *SortingItemHandler) GetSort(id string) types.ByValue {
+	if snapshot, ok := h.getSnapshot(id); ok {
+		return snapshot.Values()
 	}
 	return nil
 }
 
+// GetSortedItemsIterator yields the items in the sort order starting at start,
+// field sorts are followed by the items without a value in popular order.
+// Composite sorts like price,-popular are described in types.ParseSortSpec.
+// The popular sort follows the experiment variant of the session and is
+// personalized for sessions with interactions, sessionId 0 returns the same
+// order for everyone
 func (s *SortingItemHandler) GetSortedItemsIterator(sessionId int, sort string, items *types.ItemList, start int) iter.Seq[types.ItemId] {
-	precalculated := s.GetSort(sort)
-	c := 0
+	if keys, ok := types.ParseSortSpec(sort); ok {
+		return s.getCompositeIterator(keys, items, start)
+	}
+	if sort == "popular" && sessionId != 0 {
+		sort, _ = s.popularSortFor(sessionId)
+		if it, ok := s.getPersonalizedIterator(sessionId, sort, items, start); ok {
+			return it
+		}
+	}
+	_, isField := ParseFieldSort(sort)
+	snapshot, ok := s.getSnapshot(sort)
+	if !ok && isField {
+		log.Printf("Can not sort on %s, using popular", sort)
+		snapshot, _ = s.getSnapshot("popular")
+	}
+	isFieldSorter := ok && isField
 	return func(yield func(types.ItemId) bool) {
-		for _, v := range precalculated {
-			if items == nil || !items.Contains(v.Id) {
-				continue
-			}
-			if c < start {
-				c++
-				continue
+		if items.IsEmpty() {
+			return
+		}
+		bm := items.Bitmap()
+		yielded := 0
+		for id := range snapshot.Iterate(bm, start, false) {
+			yielded++
+			if !yield(types.ItemId(id)) {
+				return
 			}
-			if !yield(types.ItemId(v.Id)) {
-				break
+		}
+		if !isFieldSorter {
+			return
+		}
+		// items without a value follow in popular order
+		fallbackStart := 0
+		if yielded == 0 {
+			fallbackStart = max(0, start-snapshot.Count(bm))
+		}
+		popular, _ := s.getSnapshot("popular")
+		for id := range popular.Iterate(snapshot.Without(bm), fallbackStart, false) {
+			if !yield(types.ItemId(id)) {
+				return
 			}
 		}
 	}
 }
+
+func (s *SortingItemHandler) getPersonalizedIterator(sessionId int, sort string, items *types.ItemList, start int) (iter.Seq[types.ItemId], bool) {
+	boost := types.CurrentSettings.GetSessionBoost()
+	if boost == nil || boost.Weight == 0 {
+		return nil, false
+	}
+	profile, ok := s.sessions.get(sessionId, time.Now())
+	if !ok {
+		return nil, false
+	}
+	return s.getSessionIterator(sort, profile, boost.Weight, items, start)
+}
@@ -0,0 +1,150 @@
+package sorting
+
+import (
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+// orderNode is a node of a persistent treap ordered by a lookup comparator.
+// Nodes are never modified after creation, updates copy the path to the root
+// so any root is a consistent snapshot that readers can walk without locks
+type orderNode struct {
+	item        types.Lookup
+	priority    uint32
+	size        int
+	left, right *orderNode
+}
+
+// orderPriority spreads the ids to balance the treap without random state
+func orderPriority(id uint32) uint32 {
+	h := id * 0x9e3779b1
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	return h
+}
+
+func (n *orderNode) len() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *orderNode) with(left, right *orderNode) *orderNode {
+	return &orderNode{
+		item:     n.item,
+		priority: n.priority,
+		size:     left.len() + right.len() + 1,
+		left:     left,
+		right:    right,
+	}
+}
+
+// splitOrder returns the items ordered before key and the rest
+func splitOrder(n *orderNode, key types.Lookup, compare func(a, b types.Lookup) int) (*orderNode, *orderNode) {
+	if n == nil {
+		return nil, nil
+	}
+	if compare(n.item, key) < 0 {
+		l, r := splitOrder(n.right, key, compare)
+		return n.with(n.left, l), r
+	}
+	l, r := splitOrder(n.left, key, compare)
+	return l, n.with(r, n.right)
+}
+
+// mergeOrder joins two trees where all items of a are ordered before b
+func mergeOrder(a, b *orderNode) *orderNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.priority > b.priority {
+		return a.with(a.left, mergeOrder(a.right, b))
+	}
+	return b.with(mergeOrder(a, b.left), b.right)
+}
+
+func insertOrder(n *orderNode, item types.Lookup, compare func(a, b types.Lookup) int) *orderNode {
+	l, r := splitOrder(n, item, compare)
+	node := &orderNode{item: item, priority: orderPriority(item.Id), size: 1}
+	return mergeOrder(mergeOrder(l, node), r)
+}
+
+// removeOrder returns the tree without the item, unchanged when it's missing
+func removeOrder(n *orderNode, item types.Lookup, compare func(a, b types.Lookup) int) *orderNode {
+	if n == nil {
+		return nil
+	}
+	switch c := compare(item, n.item); {
+	case c < 0:
+		left := removeOrder(n.left, item, compare)
+		if left == n.left {
+			return n
+		}
+		return n.with(left, n.right)
+	case c > 0:
+		right := removeOrder(n.right, item, compare)
+		if right == n.right {
+			return n
+		}
+		return n.with(n.left, right)
+	}
+	return mergeOrder(n.left, n.right)
+}
+
+// findOrder returns the item of the tree equal to key
+func findOrder(n *orderNode, key types.Lookup, compare func(a, b types.Lookup) int) (types.Lookup, bool) {
+	for n != nil {
+		switch c := compare(key, n.item); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return n.item, true
+		}
+	}
+	return types.Lookup{}, false
+}
+
+// buildOrder builds the tree from items already in tree order in linear time,
+// the nodes are only modified before the root is returned
+func buildOrder(items []types.Lookup) *orderNode {
+	stack := make([]*orderNode, 0, 64)
+	for _, item := range items {
+		node := &orderNode{item: item, priority: orderPriority(item.Id)}
+		var last *orderNode
+		for len(stack) > 0 && stack[len(stack)-1].priority < node.priority {
+			last = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		}
+		node.left = last
+		if len(stack) > 0 {
+			stack[len(stack)-1].right = node
+		}
+		stack = append(stack, node)
+	}
+	if len(stack) == 0 {
+		return nil
+	}
+	setOrderSizes(stack[0])
+	return stack[0]
+}
+
+func setOrderSizes(n *orderNode) int {
+	if n == nil {
+		return 0
+	}
+	n.size = setOrderSizes(n.left) + setOrderSizes(n.right) + 1
+	return n.size
+}
+
+func walkOrder(n *orderNode, yield func(types.Lookup) bool) bool {
+	if n == nil {
+		return true
+	}
+	return walkOrder(n.left, yield) && yield(n.item) && walkOrder(n.right, yield)
+}
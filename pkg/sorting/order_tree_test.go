@@ -0,0 +1,80 @@
+package sorting
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func TestOrderTreeMatchesSort(t *testing.T) {
+	compare := LookupSortFunc(false)
+	rnd := rand.New(rand.NewSource(2))
+	var root *orderNode
+	current := map[uint32]float64{}
+	for range 5000 {
+		id := uint32(rnd.Intn(500))
+		if v, ok := current[id]; ok {
+			root = removeOrder(root, types.Lookup{Id: id, Value: v}, compare)
+			delete(current, id)
+			if rnd.Intn(3) == 0 {
+				continue
+			}
+		}
+		v := float64(rnd.Intn(50))
+		root = insertOrder(root, types.Lookup{Id: id, Value: v}, compare)
+		current[id] = v
+	}
+	want := make(types.ByValue, 0, len(current))
+	for id, v := range current {
+		want = append(want, types.Lookup{Id: id, Value: v})
+	}
+	slices.SortFunc(want, compare)
+	got := make(types.ByValue, 0, len(want))
+	walkOrder(root, func(v types.Lookup) bool {
+		got = append(got, v)
+		return true
+	})
+	if !slices.Equal(got, want) || root.len() != len(want) {
+		t.Fatalf("Expected tree order to match the sorted items")
+	}
+	built := buildOrder(want)
+	for _, v := range want {
+		if found, ok := findOrder(built, v, compare); !ok || found != v {
+			t.Fatalf("Expected %v in the built tree got %v", v, found)
+		}
+	}
+	if built.len() != len(want) {
+		t.Errorf("Expected %d items in the built tree got %d", len(want), built.len())
+	}
+	if _, ok := findOrder(built, types.Lookup{Id: 9999, Value: 1}, compare); ok {
+		t.Error("Expected no missing item in the built tree")
+	}
+	if removeOrder(root, types.Lookup{Id: 9999, Value: 1}, compare) != root {
+		t.Error("Expected removing a missing item to keep the tree")
+	}
+}
+
+func TestSortSnapshotIsStable(t *testing.T) {
+	s := NewBaseSorter("price", func(it types.Item) float64 {
+		return float64(it.GetPrice())
+	}, true).(*BaseSorter)
+	for i := 1; i <= 5; i++ {
+		s.ProcessItem(newMockItem(i, i*10))
+	}
+	snapshot := s.Snapshot()
+	s.ProcessItem(newMockItem(1, 100))
+	s.ProcessItem(&mockItem{id: 2, deleted: true})
+	if got := snapshot.Values(); len(got) != 5 || got[0].Id != 1 {
+		t.Errorf("Expected the snapshot to keep the old order got %v", got)
+	}
+	got := s.GetSort()
+	ids := make([]uint32, 0, len(got))
+	for _, v := range got {
+		ids = append(ids, v.Id)
+	}
+	if !slices.Equal(ids, []uint32{3, 4, 5, 1}) {
+		t.Errorf("Expected updated order got %v", ids)
+	}
+}
@@ -0,0 +1,269 @@
+package sorting
+
+import (
+	"iter"
+	"slices"
+	"sort"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+// RankBucketSize is the number of consecutive ranks in each bucket of a rank
+// index, buckets changed by updates hold between 1 and twice as many
+const RankBucketSize = 1024
+
+// rankBucket is a run of consecutive ranks with a bitmap of its items
+type rankBucket struct {
+	sorted types.ByValue
+	ids    *roaring.Bitmap
+	// gen is the generation of the rankBuilder that may still modify it
+	gen uint64
+}
+
+func newRankBucket(sorted types.ByValue, gen uint64) *rankBucket {
+	ids := make([]uint32, len(sorted))
+	for i, v := range sorted {
+		ids[i] = v.Id
+	}
+	bm := roaring.BitmapOf(ids...)
+	bm.RunOptimize()
+	return &rankBucket{sorted: sorted, ids: bm, gen: gen}
+}
+
+// RankIndex partitions a sort into buckets of consecutive ranks, each with a
+// bitmap of its items. Iterating a result only counts the matches of the
+// buckets before the start and scans the buckets needed for the page,
+// instead of testing every item of the sort against the result
+type RankIndex struct {
+	buckets []*rankBucket
+	size    int
+	gen     uint64
+}
+
+func NewRankIndex(sorted types.ByValue) *RankIndex {
+	buckets := make([]*rankBucket, 0, len(sorted)/RankBucketSize+1)
+	for lo := 0; lo < len(sorted); lo += RankBucketSize {
+		hi := min(lo+RankBucketSize, len(sorted))
+		buckets = append(buckets, newRankBucket(sorted[lo:hi:hi], 0))
+	}
+	return &RankIndex{
+		buckets: buckets,
+		size:    len(sorted),
+	}
+}
+
+func (r *RankIndex) Len() int {
+	if r == nil {
+		return 0
+	}
+	return r.size
+}
+
+// Count returns the number of items in the result that have a rank
+func (r *RankIndex) Count(items *roaring.Bitmap) int {
+	if r == nil || items == nil {
+		return 0
+	}
+	count := 0
+	for _, b := range r.buckets {
+		count += int(b.ids.AndCardinality(items))
+	}
+	return count
+}
+
+// Without returns the items of the result that have no rank
+func (r *RankIndex) Without(items *roaring.Bitmap) *roaring.Bitmap {
+	ret := items.Clone()
+	if r == nil {
+		return ret
+	}
+	for _, b := range r.buckets {
+		ret.AndNot(b.ids)
+	}
+	return ret
+}
+
+// At returns the item at the rank
+func (r *RankIndex) At(i int) (types.Lookup, bool) {
+	if r == nil || i < 0 {
+		return types.Lookup{}, false
+	}
+	for _, b := range r.buckets {
+		if i < len(b.sorted) {
+			return b.sorted[i], true
+		}
+		i -= len(b.sorted)
+	}
+	return types.Lookup{}, false
+}
+
+// All yields the items in rank order
+func (r *RankIndex) All() iter.Seq[types.Lookup] {
+	return func(yield func(types.Lookup) bool) {
+		if r == nil {
+			return
+		}
+		for _, b := range r.buckets {
+			for _, v := range b.sorted {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Iterate yields the items of the result in rank order, or reversed, after
+// skipping the first start matching items
+func (r *RankIndex) Iterate(items *roaring.Bitmap, start int, reverse bool) iter.Seq[uint32] {
+	return func(yield func(uint32) bool) {
+		if r == nil || items == nil || items.IsEmpty() {
+			return
+		}
+		skip := start
+		n := len(r.buckets)
+		for i := range n {
+			k := i
+			if reverse {
+				k = n - 1 - i
+			}
+			count := int(r.buckets[k].ids.AndCardinality(items))
+			if count == 0 {
+				continue
+			}
+			if skip >= count {
+				skip -= count
+				continue
+			}
+			segment := r.buckets[k].sorted
+			for j := range segment {
+				idx := j
+				if reverse {
+					idx = len(segment) - 1 - j
+				}
+				id := segment[idx].Id
+				if !items.Contains(id) {
+					continue
+				}
+				count--
+				if skip > 0 {
+					skip--
+				} else if !yield(id) {
+					return
+				}
+				if count == 0 {
+					break
+				}
+			}
+		}
+	}
+}
+
+// rankBuilder keeps a rank index up to date with single item changes. The
+// index and buckets of older generations may be read by others and are
+// copied before they are changed, so a published index never changes
+type rankBuilder struct {
+	current *RankIndex
+	compare func(a, b types.Lookup) int
+	gen     uint64
+	changed bool
+}
+
+func newRankBuilder(sorted types.ByValue, compare func(a, b types.Lookup) int) rankBuilder {
+	return rankBuilder{
+		current: NewRankIndex(sorted),
+		compare: compare,
+		gen:     1,
+	}
+}
+
+// publish returns the current index, later changes copy what they modify
+func (b *rankBuilder) publish() *RankIndex {
+	if b.changed {
+		b.changed = false
+		b.gen++
+	}
+	return b.current
+}
+
+func (b *rankBuilder) ownIndex() *RankIndex {
+	if b.current.gen != b.gen {
+		b.current = &RankIndex{
+			buckets: slices.Clone(b.current.buckets),
+			size:    b.current.size,
+			gen:     b.gen,
+		}
+	}
+	b.changed = true
+	return b.current
+}
+
+func (b *rankBuilder) ownBucket(r *RankIndex, k int) *rankBucket {
+	bucket := r.buckets[k]
+	if bucket.gen != b.gen {
+		bucket = &rankBucket{
+			sorted: slices.Clone(bucket.sorted),
+			ids:    bucket.ids.Clone(),
+			gen:    b.gen,
+		}
+		r.buckets[k] = bucket
+	}
+	return bucket
+}
+
+// bucketFor returns the first bucket that doesn't end before the item
+func (b *rankBuilder) bucketFor(item types.Lookup) int {
+	buckets := b.current.buckets
+	return sort.Search(len(buckets), func(i int) bool {
+		last := buckets[i].sorted[len(buckets[i].sorted)-1]
+		return b.compare(last, item) >= 0
+	})
+}
+
+func (b *rankBuilder) insert(item types.Lookup) {
+	k := b.bucketFor(item)
+	r := b.ownIndex()
+	r.size++
+	if len(r.buckets) == 0 {
+		r.buckets = append(r.buckets, newRankBucket(types.ByValue{item}, b.gen))
+		return
+	}
+	k = min(k, len(r.buckets)-1)
+	bucket := b.ownBucket(r, k)
+	pos, _ := slices.BinarySearchFunc(bucket.sorted, item, b.compare)
+	bucket.sorted = slices.Insert(bucket.sorted, pos, item)
+	bucket.ids.Add(item.Id)
+	if len(bucket.sorted) > 2*RankBucketSize {
+		half := len(bucket.sorted) / 2
+		first := newRankBucket(slices.Clone(bucket.sorted[:half]), b.gen)
+		second := newRankBucket(slices.Clone(bucket.sorted[half:]), b.gen)
+		r.buckets = slices.Replace(r.buckets, k, k+1, first, second)
+	}
+}
+
+// remove takes the item out of the index, false when it has no rank
+func (b *rankBuilder) remove(item types.Lookup) bool {
+	k := b.bucketFor(item)
+	if k == len(b.current.buckets) {
+		return false
+	}
+	pos, found := slices.BinarySearchFunc(b.current.buckets[k].sorted, item, b.compare)
+	if !found {
+		return false
+	}
+	r := b.ownIndex()
+	r.size--
+	bucket := b.ownBucket(r, k)
+	bucket.sorted = slices.Delete(bucket.sorted, pos, pos+1)
+	bucket.ids.Remove(item.Id)
+	switch {
+	case len(bucket.sorted) == 0:
+		r.buckets = slices.Delete(r.buckets, k, k+1)
+	case len(bucket.sorted) < RankBucketSize/4 && k+1 < len(r.buckets) && len(bucket.sorted)+len(r.buckets[k+1].sorted) <= 2*RankBucketSize:
+		// small buckets are joined with the next to keep the bucket count down
+		joined := newRankBucket(slices.Concat(bucket.sorted, r.buckets[k+1].sorted), b.gen)
+		r.buckets = slices.Replace(r.buckets, k, k+2, joined)
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package sorting
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+/*
+Sorted retrieval benchmarks, the plain scan tests every item of the sort
+against the result while the rank index only scans the buckets needed for the
+page. Sparse results deep into the pages are where the scan hurts the most.
+*/
+
+const (
+	rankBenchmarkItems = 500_000
+	rankBenchmarkPage  = 40
+)
+
+func rankBenchmarkResult(every int) *roaring.Bitmap {
+	items := roaring.New()
+	for i := 1; i <= rankBenchmarkItems; i += every {
+		items.Add(uint32(i))
+	}
+	return items
+}
+
+var rankBenchmarkCases = []struct {
+	name  string
+	every int
+	page  int
+}{
+	{"sparse/page=0", 1000, 0},
+	{"sparse/page=10", 1000, 10},
+	{"dense/page=0", 2, 0},
+	{"dense/page=50", 2, 50},
+	{"dense/page=1000", 2, 1000},
+}
+
+func BenchmarkSortedScan(b *testing.B) {
+	sorted := rankedLookups(rankBenchmarkItems)
+	for _, c := range rankBenchmarkCases {
+		b.Run(c.name, func(b *testing.B) {
+			items := rankBenchmarkResult(c.every)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = scanSorted(sorted, items, c.page*rankBenchmarkPage, rankBenchmarkPage)
+			}
+		})
+	}
+}
+
+func BenchmarkRankIndexIterate(b *testing.B) {
+	ranks := NewRankIndex(rankedLookups(rankBenchmarkItems))
+	for _, c := range rankBenchmarkCases {
+		b.Run(c.name, func(b *testing.B) {
+			items := rankBenchmarkResult(c.every)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = takeRanked(ranks, items, c.page*rankBenchmarkPage, false, rankBenchmarkPage)
+			}
+		})
+	}
+}
+
+func BenchmarkNewRankIndex(b *testing.B) {
+	for _, size := range []int{10_000, 100_000, rankBenchmarkItems} {
+		b.Run(fmt.Sprintf("N=%d", size), func(b *testing.B) {
+			sorted := rankedLookups(size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = NewRankIndex(sorted)
+			}
+		})
+	}
+}
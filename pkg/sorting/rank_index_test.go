@@ -0,0 +1,141 @@
+package sorting
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func rankedLookups(n int) types.ByValue {
+	ret := make(types.ByValue, 0, n)
+	for i := range n {
+		ret = append(ret, types.Lookup{Id: uint32(i*7%n + 1), Value: float64(n - i)})
+	}
+	return ret
+}
+
+// scanSorted is the plain scan of the whole sort the rank index replaces
+func scanSorted(sorted types.ByValue, items *roaring.Bitmap, start int, limit int) []uint32 {
+	ret := make([]uint32, 0, limit)
+	c := 0
+	for _, v := range sorted {
+		if !items.Contains(v.Id) {
+			continue
+		}
+		if c < start {
+			c++
+			continue
+		}
+		ret = append(ret, v.Id)
+		if len(ret) == limit {
+			break
+		}
+	}
+	return ret
+}
+
+func takeRanked(ranks *RankIndex, items *roaring.Bitmap, start int, reverse bool, limit int) []uint32 {
+	ret := make([]uint32, 0, limit)
+	for id := range ranks.Iterate(items, start, reverse) {
+		ret = append(ret, id)
+		if len(ret) == limit {
+			break
+		}
+	}
+	return ret
+}
+
+func TestRankIndexIterate(t *testing.T) {
+	sorted := rankedLookups(5000)
+	reversed := slices.Clone(sorted)
+	slices.Reverse(reversed)
+	ranks := NewRankIndex(sorted)
+	rnd := rand.New(rand.NewSource(1))
+	for _, density := range []float64{0.001, 0.05, 0.5, 1} {
+		items := roaring.New()
+		for i := 1; i <= 5000; i++ {
+			if rnd.Float64() < density {
+				items.Add(uint32(i))
+			}
+		}
+		// ids outside of the sort are never yielded
+		items.Add(9999)
+		for _, start := range []int{0, 3, 1023, 1024, 2500, 6000} {
+			if got, want := takeRanked(ranks, items, start, false, 25), scanSorted(sorted, items, start, 25); !slices.Equal(got, want) {
+				t.Errorf("density %v start %d: expected %v got %v", density, start, want, got)
+			}
+			if got, want := takeRanked(ranks, items, start, true, 25), scanSorted(reversed, items, start, 25); !slices.Equal(got, want) {
+				t.Errorf("reversed density %v start %d: expected %v got %v", density, start, want, got)
+			}
+		}
+		if got := ranks.Count(items); got != int(items.GetCardinality())-1 {
+			t.Errorf("Expected count %d got %d", items.GetCardinality()-1, got)
+		}
+	}
+	if got := takeRanked(ranks, nil, 0, false, 10); len(got) != 0 {
+		t.Errorf("Expected no items for a missing result got %v", got)
+	}
+}
+
+func sortedState(current map[uint32]float64, compare func(a, b types.Lookup) int) types.ByValue {
+	ret := make(types.ByValue, 0, len(current))
+	for id, v := range current {
+		ret = append(ret, types.Lookup{Id: id, Value: v})
+	}
+	slices.SortFunc(ret, compare)
+	return ret
+}
+
+func TestSortStateUpdates(t *testing.T) {
+	compare := LookupSortFunc(false)
+	rnd := rand.New(rand.NewSource(3))
+	current := map[uint32]float64{}
+	for i := 1; i <= 3000; i++ {
+		current[uint32(i)] = float64(rnd.Intn(100))
+	}
+	initial := sortedState(current, compare)
+	state := newSortState(initial, compare)
+	snapshot := state.snapshot()
+	for round := range 4 {
+		for range 3000 {
+			id := uint32(rnd.Intn(6000) + 1)
+			if rnd.Intn(3) == 0 {
+				state.remove(id)
+				delete(current, id)
+				continue
+			}
+			v := float64(rnd.Intn(100))
+			state.set(types.Lookup{Id: id, Value: v})
+			current[id] = v
+		}
+		want := sortedState(current, compare)
+		next := state.snapshot()
+		if got := next.Values(); !slices.Equal(got, want) {
+			t.Fatalf("round %d: expected the state to match the sorted items", round)
+		}
+		if next.Len() != len(want) {
+			t.Errorf("round %d: expected %d items got %d", round, len(want), next.Len())
+		}
+		items := roaring.New()
+		for id := uint32(1); id <= 6000; id += 3 {
+			items.Add(id)
+		}
+		for _, start := range []int{0, 100, 1500} {
+			if got, want := takeRanked(next.ranks, items, start, false, 25), scanSorted(want, items, start, 25); !slices.Equal(got, want) {
+				t.Errorf("round %d start %d: expected %v got %v", round, start, want, got)
+			}
+		}
+		for id, v := range current {
+			if got := next.Value(id); got != v {
+				t.Fatalf("Expected value %v for %d got %v", v, id, got)
+			}
+		}
+		if got := snapshot.Values(); !slices.Equal(got, initial) {
+			t.Fatalf("round %d: expected the first snapshot to keep its order", round)
+		}
+		snapshot, initial = next, want
+	}
+}
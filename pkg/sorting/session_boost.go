@@ -0,0 +1,330 @@
+package sorting
+
+import (
+	"container/list"
+	"fmt"
+	"iter"
+	"log"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+const (
+	// SessionTTL is how long a session boost is kept without updates
+	SessionTTL = 30 * time.Minute
+	// MaxSessions limits the sessions kept, the least recently used is evicted
+	MaxSessions = 50_000
+)
+
+// ParseSessionKey reads the session id of sort overrides keyed session-<id>
+func ParseSessionKey(key string) (int, bool) {
+	idString, ok := strings.CutPrefix(key, "session-")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(idString)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// sessionValues indexes the boostable facet values of the items, values are
+// interned as facetId:value keys
+type sessionValues struct {
+	mu     sync.RWMutex
+	keys   map[string]uint32
+	items  map[uint32]*roaring.Bitmap
+	values map[uint32][]uint32
+}
+
+func newSessionValues() *sessionValues {
+	return &sessionValues{
+		keys:   make(map[string]uint32),
+		items:  make(map[uint32]*roaring.Bitmap),
+		values: make(map[uint32][]uint32),
+	}
+}
+
+func (v *sessionValues) key(id types.FacetId, value string) uint32 {
+	name := fmt.Sprintf("%d:%s", id, value)
+	if k, ok := v.keys[name]; ok {
+		return k
+	}
+	k := uint32(len(v.keys) + 1)
+	v.keys[name] = k
+	return k
+}
+
+// update replaces the indexed values of the item
+func (v *sessionValues) update(item types.Item, facetIds []types.FacetId) {
+	if v == nil {
+		return
+	}
+	id := uint32(item.GetId())
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, k := range v.values[id] {
+		if bm, ok := v.items[k]; ok {
+			bm.Remove(id)
+		}
+	}
+	delete(v.values, id)
+	if item.IsDeleted() {
+		return
+	}
+	keys := make([]uint32, 0, len(facetIds))
+	for _, facetId := range facetIds {
+		values, ok := item.GetStringsFieldValue(facetId)
+		if !ok {
+			continue
+		}
+		for _, value := range values {
+			if value == "" {
+				continue
+			}
+			k := v.key(facetId, value)
+			if slices.Contains(keys, k) {
+				continue
+			}
+			keys = append(keys, k)
+			bm, ok := v.items[k]
+			if !ok {
+				bm = roaring.New()
+				v.items[k] = bm
+			}
+			bm.Add(id)
+		}
+	}
+	if len(keys) > 0 {
+		v.values[id] = keys
+	}
+}
+
+// rebuild indexes the values of all items for the facets, the keys are kept
+// so the profiles of the sessions stay valid for the facets still boosted
+func (v *sessionValues) rebuild(items iter.Seq[types.Item], facetIds []types.FacetId) {
+	v.mu.RLock()
+	next := newSessionValues()
+	next.keys = maps.Clone(v.keys)
+	v.mu.RUnlock()
+	if len(facetIds) > 0 {
+		for item := range items {
+			next.update(item, facetIds)
+		}
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.keys, v.items, v.values = next.keys, next.items, next.values
+}
+
+// profile sums the interactions of the items per facet value
+func (v *sessionValues) profile(interactions map[uint32]float64) map[uint32]float64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	ret := make(map[uint32]float64)
+	for id, weight := range interactions {
+		for _, k := range v.values[id] {
+			ret[k] += weight
+		}
+	}
+	return ret
+}
+
+// boosted returns the items of the result sharing a value with the profile
+// and their boost
+func (v *sessionValues) boosted(profile map[uint32]float64, items *roaring.Bitmap) map[uint32]float64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	matching := roaring.New()
+	for k := range profile {
+		if bm, ok := v.items[k]; ok {
+			matching.Or(bm)
+		}
+	}
+	matching.And(items)
+	ret := make(map[uint32]float64, matching.GetCardinality())
+	it := matching.Iterator()
+	for it.HasNext() {
+		id := it.Next()
+		for _, k := range v.values[id] {
+			ret[id] += profile[k]
+		}
+	}
+	return ret
+}
+
+type sessionEntry struct {
+	id      int
+	profile map[uint32]float64
+	expires time.Time
+}
+
+// sessionStore keeps the boost profiles of the most recent sessions
+type sessionStore struct {
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[int]*list.Element
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{
+		lru:     list.New(),
+		entries: make(map[int]*list.Element),
+	}
+}
+
+func (s *sessionStore) set(id int, profile map[uint32]float64, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[id]; ok {
+		s.lru.Remove(el)
+		delete(s.entries, id)
+	}
+	if len(profile) == 0 {
+		return
+	}
+	s.entries[id] = s.lru.PushFront(&sessionEntry{
+		id:      id,
+		profile: profile,
+		expires: now.Add(SessionTTL),
+	})
+	for s.lru.Len() > MaxSessions {
+		s.remove(s.lru.Back())
+	}
+}
+
+func (s *sessionStore) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.entries, el.Value.(*sessionEntry).id)
+}
+
+func (s *sessionStore) get(id int, now time.Time) (map[uint32]float64, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[id]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*sessionEntry)
+	if now.After(entry.expires) {
+		s.remove(el)
+		return nil, false
+	}
+	s.lru.MoveToFront(el)
+	return entry.profile, true
+}
+
+// expire removes the sessions past their ttl, the oldest are at the back
+func (s *sessionStore) expire(now time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for el := s.lru.Back(); el != nil; el = s.lru.Back() {
+		if !now.After(el.Value.(*sessionEntry).expires) {
+			return
+		}
+		s.remove(el)
+	}
+}
+
+func (s *sessionStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// SetSessionBoost indexes the facet values of the items again when the
+// session boost settings change, the boost is off without facets
+func (h *SortingItemHandler) SetSessionBoost(boost *types.SessionBoost, items iter.Seq[types.Item]) {
+	var facetIds []types.FacetId
+	if boost != nil {
+		facetIds = boost.FacetIds
+	}
+	h.sessionValues.rebuild(items, facetIds)
+	log.Printf("Indexed session boost values for facets %v", facetIds)
+}
+
+// HandleSessionOverride replaces the interactions of a session, the data is
+// the interaction weight per item id. The writers publish them as
+// session-<id> overrides from the tracking events
+func (h *SortingItemHandler) HandleSessionOverride(sessionId int, interactions map[uint32]float64) {
+	h.sessions.set(sessionId, h.sessionValues.profile(interactions), time.Now())
+}
+
+// getSessionIterator yields the popular order, or the variant's, with the items sharing a facet
+// value with the session interactions lifted by their boost. Only the boosted
+// items of the result are sorted, the rest keep the precalculated order
+func (h *SortingItemHandler) getSessionIterator(sort string, profile map[uint32]float64, weight float64, items *types.ItemList, start int) (iter.Seq[types.ItemId], bool) {
+	popular, ok := h.getSnapshot(sort)
+	if !ok || popular.Len() == 0 {
+		return nil, false
+	}
+	return func(yield func(types.ItemId) bool) {
+		if items.IsEmpty() {
+			return
+		}
+		bm := items.Bitmap()
+		boosts := h.sessionValues.boosted(profile, bm)
+		boosted := make(types.ByValue, 0, len(boosts))
+		for id, boost := range boosts {
+			if value := popular.Value(id); !math.IsNaN(value) {
+				boosted = append(boosted, types.Lookup{Id: id, Value: value + boost*weight})
+			}
+		}
+		if len(boosted) == 0 {
+			for id := range popular.Iterate(bm, start, false) {
+				if !yield(types.ItemId(id)) {
+					return
+				}
+			}
+			return
+		}
+		compare := LookupSortFunc(false)
+		slices.SortFunc(boosted, compare)
+
+		// boosted items missing from the popular order stay in the rest
+		rest := bm.Clone()
+		for _, b := range boosted {
+			rest.Remove(b.Id)
+		}
+		c := 0
+		emit := func(id uint32) bool {
+			if c < start {
+				c++
+				return true
+			}
+			return yield(types.ItemId(id))
+		}
+		i := 0
+		for id := range popular.Iterate(rest, 0, false) {
+			next := types.Lookup{Id: id, Value: popular.Value(id)}
+			for ; i < len(boosted) && compare(boosted[i], next) < 0; i++ {
+				if !emit(boosted[i].Id) {
+					return
+				}
+			}
+			if !emit(id) {
+				return
+			}
+		}
+		for ; i < len(boosted); i++ {
+			if !emit(boosted[i].Id) {
+				return
+			}
+		}
+	}, true
+}
@@ -0,0 +1,155 @@
+package sorting
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func TestParseSessionKey(t *testing.T) {
+	if id, ok := ParseSessionKey("session-42"); !ok || id != 42 {
+		t.Errorf("Expected session 42 got %d %v", id, ok)
+	}
+	for _, key := range []string{"popular", "session-", "session-abc", "session-0"} {
+		if _, ok := ParseSessionKey(key); ok {
+			t.Errorf("Expected %s not to be a session key", key)
+		}
+	}
+}
+
+func TestSessionStoreEviction(t *testing.T) {
+	s := newSessionStore()
+	now := time.Now()
+	profile := map[uint32]float64{1: 1}
+	for id := 1; id <= MaxSessions+1; id++ {
+		s.set(id, profile, now)
+	}
+	if s.len() != MaxSessions {
+		t.Errorf("Expected %d sessions got %d", MaxSessions, s.len())
+	}
+	if _, ok := s.get(1, now); ok {
+		t.Error("Expected the least recently used session to be evicted")
+	}
+	if _, ok := s.get(2, now.Add(SessionTTL+time.Second)); ok {
+		t.Error("Expected an expired session to be dropped")
+	}
+	s.expire(now.Add(SessionTTL + time.Second))
+	if s.len() != 0 {
+		t.Errorf("Expected all sessions to expire got %d", s.len())
+	}
+	s.set(3, profile, now)
+	s.set(3, nil, now)
+	if _, ok := s.get(3, now); ok {
+		t.Error("Expected an empty update to remove the session")
+	}
+}
+
+func sessionHandler() *SortingItemHandler {
+	popular := make(types.ByValue, 0)
+	for i := 6; i >= 1; i-- {
+		popular = append(popular, types.Lookup{Id: uint32(i), Value: float64(i * 10)})
+	}
+	h := &SortingItemHandler{
+		Sorters:       []Sorter{newStaticSorter("popular", popular)},
+		fieldSorters:  map[string]*FieldSorter{},
+		sessions:      newSessionStore(),
+		sessionValues: newSessionValues(),
+	}
+	brands := []string{"a", "a", "b", "b", "c", "c;a"}
+	for i, brand := range brands {
+		item := newMockItem(i+1, 0)
+		item.stringMap = map[types.FacetId]string{10: brand}
+		h.handleItemUnsafe(item)
+	}
+	return h
+}
+
+func TestSessionPersonalizedSort(t *testing.T) {
+	previous := types.CurrentSettings.GetSessionBoost()
+	types.CurrentSettings.Lock()
+	types.CurrentSettings.SessionBoost = &types.SessionBoost{FacetIds: []types.FacetId{10}, Weight: 25}
+	types.CurrentSettings.Unlock()
+	defer func() {
+		types.CurrentSettings.Lock()
+		types.CurrentSettings.SessionBoost = previous
+		types.CurrentSettings.Unlock()
+	}()
+
+	h := sessionHandler()
+	h.HandleSortOverrideUpdate(types.SortOverrideUpdate{Key: "session-7", Data: map[uint32]float64{1: 1}})
+	items := types.NewItemList()
+	for i := uint32(1); i <= 6; i++ {
+		items.AddId(i)
+	}
+
+	// brand a is boosted by 25: 6 (85), 2 (45), 1 (35) and the rest unchanged
+	got := takeIds(h.GetSortedItemsIterator(7, "popular", items, 0), 10)
+	if !slices.Equal(got, []types.ItemId{6, 5, 2, 4, 1, 3}) {
+		t.Errorf("Expected personalized order got %v", got)
+	}
+	got = takeIds(h.GetSortedItemsIterator(7, "popular", items, 2), 2)
+	if !slices.Equal(got, []types.ItemId{2, 4}) {
+		t.Errorf("Expected personalized page got %v", got)
+	}
+	plain := []types.ItemId{6, 5, 4, 3, 2, 1}
+	if got = takeIds(h.GetSortedItemsIterator(0, "popular", items, 0), 10); !slices.Equal(got, plain) {
+		t.Errorf("Expected session 0 to skip personalization got %v", got)
+	}
+	if got = takeIds(h.GetSortedItemsIterator(8, "popular", items, 0), 10); !slices.Equal(got, plain) {
+		t.Errorf("Expected unknown sessions to use popular got %v", got)
+	}
+
+	// items changing brand move with the index
+	moved := newMockItem(3, 0)
+	moved.stringMap = map[types.FacetId]string{10: "a"}
+	h.handleItemUnsafe(moved)
+	subset := types.NewItemList()
+	for _, id := range []uint32{1, 3, 4} {
+		subset.AddId(id)
+	}
+	got = takeIds(h.GetSortedItemsIterator(7, "popular", subset, 0), 10)
+	if !slices.Equal(got, []types.ItemId{3, 4, 1}) {
+		t.Errorf("Expected boosted subset got %v", got)
+	}
+}
+
+func TestSetSessionBoostIndexesItems(t *testing.T) {
+	previous := types.CurrentSettings.GetSessionBoost()
+	types.CurrentSettings.Lock()
+	types.CurrentSettings.SessionBoost = nil
+	types.CurrentSettings.Unlock()
+	defer func() {
+		types.CurrentSettings.Lock()
+		types.CurrentSettings.SessionBoost = previous
+		types.CurrentSettings.Unlock()
+	}()
+
+	// the items are handled before the boost is enabled
+	h := sessionHandler()
+	items := types.NewItemList()
+	catalog := make([]types.Item, 0, 6)
+	brands := []string{"a", "a", "b", "b", "c", "c;a"}
+	for i, brand := range brands {
+		item := newMockItem(i+1, 0)
+		item.stringMap = map[types.FacetId]string{10: brand}
+		catalog = append(catalog, item)
+		items.AddId(uint32(i + 1))
+	}
+
+	boost := &types.SessionBoost{FacetIds: []types.FacetId{10}, Weight: 25}
+	types.CurrentSettings.Lock()
+	types.CurrentSettings.SessionBoost = boost
+	types.CurrentSettings.Unlock()
+	h.SetSessionBoost(boost, slices.Values(catalog))
+	h.HandleSortOverrideUpdate(types.SortOverrideUpdate{Key: "session-7", Data: map[uint32]float64{1: 1}})
+	if got := takeIds(h.GetSortedItemsIterator(7, "popular", items, 0), 10); !slices.Equal(got, []types.ItemId{6, 5, 2, 4, 1, 3}) {
+		t.Errorf("Expected personalized order after enabling the boost got %v", got)
+	}
+
+	h.SetSessionBoost(&types.SessionBoost{FacetIds: []types.FacetId{11}, Weight: 25}, slices.Values(catalog))
+	if got := takeIds(h.GetSortedItemsIterator(7, "popular", items, 0), 10); !slices.Equal(got, []types.ItemId{6, 5, 4, 3, 2, 1}) {
+		t.Errorf("Expected no boost for facets without values got %v", got)
+	}
+}
@@ -0,0 +1,142 @@
+package sorting
+
+import (
+	"cmp"
+	"iter"
+	"math"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func compareIds(a, b types.Lookup) int {
+	return cmp.Compare(a.Id, b.Id)
+}
+
+// sortState keeps the items in sort order with a rank index and the value of
+// each item in a persistent tree by id. Changes only copy the parts they
+// touch after a snapshot, so the snapshots stay consistent without copying
+// the whole order. Owners serialize the access
+type sortState struct {
+	ranks  rankBuilder
+	values *orderNode
+}
+
+// newSortState starts from items already in sort order, both the ranks and
+// the value tree are built in linear time instead of inserting each item
+func newSortState(sorted types.ByValue, compare func(a, b types.Lookup) int) *sortState {
+	values := make(map[uint32]float64, len(sorted))
+	ids := roaring.NewBitmap()
+	for _, v := range sorted {
+		values[v.Id] = v.Value
+		ids.Add(v.Id)
+	}
+	byId := make(types.ByValue, 0, len(values))
+	it := ids.Iterator()
+	for it.HasNext() {
+		id := it.Next()
+		byId = append(byId, types.Lookup{Id: id, Value: values[id]})
+	}
+	return &sortState{
+		ranks:  newRankBuilder(sorted, compare),
+		values: buildOrder(byId),
+	}
+}
+
+func (s *sortState) get(id uint32) (float64, bool) {
+	v, ok := findOrder(s.values, types.Lookup{Id: id}, compareIds)
+	return v.Value, ok
+}
+
+// set moves the item to the position of the value, false when unchanged
+func (s *sortState) set(item types.Lookup) bool {
+	if current, ok := findOrder(s.values, item, compareIds); ok {
+		if current.Value == item.Value {
+			return false
+		}
+		s.ranks.remove(current)
+		s.values = removeOrder(s.values, current, compareIds)
+	}
+	s.ranks.insert(item)
+	s.values = insertOrder(s.values, item, compareIds)
+	return true
+}
+
+// remove takes the item out of the sort, false when it wasn't sorted
+func (s *sortState) remove(id uint32) bool {
+	current, ok := findOrder(s.values, types.Lookup{Id: id}, compareIds)
+	if !ok {
+		return false
+	}
+	s.ranks.remove(current)
+	s.values = removeOrder(s.values, current, compareIds)
+	return true
+}
+
+func (s *sortState) snapshot() SortSnapshot {
+	return SortSnapshot{ranks: s.ranks.publish(), values: s.values}
+}
+
+// SortSnapshot is an immutable view of a sorter at one point in time
+type SortSnapshot struct {
+	ranks  *RankIndex
+	values *orderNode
+}
+
+func (s SortSnapshot) Len() int {
+	return s.ranks.Len()
+}
+
+// At returns the item at the position in the sort order
+func (s SortSnapshot) At(i int) (types.Lookup, bool) {
+	return s.ranks.At(i)
+}
+
+func (s SortSnapshot) All() iter.Seq[types.Lookup] {
+	return s.ranks.All()
+}
+
+// Values returns the items in sort order
+func (s SortSnapshot) Values() types.ByValue {
+	ret := make(types.ByValue, 0, s.Len())
+	for v := range s.All() {
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+// Value returns the sort value of the item, NaN when it isn't sorted
+func (s SortSnapshot) Value(id uint32) float64 {
+	if v, ok := findOrder(s.values, types.Lookup{Id: id}, compareIds); ok {
+		return v.Value
+	}
+	return math.NaN()
+}
+
+func (s SortSnapshot) Has(id uint32) bool {
+	_, ok := findOrder(s.values, types.Lookup{Id: id}, compareIds)
+	return ok
+}
+
+// isAscending tells the direction of the sort
+func (s SortSnapshot) isAscending() bool {
+	first, _ := s.At(0)
+	last, _ := s.At(s.Len() - 1)
+	return first.Value <= last.Value
+}
+
+// Iterate yields the items of the result in sort order, or reversed, after
+// skipping the first start matching items
+func (s SortSnapshot) Iterate(items *roaring.Bitmap, start int, reverse bool) iter.Seq[uint32] {
+	return s.ranks.Iterate(items, start, reverse)
+}
+
+// Count returns the number of items in the result that are sorted
+func (s SortSnapshot) Count(items *roaring.Bitmap) int {
+	return s.ranks.Count(items)
+}
+
+// Without returns the items of the result that aren't sorted
+func (s SortSnapshot) Without(items *roaring.Bitmap) *roaring.Bitmap {
+	return s.ranks.Without(items)
+}
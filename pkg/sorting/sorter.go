@@ -1,7 +1,6 @@
 package sorting
 
 import (
-	"slices"
 	"sync"
 
 	"github.com/matst80/slask-finder/pkg/types"
@@ -10,15 +9,19 @@ import (
 type Sorter interface {
 	ProcessItem(item types.Item)
 	GetSort() types.ByValue
+	Snapshot() SortSnapshot
 	IsDirty() bool
 	Name() string
 	HandleOverride(types.SortOverrideUpdate)
 }
 
+// BaseSorter keeps the items ordered as they are processed, each change only
+// updates the positions of the changed items and readers use snapshots
 type BaseSorter struct {
-	mu          sync.RWMutex
+	mu          sync.Mutex
 	override    types.SortOverride
 	scores      map[types.ItemId]float64
+	state       *sortState
 	isReversed  bool
 	name        string
 	overrideKey string
@@ -27,23 +30,15 @@ type BaseSorter struct {
 }
 
 func NewBaseSorter(name string, fn func(item types.Item) float64, isReversed bool) Sorter {
-	return &BaseSorter{
-		mu:          sync.RWMutex{},
-		override:    types.SortOverride{},
-		scores:      make(map[types.ItemId]float64),
-		isReversed:  isReversed,
-		name:        name,
-		dirty:       false,
-		fn:          fn,
-		overrideKey: name,
-	}
+	return NewBaseSorterWithCustomOverrideKey(name, fn, isReversed, name)
 }
 
 func NewBaseSorterWithCustomOverrideKey(name string, fn func(item types.Item) float64, isReversed bool, overrideKey string) Sorter {
 	return &BaseSorter{
-		mu:          sync.RWMutex{},
+		mu:          sync.Mutex{},
 		override:    types.SortOverride{},
 		scores:      make(map[types.ItemId]float64),
+		state:       newSortState(nil, LookupSortFunc(isReversed)),
 		isReversed:  isReversed,
 		name:        name,
 		dirty:       false,
@@ -52,16 +47,41 @@ func NewBaseSorterWithCustomOverrideKey(name string, fn func(item types.Item) fl
 	}
 }
 
+func (s *BaseSorter) lookup(id types.ItemId, score float64) types.Lookup {
+	return types.Lookup{Id: uint32(id), Value: score + s.override[uint32(id)]}
+}
+
+// HandleOverride moves the items with a changed override
 func (s *BaseSorter) HandleOverride(update types.SortOverrideUpdate) {
-	if update.Key == s.overrideKey {
-		s.mu.Lock()
-		defer s.mu.Unlock()
-		s.override = update.Data
-		s.dirty = true
+	if update.Key != s.overrideKey {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.override
+	s.override = update.Data
+	move := func(id uint32) {
+		score, ok := s.scores[types.ItemId(id)]
+		if !ok || previous[id] == s.override[id] {
+			return
+		}
+		if s.state.set(s.lookup(types.ItemId(id), score)) {
+			s.dirty = true
+		}
+	}
+	for id := range previous {
+		move(id)
+	}
+	for id := range s.override {
+		if _, seen := previous[id]; !seen {
+			move(id)
+		}
 	}
 }
 
 func (s *BaseSorter) IsDirty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.dirty
 }
 
@@ -73,37 +93,38 @@ func (s *BaseSorter) ProcessItem(item types.Item) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	id := item.GetId()
+	current, exists := s.scores[id]
 	if item.IsDeleted() {
-		delete(s.scores, id)
+		if exists {
+			s.state.remove(uint32(id))
+			delete(s.scores, id)
+			s.dirty = true
+		}
 		return
 	}
 	newscore := s.fn(item)
-	if current, ok := s.scores[id]; ok && current == newscore {
+	if exists && current == newscore {
 		return
 	}
-	s.scores[id] = newscore // + s.override[uint32(id)]
-	s.dirty = true
-}
-
-func (s *BaseSorter) GetSort() types.ByValue {
-	// Acquire read lock to snapshot scores & overrides quickly.
-	s.mu.RLock()
-	l := len(s.scores)
-	sortMap := make(types.ByValue, 0, l)
-	for id, score := range s.scores {
-		ov := s.override[uint32(id)]
-		sortMap = append(sortMap, types.Lookup{Id: uint32(id), Value: score + ov})
+	s.scores[id] = newscore
+	if s.state.set(s.lookup(id, newscore)) {
+		s.dirty = true
 	}
-	isReversed := s.isReversed
-	s.mu.RUnlock() // release before the (potentially) more expensive sort
+}
 
-	// Use shared comparator helper: descending by default, ascending if isReversed.
-	slices.SortFunc(sortMap, LookupSortFunc(isReversed))
+// Snapshot returns the current order, later changes don't affect it
+func (s *BaseSorter) Snapshot() SortSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.snapshot()
+}
 
-	// Mark clean under write lock.
+// GetSort returns the items in order, descending by default and ascending
+// when reversed, ties are ordered by id
+func (s *BaseSorter) GetSort() types.ByValue {
 	s.mu.Lock()
+	snapshot := s.state.snapshot()
 	s.dirty = false
 	s.mu.Unlock()
-
-	return sortMap
+	return snapshot.Values()
 }
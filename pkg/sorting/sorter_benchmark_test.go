@@ -3,6 +3,7 @@ package sorting
 import (
 	"fmt"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,7 +13,7 @@ import (
 /*
 Benchmark goals:
 
-1. Measure the cost of GetSort (building + sorting the slice) for various collection sizes.
+1. Measure the cost of GetSort (copying the maintained order to a slice) for various collection sizes.
 2. Cover both descending (default) and ascending (isReversed=true) variants.
 3. Provide a realistic scoring function (price-based) while keeping mock item lightweight.
 
@@ -50,35 +51,44 @@ func newMockItem(id int, price int) *mockItem {
 	}
 }
 
-func (m *mockItem) GetId() types.ItemId                        { return m.id }
-func (m *mockItem) GetSku() string                             { return m.sku }
-func (m *mockItem) GetStock() map[string]uint32                { return nil }
-func (m *mockItem) HasStock() bool                             { return true }
-func (m *mockItem) IsDeleted() bool                            { return m.deleted }
-func (m *mockItem) IsSoftDeleted() bool                        { return false }
-func (m *mockItem) GetPropertyValue(name string) any           { return nil }
-func (m *mockItem) GetPrice() int                              { return m.price }
-func (m *mockItem) GetDiscount() int                           { return 0 }
-func (m *mockItem) GetRating() (int, int)                      { return 0, 0 }
-func (m *mockItem) GetStringFields() map[types.FacetId]string  { return m.stringMap }
-func (m *mockItem) GetNumberFields() map[types.FacetId]float64 { return m.numberMap }
+func (m *mockItem) GetId() types.ItemId                                  { return m.id }
+func (m *mockItem) GetSku() string                                       { return m.sku }
+func (m *mockItem) GetStock() map[string]uint16                          { return nil }
+func (m *mockItem) UpdateStock(locationId string, quantity uint16) error { return nil }
+func (m *mockItem) HasStock() bool                                       { return true }
+func (m *mockItem) IsDeleted() bool                                      { return m.deleted }
+func (m *mockItem) IsSoftDeleted() bool                                  { return false }
+func (m *mockItem) GetPropertyValue(name string) any                     { return nil }
+func (m *mockItem) GetPrice() int                                        { return m.price }
+func (m *mockItem) GetDiscount() int                                     { return 0 }
+func (m *mockItem) GetRating() (int, int)                                { return 0, 0 }
+func (m *mockItem) GetStringFields() map[types.FacetId]string            { return m.stringMap }
+func (m *mockItem) GetNumberFields() map[types.FacetId]float64           { return m.numberMap }
 func (m *mockItem) GetStringFieldValue(id types.FacetId) (string, bool) {
 	v, ok := m.stringMap[id]
 	return v, ok
 }
-func (m *mockItem) GetStringsFieldValue(id types.FacetId) ([]string, bool) { return nil, false }
+func (m *mockItem) GetStringsFieldValue(id types.FacetId) ([]string, bool) {
+	v, ok := m.stringMap[id]
+	if !ok {
+		return nil, false
+	}
+	return strings.Split(v, ";"), true
+}
 func (m *mockItem) GetNumberFieldValue(id types.FacetId) (float64, bool) {
 	v, ok := m.numberMap[id]
 	return v, ok
 }
-func (m *mockItem) GetLastUpdated() int64              { return m.updated }
-func (m *mockItem) GetCreated() int64                  { return m.created }
-func (m *mockItem) GetTitle() string                   { return m.title }
-func (m *mockItem) ToString() string                   { return m.title }
-func (m *mockItem) ToStringList() []string             { return []string{m.title} }
-func (m *mockItem) CanHaveEmbeddings() bool            { return false }
-func (m *mockItem) GetEmbeddingsText() (string, error) { return "", nil }
-func (m *mockItem) Write(w io.Writer) (int, error)     { return w.Write([]byte(m.title)) }
+func (m *mockItem) GetNumberListFields() map[types.FacetId][]float64        { return nil }
+func (m *mockItem) GetNumbersFieldValue(id types.FacetId) ([]float64, bool) { return nil, false }
+func (m *mockItem) GetLastUpdated() int64                                   { return m.updated }
+func (m *mockItem) GetCreated() int64                                       { return m.created }
+func (m *mockItem) GetTitle() string                                        { return m.title }
+func (m *mockItem) ToString() string                                        { return m.title }
+func (m *mockItem) ToStringList() []string                                  { return []string{m.title} }
+func (m *mockItem) CanHaveEmbeddings() bool                                 { return false }
+func (m *mockItem) GetEmbeddingsText() (string, error)                      { return "", nil }
+func (m *mockItem) Write(w io.Writer) (int, error)                          { return w.Write([]byte(m.title)) }
 
 // prepareSorter builds and primes a sorter with N mock items.
 // The scoring function just returns float64(price).
@@ -0,0 +1,100 @@
+package sorting
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+func sortIds(sort types.ByValue) []uint32 {
+	ret := make([]uint32, 0, len(sort))
+	for _, v := range sort {
+		ret = append(ret, v.Id)
+	}
+	return ret
+}
+
+// staticSorter is a sorter with a fixed order that ignores item changes
+type staticSorter struct {
+	name     string
+	snapshot SortSnapshot
+}
+
+func newStaticSorter(name string, sorted types.ByValue) *staticSorter {
+	ascending := len(sorted) > 0 && sorted[0].Value <= sorted[len(sorted)-1].Value
+	state := newSortState(sorted, LookupSortFunc(ascending))
+	return &staticSorter{name: name, snapshot: state.snapshot()}
+}
+
+func (s *staticSorter) ProcessItem(types.Item)                  {}
+func (s *staticSorter) GetSort() types.ByValue                  { return s.snapshot.Values() }
+func (s *staticSorter) Snapshot() SortSnapshot                  { return s.snapshot }
+func (s *staticSorter) IsDirty() bool                           { return false }
+func (s *staticSorter) Name() string                            { return s.name }
+func (s *staticSorter) HandleOverride(types.SortOverrideUpdate) {}
+
+func TestBaseSorterIncremental(t *testing.T) {
+	s := NewBaseSorter("popular", func(it types.Item) float64 {
+		return float64(it.GetPrice())
+	}, false)
+	for i := 1; i <= 4; i++ {
+		s.ProcessItem(newMockItem(i, i))
+	}
+	if !s.IsDirty() {
+		t.Error("Expected sorter to be dirty after new items")
+	}
+	if got := sortIds(s.GetSort()); !slices.Equal(got, []uint32{4, 3, 2, 1}) {
+		t.Errorf("Expected descending order got %v", got)
+	}
+	if s.IsDirty() {
+		t.Error("Expected sorter to be clean after GetSort")
+	}
+	s.ProcessItem(newMockItem(2, 2))
+	if s.IsDirty() {
+		t.Error("Expected unchanged score to keep the sorter clean")
+	}
+	s.ProcessItem(newMockItem(1, 10))
+	s.ProcessItem(&mockItem{id: 3, deleted: true})
+	if got := sortIds(s.GetSort()); !slices.Equal(got, []uint32{1, 4, 2}) {
+		t.Errorf("Expected moved and deleted items got %v", got)
+	}
+
+	s.HandleOverride(types.SortOverrideUpdate{Key: "other", Data: map[uint32]float64{2: 100}})
+	if s.IsDirty() {
+		t.Error("Expected overrides for other sorts to be ignored")
+	}
+	s.HandleOverride(types.SortOverrideUpdate{Key: "popular", Data: map[uint32]float64{2: 100, 7: 5}})
+	sort := s.GetSort()
+	if got := sortIds(sort); !slices.Equal(got, []uint32{2, 1, 4}) {
+		t.Errorf("Expected override to move item got %v", got)
+	}
+	if sort[0].Value != 102 {
+		t.Errorf("Expected override added to the score got %v", sort[0].Value)
+	}
+	// the override is applied to items added later and removed when replaced
+	s.ProcessItem(newMockItem(7, 1))
+	s.HandleOverride(types.SortOverrideUpdate{Key: "popular", Data: map[uint32]float64{}})
+	if got := sortIds(s.GetSort()); !slices.Equal(got, []uint32{1, 4, 2, 7}) {
+		t.Errorf("Expected cleared override order got %v", got)
+	}
+}
+
+func TestHandlerReadsItemChanges(t *testing.T) {
+	h := &SortingItemHandler{
+		Sorters:      []Sorter{NewPriceSorter()},
+		fieldSorters: map[string]*FieldSorter{},
+	}
+	items := types.NewItemList()
+	for i := 1; i <= 3; i++ {
+		items.AddId(uint32(i))
+	}
+	h.HandleItems(slices.Values([]types.Item{newMockItem(1, 30), newMockItem(2, 10), newMockItem(3, 20)}))
+	if got := takeIds(h.GetSortedItemsIterator(0, "price", items, 0), 10); !slices.Equal(got, []types.ItemId{2, 3, 1}) {
+		t.Errorf("Expected price order got %v", got)
+	}
+	h.HandleItems(slices.Values([]types.Item{newMockItem(1, 5)}))
+	if got := takeIds(h.GetSortedItemsIterator(0, "price", items, 0), 10); !slices.Equal(got, []types.ItemId{1, 2, 3}) {
+		t.Errorf("Expected the change without publishing got %v", got)
+	}
+}
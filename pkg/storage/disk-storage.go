@@ -9,6 +9,7 @@ import (
 	"iter"
 	"log"
 	"os"
+	"path"
 	"runtime"
 	"slices"
 	"strings"
@@ -112,6 +113,18 @@ func (d *DiskStorage) LoadSortOverride(name string) (*types.SortOverride, error)
 	return tmp, err
 }
 
+func (d *DiskStorage) SaveSortOverride(name string, override *types.SortOverride) error {
+	fileName := d.GetOverrideFilename(name)
+	if err := os.MkdirAll(path.Dir(fileName), 0755); err != nil {
+		return err
+	}
+	tmpFileName := fileName + ".tmp"
+	if err := os.WriteFile(tmpFileName, []byte(override.ToString()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFileName, fileName)
+}
+
 func convertCategory(item *index.DataItem) *index.DataItem {
 	cat := make([]string, 0, 5)
 	v, ok := item.GetStringFieldValue(10)
@@ -0,0 +1,251 @@
+package tracking
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matst80/slask-finder/pkg/messaging"
+	"github.com/matst80/slask-finder/pkg/types"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// MinPopularityScore drops items whose decayed score has fallen below it
+const MinPopularityScore = 0.01
+
+// EventDecay is the score an event adds to its item and the time it takes for
+// that score to halve
+type EventDecay struct {
+	Weight   float64
+	HalfLife time.Duration
+}
+
+var eventNames = map[string]uint16{
+	"click":    EventItemClick,
+	"cart":     EventAddToCart,
+	"purchase": EventPurchase,
+}
+
+// DefaultPopularityDecay makes purchases count the most and last the longest
+func DefaultPopularityDecay() map[uint16]EventDecay {
+	return map[uint16]EventDecay{
+		EventItemClick: {Weight: 1, HalfLife: 24 * time.Hour},
+		EventAddToCart: {Weight: 5, HalfLife: 3 * 24 * time.Hour},
+		EventPurchase:  {Weight: 20, HalfLife: 7 * 24 * time.Hour},
+	}
+}
+
+// ParsePopularityDecay reads event decays like click=1:24h,purchase=20:168h,
+// events not mentioned keep their default
+func ParsePopularityDecay(value string) (map[uint16]EventDecay, error) {
+	ret := DefaultPopularityDecay()
+	for part := range strings.SplitSeq(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, rest, ok := strings.Cut(part, "=")
+		event, known := eventNames[name]
+		if !ok || !known {
+			return nil, fmt.Errorf("unknown popularity event %q", part)
+		}
+		weightString, halfLifeString, ok := strings.Cut(rest, ":")
+		if !ok {
+			return nil, fmt.Errorf("expected weight:halfLife for %s", name)
+		}
+		weight, err := strconv.ParseFloat(weightString, 64)
+		if err != nil {
+			return nil, err
+		}
+		halfLife, err := time.ParseDuration(halfLifeString)
+		if err != nil {
+			return nil, err
+		}
+		if halfLife <= 0 {
+			return nil, fmt.Errorf("half life for %s must be positive", name)
+		}
+		ret[event] = EventDecay{Weight: weight, HalfLife: halfLife}
+	}
+	return ret, nil
+}
+
+type popularityKey struct {
+	item  uint32
+	event uint16
+}
+
+type decayedScore struct {
+	value   float64
+	updated time.Time
+}
+
+func (s decayedScore) at(now time.Time, halfLife time.Duration) float64 {
+	elapsed := now.Sub(s.updated)
+	if elapsed <= 0 {
+		return s.value
+	}
+	return s.value * math.Exp2(-float64(elapsed)/float64(halfLife))
+}
+
+// PopularityAggregator keeps time decayed scores per item and event type from
+// the tracking events, the sum is published as the popular sort override
+type PopularityAggregator struct {
+	mu     sync.Mutex
+	decay  map[uint16]EventDecay
+	scores map[popularityKey]decayedScore
+	// Sessions are the interactions per session for the session boost
+	Sessions *SessionInteractions
+}
+
+func NewPopularityAggregator(decay map[uint16]EventDecay) *PopularityAggregator {
+	return &PopularityAggregator{
+		decay:    decay,
+		scores:   make(map[popularityKey]decayedScore),
+		Sessions: NewSessionInteractions(decay),
+	}
+}
+
+// Add counts the event, false when the event type isn't scored
+func (p *PopularityAggregator) Add(event uint16, item uint32, at time.Time) bool {
+	d, ok := p.decay[event]
+	if !ok || item == 0 {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := popularityKey{item: item, event: event}
+	current, found := p.scores[key]
+	if !found {
+		p.scores[key] = decayedScore{value: d.Weight, updated: at}
+		return true
+	}
+	if at.Before(current.updated) {
+		// late events are decayed to the time of the stored score
+		current.value += decayedScore{value: d.Weight, updated: at}.at(current.updated, d.HalfLife)
+	} else {
+		current.value = current.at(at, d.HalfLife) + d.Weight
+		current.updated = at
+	}
+	p.scores[key] = current
+	return true
+}
+
+// Scores returns the decayed score per item and forgets the items below
+// MinPopularityScore
+func (p *PopularityAggregator) Scores(now time.Time) types.SortOverride {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ret := types.SortOverride{}
+	for key, score := range p.scores {
+		value := score.at(now, p.decay[key.event].HalfLife)
+		if math.Abs(value) < MinPopularityScore {
+			delete(p.scores, key)
+			continue
+		}
+		ret[key.item] += value
+	}
+	return ret
+}
+
+// PopularityState is a stored score of an item and event type
+type PopularityState struct {
+	Item    uint32    `json:"item"`
+	Event   uint16    `json:"event"`
+	Value   float64   `json:"value"`
+	Updated time.Time `json:"updated"`
+}
+
+// State returns the scores to store between restarts
+func (p *PopularityAggregator) State() []PopularityState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ret := make([]PopularityState, 0, len(p.scores))
+	for key, score := range p.scores {
+		ret = append(ret, PopularityState{Item: key.item, Event: key.event, Value: score.value, Updated: score.updated})
+	}
+	return ret
+}
+
+// Restore adds stored scores, event types without a decay are skipped
+func (p *PopularityAggregator) Restore(state []PopularityState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range state {
+		d, ok := p.decay[s.Event]
+		if !ok || s.Item == 0 {
+			continue
+		}
+		key := popularityKey{item: s.Item, event: s.Event}
+		restored := decayedScore{value: s.Value, updated: s.Updated}
+		if current, found := p.scores[key]; found {
+			// events counted before the restore are merged at the newest time
+			if current.updated.Before(restored.updated) {
+				current, restored = restored, current
+			}
+			current.value += restored.at(current.updated, d.HalfLife)
+			restored = current
+		}
+		p.scores[key] = restored
+	}
+}
+
+// HandleDelivery counts item events from the tracking topic, other events and
+// malformed messages are skipped
+func (p *PopularityAggregator) HandleDelivery(d amqp.Delivery) error {
+	var event Event
+	if err := json.Unmarshal(d.Body, &event); err != nil {
+		log.Printf("Failed to unmarshal tracking event: %v", err)
+		return nil
+	}
+	if event.BaseEvent == nil || event.Item > math.MaxUint32 {
+		return nil
+	}
+	at := d.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	p.Add(event.Event, uint32(event.Item), at)
+	p.Sessions.Add(event.SessionId, event.Event, uint32(event.Item), at)
+	return nil
+}
+
+// Connect consumes the tracking topic and publishes the scores as the
+// EventPopularityKey override every interval. The changed sessions are
+// published as session-<id> overrides when the session boost is enabled
+func (p *PopularityAggregator) Connect(conn *amqp.Connection, interval time.Duration, publish func(types.SortOverrideUpdate) error) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	if err = messaging.ListenToTopic(ch, "global", trackingTopic, p.HandleDelivery); err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			now := time.Now()
+			scores := p.Scores(now)
+			if err := publish(types.SortOverrideUpdate{Key: types.EventPopularityKey, Data: scores}); err != nil {
+				log.Printf("Failed to publish popularity: %v", err)
+			} else {
+				log.Printf("Published popularity for %d items", len(scores))
+			}
+			sessions := p.Sessions.Changed(now)
+			if types.CurrentSettings.GetSessionBoost() == nil {
+				continue
+			}
+			for _, session := range sessions {
+				if err := publish(session); err != nil {
+					log.Printf("Failed to publish %s: %v", session.Key, err)
+				}
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,146 @@
+package tracking
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestParsePopularityDecay(t *testing.T) {
+	decay, err := ParsePopularityDecay("click=2:1h, purchase=50:48h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decay[EventItemClick] != (EventDecay{Weight: 2, HalfLife: time.Hour}) {
+		t.Errorf("Expected click override got %v", decay[EventItemClick])
+	}
+	if decay[EventAddToCart] != DefaultPopularityDecay()[EventAddToCart] {
+		t.Errorf("Expected default cart decay got %v", decay[EventAddToCart])
+	}
+	for _, value := range []string{"view=1:1h", "click=1", "click=x:1h", "click=1:-1h"} {
+		if _, err := ParsePopularityDecay(value); err == nil {
+			t.Errorf("Expected %s to fail", value)
+		}
+	}
+}
+
+func TestPopularityAggregatorDecay(t *testing.T) {
+	p := NewPopularityAggregator(map[uint16]EventDecay{
+		EventItemClick: {Weight: 1, HalfLife: time.Hour},
+		EventPurchase:  {Weight: 10, HalfLife: 2 * time.Hour},
+	})
+	now := time.Now()
+	p.Add(EventItemClick, 1, now)
+	p.Add(EventItemClick, 1, now.Add(time.Hour))
+	p.Add(EventPurchase, 1, now)
+	p.Add(EventPurchase, 2, now.Add(-2*time.Hour))
+	if p.Add(EventSearch, 3, now) {
+		t.Error("Expected unscored events to be skipped")
+	}
+	// a late click is decayed to the time of the stored score
+	p.Add(EventItemClick, 1, now)
+
+	scores := p.Scores(now.Add(time.Hour))
+	// clicks: (1*0.5 + 1 + 0.5) at +1h, purchase: 10 at half its half life
+	if want := 2 + 10/math.Sqrt2; !near(scores[1], want) {
+		t.Errorf("Expected item 1 score %v got %v", want, scores[1])
+	}
+	if want := 10.0 / math.Pow(2, 1.5); !near(scores[2], want) {
+		t.Errorf("Expected item 2 score %v got %v", want, scores[2])
+	}
+	if _, ok := scores[3]; ok {
+		t.Error("Expected no score for skipped events")
+	}
+
+	scores = p.Scores(now.Add(40 * time.Hour))
+	if _, ok := scores[2]; ok {
+		t.Error("Expected faded items to be dropped")
+	}
+	if len(p.scores) != 0 {
+		t.Errorf("Expected faded scores to be forgotten got %d", len(p.scores))
+	}
+}
+
+func TestPopularityHandleDelivery(t *testing.T) {
+	p := NewPopularityAggregator(DefaultPopularityDecay())
+	at := time.Now()
+	for _, body := range []string{
+		`{"session_id":1,"event":4,"item":12}`,
+		`{"session_id":1,"event":1,"query":"tv"}`,
+		`not json`,
+	} {
+		if err := p.HandleDelivery(amqp.Delivery{Body: []byte(body), Timestamp: at}); err != nil {
+			t.Errorf("Expected %s to be accepted got %v", body, err)
+		}
+	}
+	scores := p.Scores(at)
+	if len(scores) != 1 || !near(scores[12], DefaultPopularityDecay()[EventAddToCart].Weight) {
+		t.Errorf("Expected only the cart event to count got %v", scores)
+	}
+}
+
+func TestPopularityRestore(t *testing.T) {
+	decay := map[uint16]EventDecay{EventItemClick: {Weight: 1, HalfLife: time.Hour}}
+	now := time.Now()
+	stored := NewPopularityAggregator(decay)
+	stored.Add(EventItemClick, 1, now)
+	stored.Add(EventItemClick, 2, now)
+	state := stored.State()
+	state = append(state, PopularityState{Item: 3, Event: EventSearch, Value: 1, Updated: now})
+
+	p := NewPopularityAggregator(decay)
+	// a click counted before the restore is kept
+	p.Add(EventItemClick, 1, now.Add(time.Hour))
+	p.Restore(state)
+	scores := p.Scores(now.Add(time.Hour))
+	if !near(scores[1], 1.5) {
+		t.Errorf("Expected merged score 1.5 got %v", scores[1])
+	}
+	if !near(scores[2], 0.5) {
+		t.Errorf("Expected restored score 0.5 got %v", scores[2])
+	}
+	if _, ok := scores[3]; ok {
+		t.Error("Expected unscored events to be skipped")
+	}
+}
+
+func TestSessionInteractions(t *testing.T) {
+	p := NewPopularityAggregator(DefaultPopularityDecay())
+	now := time.Now()
+	for _, body := range []string{
+		`{"session_id":7,"event":2,"item":12}`,
+		`{"session_id":7,"event":4,"item":12}`,
+		`{"session_id":8,"event":2,"item":3}`,
+		`{"session_id":0,"event":2,"item":3}`,
+	} {
+		if err := p.HandleDelivery(amqp.Delivery{Body: []byte(body), Timestamp: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	changed := p.Sessions.Changed(now)
+	if len(changed) != 2 {
+		t.Fatalf("Expected two sessions got %v", changed)
+	}
+	for _, update := range changed {
+		if update.Key == "session-7" && !near(update.Data[12], 6) {
+			t.Errorf("Expected click and cart weights for session 7 got %v", update.Data)
+		}
+	}
+	if changed = p.Sessions.Changed(now); len(changed) != 0 {
+		t.Errorf("Expected no changes since the last call got %v", changed)
+	}
+	p.Sessions.Add(8, EventItemClick, 4, now)
+	if changed = p.Sessions.Changed(now); len(changed) != 1 || changed[0].Key != "session-8" || len(changed[0].Data) != 2 {
+		t.Errorf("Expected all items of session 8 got %v", changed)
+	}
+	p.Sessions.Changed(now.Add(SessionInteractionTTL + time.Second))
+	if len(p.Sessions.sessions) != 0 {
+		t.Errorf("Expected idle sessions to be forgotten got %d", len(p.Sessions.sessions))
+	}
+}
@@ -23,6 +23,17 @@ type RabbitTracking struct {
 
 const trackingTopic = "tracking"
 
+// Event types sent on the tracking topic
+const (
+	EventSession        uint16 = 0
+	EventSearch         uint16 = 1
+	EventItemClick      uint16 = 2
+	EventItemImpression uint16 = 3
+	EventAddToCart      uint16 = 4
+	EventPurchase       uint16 = 5
+	EventAction         uint16 = 6
+)
+
 func NewRabbitTracking(url, country string) (*RabbitTracking, error) {
 	ret := RabbitTracking{
 		connection: nil,
@@ -85,7 +96,7 @@ func (rt *RabbitTracking) TrackSession(sessionId int, r *http.Request) {
 	}
 
 	err := rt.send(Session{
-		BaseEvent:    &BaseEvent{Event: 0, SessionId: sessionId, Country: rt.country, Context: "b2c"},
+		BaseEvent:    &BaseEvent{Event: EventSession, SessionId: sessionId, Country: rt.country, Context: "b2c"},
 		Language:     r.Header.Get("Accept-Language"),
 		UserAgent:    r.UserAgent(),
 		Ip:           ip,
@@ -115,18 +126,27 @@ type SearchEventData struct {
 	Query           string `json:"query"`
 	Page            int    `json:"page"`
 	Referer         string `json:"referer"`
+	Experiment      string `json:"experiment,omitempty"`
+	Variant         string `json:"variant,omitempty"`
 }
 
-func (rt *RabbitTracking) TrackSearch(sessionId int, filters *types.Filters, resultLen int, query string, page int, r *http.Request) {
+// TrackSearch sends the search event, the assignment is the experiment variant
+// the results were sorted with, nil when none was used
+func (rt *RabbitTracking) TrackSearch(sessionId int, filters *types.Filters, resultLen int, query string, page int, assignment *types.ExperimentAssignment, r *http.Request) {
 	referer := r.Header.Get("Referer")
-	err := rt.send(&SearchEventData{
-		BaseEvent:       &BaseEvent{Event: 1, SessionId: sessionId, Country: rt.country, Context: "b2c"},
+	event := &SearchEventData{
+		BaseEvent:       &BaseEvent{Event: EventSearch, SessionId: sessionId, Country: rt.country, Context: "b2c"},
 		Filters:         filters,
 		Query:           query,
 		NumberOfResults: resultLen,
 		Page:            page,
 		Referer:         referer,
-	})
+	}
+	if assignment != nil {
+		event.Experiment = assignment.Experiment
+		event.Variant = assignment.Variant
+	}
+	err := rt.send(event)
 	if err != nil {
 		log.Println("Error sending search event: ", err)
 	}
@@ -135,7 +155,7 @@ func (rt *RabbitTracking) TrackSearch(sessionId int, filters *types.Filters, res
 
 func (rt *RabbitTracking) TrackAction(sessionId int, value types.TrackingAction) error {
 	return rt.send(&ActionEvent{
-		BaseEvent: &BaseEvent{Event: 6, SessionId: sessionId, Country: rt.country, Context: "b2c"},
+		BaseEvent: &BaseEvent{Event: EventAction, SessionId: sessionId, Country: rt.country, Context: "b2c"},
 		Action:    value.Action,
 		Reason:    value.Reason,
 	})
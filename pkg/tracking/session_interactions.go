@@ -0,0 +1,87 @@
+package tracking
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/matst80/slask-finder/pkg/types"
+)
+
+const (
+	// SessionInteractionTTL is how long a session is kept without events, the
+	// same as the session boost of the readers
+	SessionInteractionTTL = 30 * time.Minute
+	// MaxSessionItems limits the items kept per session, the first are kept
+	MaxSessionItems = 100
+)
+
+type sessionInteraction struct {
+	items   types.SortOverride
+	updated time.Time
+	changed bool
+}
+
+// SessionInteractions sums the item event weights per session, the changed
+// sessions are published as session-<id> sort overrides for the session boost
+type SessionInteractions struct {
+	mu       sync.Mutex
+	decay    map[uint16]EventDecay
+	sessions map[int]*sessionInteraction
+}
+
+func NewSessionInteractions(decay map[uint16]EventDecay) *SessionInteractions {
+	return &SessionInteractions{
+		decay:    decay,
+		sessions: make(map[int]*sessionInteraction),
+	}
+}
+
+// Add counts the event for the session with the weight of the event type,
+// false when the event isn't counted
+func (s *SessionInteractions) Add(sessionId int, event uint16, item uint32, at time.Time) bool {
+	d, ok := s.decay[event]
+	if !ok || sessionId == 0 || item == 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, found := s.sessions[sessionId]
+	if !found {
+		session = &sessionInteraction{items: types.SortOverride{}}
+		s.sessions[sessionId] = session
+	}
+	if _, seen := session.items[item]; !seen && len(session.items) >= MaxSessionItems {
+		return false
+	}
+	session.items[item] += d.Weight
+	session.changed = true
+	if at.After(session.updated) {
+		session.updated = at
+	}
+	return true
+}
+
+// Changed returns the overrides of the sessions changed since the last call
+// and forgets the sessions without events within SessionInteractionTTL
+func (s *SessionInteractions) Changed(now time.Time) []types.SortOverrideUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ret []types.SortOverrideUpdate
+	for id, session := range s.sessions {
+		if now.Sub(session.updated) > SessionInteractionTTL {
+			delete(s.sessions, id)
+			continue
+		}
+		if !session.changed {
+			continue
+		}
+		session.changed = false
+		ret = append(ret, types.SortOverrideUpdate{
+			Key:  fmt.Sprintf("session-%d", id),
+			Data: maps.Clone(session.items),
+		})
+	}
+	return ret
+}
@@ -0,0 +1,197 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CampaignWindow is a recurring part of the day when a campaign is active,
+// a window with To before From runs over midnight
+type CampaignWindow struct {
+	// Days the window starts on, 0 is sunday, empty means every day
+	Days []time.Weekday `json:"days,omitempty"`
+	// From and To are the local time of day as 15:04
+	From string `json:"from"`
+	To   string `json:"to"`
+	// from and to are the minutes since midnight, set by Validate
+	from, to int
+	parsed   bool
+}
+
+// CampaignRule applies its rules between ValidFrom and ValidTo, and only
+// within the windows when any are set
+type CampaignRule struct {
+	Name string `json:"name,omitempty"`
+	// ValidFrom and ValidTo are unix milliseconds like the other timestamps, 0
+	// leaves that side open
+	ValidFrom int64            `json:"validFrom,omitempty"`
+	ValidTo   int64            `json:"validTo,omitempty"`
+	Windows   []CampaignWindow `json:"windows,omitempty"`
+	// Timezone of the windows, the local timezone when empty
+	Timezone string    `json:"timezone,omitempty"`
+	Rules    JsonTypes `json:"rules"`
+	// loc is the timezone loaded by Validate
+	loc *time.Location
+}
+
+func (_ *CampaignRule) Type() RuleType {
+	return "CampaignRule"
+}
+
+func (_ *CampaignRule) New() JsonType {
+	return &CampaignRule{}
+}
+
+// UnmarshalJSON validates the campaign so invalid rules are rejected on load
+func (r *CampaignRule) UnmarshalJSON(b []byte) error {
+	type campaign CampaignRule
+	if err := json.Unmarshal(b, (*campaign)(r)); err != nil {
+		return err
+	}
+	if err := r.Validate(); err != nil {
+		return fmt.Errorf("campaign %s: %w", r.Name, err)
+	}
+	return nil
+}
+
+// Validate checks the campaign and resolves the timezone and the windows so
+// they are not parsed again for every item scored
+func (r *CampaignRule) Validate() error {
+	if r.ValidFrom > 0 && r.ValidTo > 0 && r.ValidTo <= r.ValidFrom {
+		return errors.New("validTo must be after validFrom")
+	}
+	loc, err := r.location()
+	if err != nil {
+		return err
+	}
+	for idx := range r.Windows {
+		w := &r.Windows[idx]
+		if w.from, err = parseClock(w.From); err != nil {
+			return fmt.Errorf("window %d: %w", idx, err)
+		}
+		if w.to, err = parseClock(w.To); err != nil {
+			return fmt.Errorf("window %d: %w", idx, err)
+		}
+		for _, day := range w.Days {
+			if day < time.Sunday || day > time.Saturday {
+				return fmt.Errorf("window %d: invalid day %d", idx, day)
+			}
+		}
+		w.parsed = true
+	}
+	for _, rule := range r.Rules {
+		if _, ok := rule.(ItemPopularityRule); !ok {
+			return fmt.Errorf("%s is not a popularity rule", rule.Type())
+		}
+	}
+	r.loc = loc
+	return nil
+}
+
+func (r *CampaignRule) location() (*time.Location, error) {
+	if r.loc != nil {
+		return r.loc, nil
+	}
+	if r.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(r.Timezone)
+}
+
+// parseClock returns the minutes since midnight of a 15:04 time
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected hh:mm", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (w *CampaignWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// clock returns the minutes since midnight of the window, parsed unless the
+// campaign is validated
+func (w *CampaignWindow) clock() (int, int, error) {
+	if w.parsed {
+		return w.from, w.to, nil
+	}
+	from, err := parseClock(w.From)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := parseClock(w.To)
+	return from, to, err
+}
+
+func (w *CampaignWindow) contains(t time.Time) bool {
+	from, to, err := w.clock()
+	if err != nil {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if from == to {
+		return w.onDay(t.Weekday())
+	}
+	if from < to {
+		return minute >= from && minute < to && w.onDay(t.Weekday())
+	}
+	if minute >= from {
+		return w.onDay(t.Weekday())
+	}
+	return minute < to && w.onDay((t.Weekday()+6)%7)
+}
+
+// IsActive reports if the campaign applies at the time
+func (r *CampaignRule) IsActive(t time.Time) bool {
+	if r.ValidFrom > 0 && t.UnixMilli() < r.ValidFrom {
+		return false
+	}
+	if r.ValidTo > 0 && t.UnixMilli() >= r.ValidTo {
+		return false
+	}
+	if len(r.Windows) == 0 {
+		return true
+	}
+	loc, err := r.location()
+	if err != nil {
+		return false
+	}
+	local := t.In(loc)
+	for i := range r.Windows {
+		if r.Windows[i].contains(local) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *CampaignRule) GetValue(item Item) float64 {
+	if !r.IsActive(time.Now()) {
+		return 0
+	}
+	return CollectPopularity(item, FromJsonTypes[ItemPopularityRule](r.Rules)...)
+}
+
+// FindCampaigns returns the campaign rules, including campaigns within campaigns
+func FindCampaigns(rules ...ItemPopularityRule) []*CampaignRule {
+	var ret []*CampaignRule
+	for _, rule := range rules {
+		if c, ok := rule.(*CampaignRule); ok {
+			ret = append(ret, c)
+			ret = append(ret, FindCampaigns(FromJsonTypes[ItemPopularityRule](c.Rules)...)...)
+		}
+	}
+	return ret
+}
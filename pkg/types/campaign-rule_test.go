@@ -0,0 +1,85 @@
+package types
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCampaignRule_IsActive(t *testing.T) {
+	from := time.Date(2025, 11, 24, 0, 0, 0, 0, time.UTC)
+	rule := &CampaignRule{
+		ValidFrom: from.UnixMilli(),
+		ValidTo:   from.Add(7 * 24 * time.Hour).UnixMilli(),
+		Timezone:  "UTC",
+		Windows: []CampaignWindow{
+			// weekday evenings over midnight and all of saturday
+			{Days: []time.Weekday{time.Monday, time.Tuesday}, From: "20:00", To: "02:00"},
+			{Days: []time.Weekday{time.Saturday}, From: "00:00", To: "00:00"},
+		},
+	}
+	cases := []struct {
+		at     time.Time
+		active bool
+	}{
+		{from.Add(-time.Hour), false},
+		{from.Add(19 * time.Hour), false},
+		{from.Add(20 * time.Hour), true},
+		{from.Add(25 * time.Hour), true},
+		{from.Add(26 * time.Hour), false},
+		{from.Add(49 * time.Hour), true},
+		{from.Add(73 * time.Hour), false},
+		{from.Add(5*24*time.Hour + 12*time.Hour), true},
+		{from.Add(7*24*time.Hour + 12*time.Hour), false},
+	}
+	for _, c := range cases {
+		if got := rule.IsActive(c.at); got != c.active {
+			t.Errorf("%s: expected active %v", c.at.Format(time.RFC1123), c.active)
+		}
+	}
+}
+
+func TestCampaignRule_GetValue(t *testing.T) {
+	rule := &CampaignRule{
+		ValidFrom: time.Now().Add(-time.Hour).UnixMilli(),
+		Rules:     JsonTypes{&DiscountRule{Multiplier: 2}},
+	}
+	expected := CollectPopularity(item, &DiscountRule{Multiplier: 2})
+	if res := rule.GetValue(item); res != expected {
+		t.Errorf("Expected %v but got %v", expected, res)
+	}
+	rule.ValidTo = time.Now().Add(-time.Minute).UnixMilli()
+	if res := rule.GetValue(item); res != 0 {
+		t.Errorf("Expected no value after the campaign, got %v", res)
+	}
+}
+
+func TestCampaignRule_Json(t *testing.T) {
+	var rules JsonTypes
+	err := json.Unmarshal([]byte(`[{"$type":"CampaignRule","name":"sale","validFrom":100,"windows":[{"from":"08:00","to":"10:00"}],
+		"rules":[{"$type":"CampaignRule","name":"inner","rules":[{"$type":"DiscountRule","multiplier":1}]}]}]`), &rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	campaigns := FindCampaigns(FromJsonTypes[ItemPopularityRule](rules)...)
+	if len(campaigns) != 2 || campaigns[0].Name != "sale" || campaigns[1].Name != "inner" {
+		t.Errorf("Expected nested campaigns, got %v", campaigns)
+	}
+	if w := campaigns[0].Windows[0]; campaigns[0].loc == nil || !w.parsed || w.from != 8*60 || w.to != 10*60 {
+		t.Errorf("Expected the timezone and window to be resolved on load, got %+v", campaigns[0])
+	}
+
+	invalid := map[string]string{
+		`{"$type":"CampaignRule","validFrom":100,"validTo":50}`:                         "validTo must be after validFrom",
+		`{"$type":"CampaignRule","windows":[{"from":"25:00","to":"10:00"}]}`:            "invalid time of day",
+		`{"$type":"CampaignRule","timezone":"Nowhere/City"}`:                            "unknown time zone",
+		`{"$type":"CampaignRule","windows":[{"days":[7],"from":"08:00","to":"10:00"}]}`: "invalid day 7",
+	}
+	for body, message := range invalid {
+		err = json.Unmarshal([]byte("["+body+"]"), &rules)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: expected error containing %q, got %v", body, message, err)
+		}
+	}
+}
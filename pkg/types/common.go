@@ -16,19 +16,62 @@ type BaseField struct {
 	KeySpecification bool    `json:"isKey,omitempty"`
 	InternalOnly     bool    `json:"internal,omitempty"`
 	Searchable       bool    `json:"searchable,omitempty"`
+	// Sortable numeric facets can be used in field:<id>:asc|desc item sorts
+	Sortable bool `json:"sortable,omitempty"`
+	// Histogram selects the binning of numeric facet results, equal, quantile or nice
+	Histogram     string `json:"histogram,omitempty"`
+	HistogramBins int    `json:"histogramBins,omitempty"`
+	// Ranges are named value bands for numeric facets, filtered by name like key values
+	Ranges []NamedRange `json:"ranges,omitempty"`
+	// ValueOrder pins values first in this order, used by ValueSortCustom
+	ValueOrder []string `json:"valueOrder,omitempty"`
+	// ValueLimit is the max number of sorted values returned, the rest is reported as more
+	ValueLimit int `json:"valueLimit,omitempty"`
+	// Property reads the facet value from an item property like ReleaseDate instead of the item fields
+	Property string `json:"property,omitempty"`
+	// Normalize are the rules applied to key values before they are indexed
+	Normalize *ValueNormalization `json:"normalize,omitempty"`
+	// ValueNames are display names for values, like names for codes
+	ValueNames map[string]string `json:"valueNames,omitempty"`
 	// IgnoreCategoryIfSearched bool    `json:"-"`
 	// IgnoreIfInSearch         bool    `json:"-"`
 }
 
+// Value sorting modes for BaseField.ValueSorting
+const (
+	ValueSortCount   uint = 0
+	ValueSortAlpha   uint = 1
+	ValueSortNatural uint = 2
+	ValueSortCustom  uint = 3
+)
+
+// NamedRange is a numeric band with inclusive bounds, a missing bound is open ended
+type NamedRange struct {
+	Name string   `json:"name"`
+	Min  *float64 `json:"min,omitempty"`
+	Max  *float64 `json:"max,omitempty"`
+}
+
+func (b *BaseField) GetNamedRange(name string) (NamedRange, bool) {
+	for _, r := range b.Ranges {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return NamedRange{}, false
+}
+
 type FacetRequest struct {
 	*Filters
 	Query        string    `json:"query" schema:"query"`
 	Stock        []string  `json:"stock" schema:"stock"`
 	IgnoreFacets []FacetId `json:"skipFacets" schema:"sf"`
+	// Stats requests numeric statistics over the matching items for the facets
+	Stats []FacetId `json:"stats,omitempty" schema:"stats"`
 }
 
 func (s *FacetRequest) Sanitize() {
-	if (len(s.StringFilter) > 0 || len(s.RangeFilter) > 0) && s.Query == "*" {
+	if (len(s.StringFilter) > 0 || len(s.RangeFilter) > 0 || len(s.PathFilter) > 0) && s.Query == "*" {
 		s.Query = ""
 	}
 }
@@ -52,11 +95,20 @@ func (b *BaseField) UpdateFrom(field *BaseField) {
 	b.LinkedId = field.LinkedId
 	b.ValueSorting = field.ValueSorting
 	b.Searchable = field.Searchable
+	b.Sortable = field.Sortable
 	b.HideFacet = field.HideFacet
 	b.CategoryLevel = field.CategoryLevel
 	b.GroupId = field.GroupId
 	b.KeySpecification = field.KeySpecification
 	b.InternalOnly = field.InternalOnly
+	b.Histogram = field.Histogram
+	b.HistogramBins = field.HistogramBins
+	b.Ranges = field.Ranges
+	b.ValueOrder = field.ValueOrder
+	b.ValueLimit = field.ValueLimit
+	b.Property = field.Property
+	b.Normalize = field.Normalize
+	b.ValueNames = field.ValueNames
 }
 
 func (f *FacetRequest) HasField(id FacetId) bool {
@@ -108,6 +160,8 @@ const FacetKeyType = 1
 const FacetNumberType = 2
 const FacetIntegerType = 3
 const FacetTreeType = 4
+const FacetDateType = 5
+const FacetBoolType = 6
 
 type Embeddings []float32
 
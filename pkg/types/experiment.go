@@ -0,0 +1,114 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+)
+
+// Experiment splits the sessions between ranking variants
+type Experiment struct {
+	Id       string              `json:"id"`
+	Variants []ExperimentVariant `json:"variants"`
+	// Started is set when the experiment is started, in unix seconds
+	Started int64 `json:"started,omitempty"`
+}
+
+// ExperimentVariant replaces parts of the ranking for its sessions, settings
+// left empty keep the defaults
+type ExperimentVariant struct {
+	Id string `json:"id"`
+	// Share is the part of the sessions relative to the other variants
+	Share uint `json:"share"`
+	// PopularityRules replace the popularity rules of the popular sort
+	PopularityRules JsonTypes `json:"popularityRules,omitempty"`
+	// OverrideKey is the sort override applied instead of popular
+	OverrideKey string `json:"overrideKey,omitempty"`
+	// FacetOrder are the facets shown first when no facet profile matches
+	FacetOrder []FacetId `json:"facetOrder,omitempty"`
+}
+
+func (e *Experiment) Validate() error {
+	if e.Id == "" {
+		return errors.New("missing experiment id")
+	}
+	if len(e.Variants) == 0 {
+		return errors.New("experiment needs at least one variant")
+	}
+	seen := make(map[string]struct{}, len(e.Variants))
+	var total uint
+	for idx, v := range e.Variants {
+		if v.Id == "" {
+			return fmt.Errorf("variant %d: missing id", idx)
+		}
+		if _, found := seen[v.Id]; found {
+			return fmt.Errorf("variant %d: duplicate id %s", idx, v.Id)
+		}
+		seen[v.Id] = struct{}{}
+		for _, rule := range v.PopularityRules {
+			if _, ok := rule.(ItemPopularityRule); !ok {
+				return fmt.Errorf("variant %s: %s is not a popularity rule", v.Id, rule.Type())
+			}
+		}
+		total += v.Share
+	}
+	if total == 0 {
+		return errors.New("variants have no share")
+	}
+	return nil
+}
+
+// Assign picks the variant of the session, the same session always gets the
+// same variant within an experiment
+func (e *Experiment) Assign(sessionId int) (*ExperimentVariant, bool) {
+	if e == nil || sessionId == 0 {
+		return nil, false
+	}
+	var total uint
+	for _, v := range e.Variants {
+		total += v.Share
+	}
+	if total == 0 {
+		return nil, false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(e.Id))
+	h.Write([]byte{':'})
+	h.Write([]byte(strconv.Itoa(sessionId)))
+	slot := uint(h.Sum32()) % total
+	for i := range e.Variants {
+		v := &e.Variants[i]
+		if slot < v.Share {
+			return v, true
+		}
+		slot -= v.Share
+	}
+	return nil, false
+}
+
+// ExperimentAssignment is the experiment variant a session was served
+type ExperimentAssignment struct {
+	Experiment string
+	Variant    string
+}
+
+// SortName is the name of the popular sort of the variant
+func (e *Experiment) SortName(v *ExperimentVariant) string {
+	return fmt.Sprintf("popular@%s/%s", e.Id, v.Id)
+}
+
+// Rules returns the popularity rules of the variant, false when the default
+// rules are used
+func (v *ExperimentVariant) Rules() (ItemPopularityRules, bool) {
+	if len(v.PopularityRules) == 0 {
+		return nil, false
+	}
+	ret := make(ItemPopularityRules, 0, len(v.PopularityRules))
+	for _, rule := range v.PopularityRules {
+		if r, ok := rule.(ItemPopularityRule); ok {
+			ret = append(ret, r)
+		}
+	}
+	return ret, true
+}
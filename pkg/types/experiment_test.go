@@ -0,0 +1,77 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExperimentValidate(t *testing.T) {
+	invalid := []Experiment{
+		{Variants: []ExperimentVariant{{Id: "a", Share: 1}}},
+		{Id: "x"},
+		{Id: "x", Variants: []ExperimentVariant{{Share: 1}}},
+		{Id: "x", Variants: []ExperimentVariant{{Id: "a", Share: 1}, {Id: "a", Share: 1}}},
+		{Id: "x", Variants: []ExperimentVariant{{Id: "a"}, {Id: "b"}}},
+	}
+	for i, e := range invalid {
+		if err := e.Validate(); err == nil {
+			t.Errorf("Expected experiment %d to be invalid", i)
+		}
+	}
+	valid := Experiment{Id: "x", Variants: []ExperimentVariant{{Id: "a", Share: 1}, {Id: "b"}}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid experiment got %v", err)
+	}
+}
+
+func TestExperimentAssign(t *testing.T) {
+	e := &Experiment{Id: "x", Variants: []ExperimentVariant{{Id: "a", Share: 3}, {Id: "b", Share: 1}, {Id: "c"}}}
+	counts := map[string]int{}
+	for session := 1; session <= 4000; session++ {
+		v, ok := e.Assign(session)
+		if !ok {
+			t.Fatalf("Expected session %d to get a variant", session)
+		}
+		again, _ := e.Assign(session)
+		if again != v {
+			t.Fatalf("Expected session %d to keep its variant", session)
+		}
+		counts[v.Id]++
+	}
+	if counts["c"] != 0 {
+		t.Errorf("Expected no sessions without share got %d", counts["c"])
+	}
+	if counts["a"] < 2700 || counts["a"] > 3300 {
+		t.Errorf("Expected about three quarters in a got %v", counts)
+	}
+	if _, ok := e.Assign(0); ok {
+		t.Error("Expected no variant without session")
+	}
+	var none *Experiment
+	if _, ok := none.Assign(1); ok {
+		t.Error("Expected no variant without experiment")
+	}
+}
+
+func TestExperimentVariantRules(t *testing.T) {
+	data := `{"id":"x","variants":[{"id":"a","share":1,"popularityRules":[{"$type":"RatingRule","multiplier":2}]},{"id":"b","share":1}]}`
+	e := Experiment{}
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		t.Fatal(err)
+	}
+	rules, ok := e.Variants[0].Rules()
+	if !ok || len(rules) != 1 || rules[0].GetValue(&MockItem{}) != 40 {
+		t.Errorf("Expected rating rule got %v", rules)
+	}
+	if _, ok := e.Variants[1].Rules(); ok {
+		t.Error("Expected default rules for variant without rules")
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again := Experiment{}
+	if err = json.Unmarshal(b, &again); err != nil || len(again.Variants[0].PopularityRules) != 1 {
+		t.Errorf("Expected rules to survive a round trip got %s %v", b, err)
+	}
+}
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// ExpressionRule scores items with an expression like
+// log(rating_count+1)*rating/5*200 + (discount_percent>20 ? 500 : 0),
+// see CompileExpression for the available values and functions
+type ExpressionRule struct {
+	Expression string `json:"expression"`
+
+	once     sync.Once
+	compiled *Expression
+}
+
+func (_ *ExpressionRule) Type() RuleType {
+	return "ExpressionRule"
+}
+
+func (_ *ExpressionRule) New() JsonType {
+	return &ExpressionRule{}
+}
+
+// UnmarshalJSON compiles the expression so invalid rules are rejected on load
+func (r *ExpressionRule) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Expression string `json:"expression"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	compiled, err := CompileExpression(raw.Expression)
+	if err != nil {
+		return err
+	}
+	r.Expression = raw.Expression
+	r.compiled = compiled
+	r.once.Do(func() {})
+	return nil
+}
+
+func (r *ExpressionRule) GetValue(item Item) float64 {
+	r.once.Do(func() {
+		if r.compiled == nil {
+			r.compiled, _ = CompileExpression(r.Expression)
+		}
+	})
+	if r.compiled == nil {
+		return 0
+	}
+	return r.compiled.Evaluate(item)
+}
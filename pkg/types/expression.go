@@ -0,0 +1,640 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// MaxExpressionLength limits the size of popularity expressions
+	MaxExpressionLength = 2000
+	maxExpressionDepth  = 64
+)
+
+// ExpressionError points at the part of the expression that can't be compiled
+type ExpressionError struct {
+	Expression string
+	Position   int
+	Message    string
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("%s at column %d\n  %s\n  %s^", e.Message, e.Position+1, e.Expression, strings.Repeat(" ", e.Position))
+}
+
+// Expression is a compiled popularity expression, see CompileExpression
+type Expression struct {
+	source string
+	eval   exprFunc
+}
+
+// exprFunc returns a float64, a string or nil when the value is missing
+type exprFunc func(item Item) any
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate returns the value of the expression for the item, missing values
+// and invalid math like division by zero give 0
+func (e *Expression) Evaluate(item Item) float64 {
+	v := exprNumber(e.eval(item))
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
+
+// exprVariables are the item values available by name
+var exprVariables = map[string]exprFunc{
+	"price": func(item Item) any {
+		return float64(item.GetPrice())
+	},
+	"discount": func(item Item) any {
+		return float64(item.GetDiscount())
+	},
+	"discount_percent": func(item Item) any {
+		discount := float64(item.GetDiscount())
+		if full := float64(item.GetPrice()) + discount; full > 0 {
+			return discount / full * 100
+		}
+		return 0.0
+	},
+	"rating": func(item Item) any {
+		avg, num := item.GetRating()
+		if num == 0 {
+			return nil
+		}
+		return float64(avg)
+	},
+	"rating_count": func(item Item) any {
+		_, num := item.GetRating()
+		return float64(num)
+	},
+	"stock": func(item Item) any {
+		total := 0
+		for _, v := range item.GetStock() {
+			total += int(v)
+		}
+		return float64(total)
+	},
+	"stores": func(item Item) any {
+		return float64(len(item.GetStock()))
+	},
+	"in_stock": func(item Item) any {
+		return exprBool(item.HasStock())
+	},
+	"age_hours": func(item Item) any {
+		return exprAge(item.GetCreated(), time.Hour)
+	},
+	"age_days": func(item Item) any {
+		return exprAge(item.GetCreated(), 24*time.Hour)
+	},
+	"updated_hours": func(item Item) any {
+		return exprAge(item.GetLastUpdated(), time.Hour)
+	},
+}
+
+func exprAge(unixMilli int64, unit time.Duration) any {
+	if unixMilli <= 0 {
+		return nil
+	}
+	return float64(time.Now().UnixMilli()-unixMilli) / float64(unit.Milliseconds())
+}
+
+type exprFunction struct {
+	minArgs, maxArgs int
+	// facetArg marks functions taking a facet id as the first argument
+	facetArg bool
+	build    func(args []exprFunc) exprFunc
+}
+
+func mathFunction(fn func(float64) float64) exprFunction {
+	return exprFunction{minArgs: 1, maxArgs: 1, build: func(args []exprFunc) exprFunc {
+		return func(item Item) any {
+			return fn(exprNumber(args[0](item)))
+		}
+	}}
+}
+
+var exprFunctions = map[string]exprFunction{
+	"log":   mathFunction(math.Log),
+	"log10": mathFunction(math.Log10),
+	"sqrt":  mathFunction(math.Sqrt),
+	"abs":   mathFunction(math.Abs),
+	"floor": mathFunction(math.Floor),
+	"ceil":  mathFunction(math.Ceil),
+	"round": mathFunction(math.Round),
+	"exp":   mathFunction(math.Exp),
+	"pow": {minArgs: 2, maxArgs: 2, build: func(args []exprFunc) exprFunc {
+		return func(item Item) any {
+			return math.Pow(exprNumber(args[0](item)), exprNumber(args[1](item)))
+		}
+	}},
+	"min": {minArgs: 1, maxArgs: 16, build: func(args []exprFunc) exprFunc {
+		return func(item Item) any {
+			ret := exprNumber(args[0](item))
+			for _, arg := range args[1:] {
+				ret = math.Min(ret, exprNumber(arg(item)))
+			}
+			return ret
+		}
+	}},
+	"max": {minArgs: 1, maxArgs: 16, build: func(args []exprFunc) exprFunc {
+		return func(item Item) any {
+			ret := exprNumber(args[0](item))
+			for _, arg := range args[1:] {
+				ret = math.Max(ret, exprNumber(arg(item)))
+			}
+			return ret
+		}
+	}},
+	"clamp": {minArgs: 3, maxArgs: 3, build: func(args []exprFunc) exprFunc {
+		return func(item Item) any {
+			return math.Min(math.Max(exprNumber(args[0](item)), exprNumber(args[1](item))), exprNumber(args[2](item)))
+		}
+	}},
+	// field(id) is the value of a number or string facet
+	"field": {minArgs: 1, maxArgs: 1, facetArg: true, build: func(args []exprFunc) exprFunc {
+		id := FacetId(exprNumber(args[0](nil)))
+		return func(item Item) any {
+			if v, ok := item.GetNumberFieldValue(id); ok {
+				return v
+			}
+			if v, ok := item.GetStringFieldValue(id); ok {
+				return v
+			}
+			return nil
+		}
+	}},
+	// has(id) is 1 when the item has a value for the facet
+	"has": {minArgs: 1, maxArgs: 1, facetArg: true, build: func(args []exprFunc) exprFunc {
+		id := FacetId(exprNumber(args[0](nil)))
+		return func(item Item) any {
+			if _, ok := item.GetNumberFieldValue(id); ok {
+				return 1.0
+			}
+			_, ok := item.GetStringFieldValue(id)
+			return exprBool(ok)
+		}
+	}},
+	// prop(name) is an item property like "Title" or "Buyable"
+	"prop": {minArgs: 1, maxArgs: 1, build: func(args []exprFunc) exprFunc {
+		return func(item Item) any {
+			name, ok := args[0](item).(string)
+			if !ok {
+				return nil
+			}
+			return exprValue(item.GetPropertyValue(name))
+		}
+	}},
+	"contains": {minArgs: 2, maxArgs: 2, build: func(args []exprFunc) exprFunc {
+		return func(item Item) any {
+			return exprBool(strings.Contains(strings.ToLower(exprString(args[0](item))), strings.ToLower(exprString(args[1](item)))))
+		}
+	}},
+}
+
+// exprValue converts item properties to the expression values
+func exprValue(v any) any {
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return value
+	case bool:
+		return exprBool(value)
+	}
+	if n, ok := AsNumber[float64](v); ok {
+		return n
+	}
+	return fmt.Sprint(v)
+}
+
+func exprBool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func exprNumber(v any) float64 {
+	switch value := v.(type) {
+	case float64:
+		return value
+	case string:
+		if n, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+func exprString(v any) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return ""
+}
+
+func exprTruthy(v any) bool {
+	switch value := v.(type) {
+	case float64:
+		return value != 0 && !math.IsNaN(value)
+	case string:
+		return value != ""
+	}
+	return false
+}
+
+type exprTokenKind int
+
+const (
+	tokenEnd exprTokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type exprToken struct {
+	kind  exprTokenKind
+	text  string
+	value float64
+	pos   int
+}
+
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "+", "-", "*", "/", "%", "<", ">", "!", "?", ":", "(", ")", ","}
+
+func tokenizeExpression(src string) ([]exprToken, error) {
+	tokens := make([]exprToken, 0, len(src)/2)
+	fail := func(pos int, format string, args ...any) error {
+		return &ExpressionError{Expression: src, Position: pos, Message: fmt.Sprintf(format, args...)}
+	}
+	i := 0
+outer:
+	for i < len(src) {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			value, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fail(start, "invalid number %q", src[start:i])
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: src[start:i], value: value, pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			var sb strings.Builder
+			for i < len(src) && rune(src[i]) != c {
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				sb.WriteByte(src[i])
+				i++
+			}
+			if i >= len(src) {
+				return nil, fail(start, "unterminated string")
+			}
+			i++
+			tokens = append(tokens, exprToken{kind: tokenString, text: sb.String(), pos: start})
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: src[start:i], pos: start})
+		default:
+			for _, op := range exprOperators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, exprToken{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					continue outer
+				}
+			}
+			return nil, fail(i, "unexpected character %q", c)
+		}
+	}
+	return append(tokens, exprToken{kind: tokenEnd, pos: len(src)}), nil
+}
+
+type exprParser struct {
+	src    string
+	tokens []exprToken
+	pos    int
+	depth  int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) isOperator(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) fail(t exprToken, format string, args ...any) error {
+	return &ExpressionError{Expression: p.src, Position: t.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *exprParser) describe(t exprToken) string {
+	if t.kind == tokenEnd {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+func (p *exprParser) expect(op string) error {
+	t := p.next()
+	if t.kind != tokenOperator || t.text != op {
+		return p.fail(t, "expected %q but found %s", op, p.describe(t))
+	}
+	return nil
+}
+
+// CompileExpression parses a popularity expression. Expressions use numbers,
+// strings, + - * / %, comparisons, && || !, cond ? a : b, the variables price,
+// discount, discount_percent, rating, rating_count, stock, stores, in_stock,
+// age_hours, age_days and updated_hours and functions like log, min, max,
+// clamp, field(facetId), has(facetId) and prop("Name")
+func CompileExpression(src string) (*Expression, error) {
+	if len(src) > MaxExpressionLength {
+		return nil, &ExpressionError{Expression: src[:40] + "...", Position: 0, Message: fmt.Sprintf("expression longer than %d characters", MaxExpressionLength)}
+	}
+	if strings.TrimSpace(src) == "" {
+		return nil, &ExpressionError{Expression: src, Position: 0, Message: "empty expression"}
+	}
+	tokens, err := tokenizeExpression(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{src: src, tokens: tokens}
+	eval, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEnd {
+		return nil, p.fail(t, "unexpected %s", p.describe(t))
+	}
+	return &Expression{source: src, eval: eval}, nil
+}
+
+func (p *exprParser) parseTernary() (exprFunc, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxExpressionDepth {
+		return nil, p.fail(p.peek(), "expression nested too deep")
+	}
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.isOperator("?"); !ok {
+		return cond, nil
+	}
+	p.next()
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return func(item Item) any {
+		if exprTruthy(cond(item)) {
+			return then(item)
+		}
+		return otherwise(item)
+	}, nil
+}
+
+// exprLevels are the binary operators from the lowest precedence
+var exprLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) parseBinary(level int) (exprFunc, error) {
+	if level == len(exprLevels) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.isOperator(exprLevels[level]...)
+		if !ok {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryOperator(op, left, right)
+	}
+}
+
+func binaryOperator(op string, a, b exprFunc) exprFunc {
+	switch op {
+	case "||":
+		return func(item Item) any { return exprBool(exprTruthy(a(item)) || exprTruthy(b(item))) }
+	case "&&":
+		return func(item Item) any { return exprBool(exprTruthy(a(item)) && exprTruthy(b(item))) }
+	case "==", "!=":
+		negate := op == "!="
+		return func(item Item) any {
+			av, bv := a(item), b(item)
+			_, aString := av.(string)
+			_, bString := bv.(string)
+			var equal bool
+			if aString && bString {
+				equal = strings.EqualFold(av.(string), bv.(string))
+			} else {
+				equal = exprNumber(av) == exprNumber(bv)
+			}
+			return exprBool(equal != negate)
+		}
+	case "<":
+		return func(item Item) any { return exprBool(exprNumber(a(item)) < exprNumber(b(item))) }
+	case "<=":
+		return func(item Item) any { return exprBool(exprNumber(a(item)) <= exprNumber(b(item))) }
+	case ">":
+		return func(item Item) any { return exprBool(exprNumber(a(item)) > exprNumber(b(item))) }
+	case ">=":
+		return func(item Item) any { return exprBool(exprNumber(a(item)) >= exprNumber(b(item))) }
+	case "+":
+		return func(item Item) any {
+			av, bv := a(item), b(item)
+			_, aString := av.(string)
+			_, bString := bv.(string)
+			if aString || bString {
+				return exprString(av) + exprString(bv)
+			}
+			return exprNumber(av) + exprNumber(bv)
+		}
+	case "-":
+		return func(item Item) any { return exprNumber(a(item)) - exprNumber(b(item)) }
+	case "*":
+		return func(item Item) any { return exprNumber(a(item)) * exprNumber(b(item)) }
+	case "/":
+		return func(item Item) any {
+			divisor := exprNumber(b(item))
+			if divisor == 0 {
+				return 0.0
+			}
+			return exprNumber(a(item)) / divisor
+		}
+	}
+	// %
+	return func(item Item) any {
+		divisor := exprNumber(b(item))
+		if divisor == 0 {
+			return 0.0
+		}
+		return math.Mod(exprNumber(a(item)), divisor)
+	}
+}
+
+func (p *exprParser) parseUnary() (exprFunc, error) {
+	op, ok := p.isOperator("-", "!")
+	if !ok {
+		return p.parsePrimary()
+	}
+	p.next()
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxExpressionDepth {
+		return nil, p.fail(p.peek(), "expression nested too deep")
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if op == "-" {
+		return func(item Item) any { return -exprNumber(operand(item)) }, nil
+	}
+	return func(item Item) any { return exprBool(!exprTruthy(operand(item))) }, nil
+}
+
+func (p *exprParser) parsePrimary() (exprFunc, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		value := t.value
+		return func(Item) any { return value }, nil
+	case tokenString:
+		value := t.text
+		return func(Item) any { return value }, nil
+	case tokenIdent:
+		if _, call := p.isOperator("("); call {
+			return p.parseCall(t)
+		}
+		switch t.text {
+		case "true":
+			return func(Item) any { return 1.0 }, nil
+		case "false":
+			return func(Item) any { return 0.0 }, nil
+		}
+		variable, ok := exprVariables[t.text]
+		if !ok {
+			return nil, p.fail(t, "unknown variable %q", t.text)
+		}
+		return variable, nil
+	case tokenOperator:
+		if t.text == "(" {
+			inner, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		}
+	}
+	return nil, p.fail(t, "expected a value but found %s", p.describe(t))
+}
+
+func (p *exprParser) parseCall(name exprToken) (exprFunc, error) {
+	fn, ok := exprFunctions[name.text]
+	if !ok {
+		return nil, p.fail(name, "unknown function %q", name.text)
+	}
+	p.next()
+	args := make([]exprFunc, 0, fn.minArgs)
+	if _, empty := p.isOperator(")"); !empty {
+		for {
+			if fn.facetArg && len(args) == 0 {
+				// the facet id is resolved when compiling so it must be a literal
+				argToken := p.next()
+				after := p.peek()
+				if argToken.kind != tokenNumber || argToken.value < 0 || argToken.value != math.Trunc(argToken.value) ||
+					after.kind != tokenOperator || (after.text != ")" && after.text != ",") {
+					return nil, p.fail(argToken, "%s expects a facet id", name.text)
+				}
+				id := argToken.value
+				args = append(args, func(Item) any { return id })
+			} else {
+				arg, err := p.parseTernary()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+			}
+			if _, more := p.isOperator(","); !more {
+				break
+			}
+			p.next()
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if len(args) < fn.minArgs || len(args) > fn.maxArgs {
+		if fn.minArgs == fn.maxArgs {
+			return nil, p.fail(name, "%s takes %d arguments, got %d", name.text, fn.minArgs, len(args))
+		}
+		return nil, p.fail(name, "%s takes %d to %d arguments, got %d", name.text, fn.minArgs, fn.maxArgs, len(args))
+	}
+	return fn.build(args), nil
+}
@@ -0,0 +1,149 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+var expressionItem = &MockItem{
+	Id:    1,
+	Title: "Hello",
+	StringFields: map[FacetId]string{
+		10: "World",
+	},
+	NumberFields: map[FacetId]float64{
+		4: 15000,
+	},
+	Price:    150,
+	OrgPrice: 200,
+	Stock: map[string]uint16{
+		"1": 2,
+		"2": 3,
+	},
+	Created: time.Now().Add(-48 * time.Hour).UnixMilli(),
+}
+
+func TestExpression_Evaluate(t *testing.T) {
+	cases := []struct {
+		expression string
+		expected   float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"-2 * -3", 6},
+		{"10 % 4", 2},
+		{"1 / 0", 0},
+		{"price", 150},
+		{"discount", 50},
+		{"discount_percent", 25},
+		{"rating_count", 5},
+		{"stock", 5},
+		{"stores", 2},
+		{"round(age_days)", 2},
+		{"round(age_hours)", 48},
+		{"discount_percent > 20 ? 500 : 0", 500},
+		{"discount_percent > 30 ? 500 : 0", 0},
+		{"price > 100 && stock >= 5", 1},
+		{"price > 200 || !has(10)", 0},
+		{"field(4) / 1000", 15},
+		{"field(99) + 1", 1},
+		{"field(10) == 'world'", 1},
+		{`contains(field(10), "orl")`, 1},
+		{"max(1, 5, 3) + min(4, 2)", 7},
+		{"clamp(price, 0, 100)", 100},
+		{"pow(2, 10)", 1024},
+		{"log(-1)", 0},
+		{"log(rating_count+1)*rating/5*200 + (discount>20 ? 500 : 0)", math.Log(6)*20/5*200 + 500},
+	}
+	for _, c := range cases {
+		e, err := CompileExpression(c.expression)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.expression, err)
+			continue
+		}
+		if got := e.Evaluate(expressionItem); math.Abs(got-c.expected) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", c.expression, c.expected, got)
+		}
+	}
+}
+
+func TestCompileExpression_Errors(t *testing.T) {
+	cases := []struct {
+		expression string
+		message    string
+		position   int
+	}{
+		{"", "empty expression", 0},
+		{"price +", "expected a value but found end of expression", 7},
+		{"price * popularity", `unknown variable "popularity"`, 8},
+		{"sin(price)", `unknown function "sin"`, 0},
+		{"(price + 1", `expected ")" but found end of expression`, 10},
+		{"price ? 1", `expected ":" but found end of expression`, 9},
+		{"price 2", `unexpected "2"`, 6},
+		{"price # 2", `unexpected character '#'`, 6},
+		{"pow(2)", "pow takes 2 arguments, got 1", 0},
+		{"field(price)", "field expects a facet id", 6},
+		{"field(12 + price)", "field expects a facet id", 6},
+		{"has(3 ? rating : 1)", "has expects a facet id", 4},
+		{"field(1.5)", "field expects a facet id", 6},
+		{"'open", "unterminated string", 0},
+		{strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100), "expression nested too deep", 64},
+	}
+	for _, c := range cases {
+		_, err := CompileExpression(c.expression)
+		var exprErr *ExpressionError
+		if !errors.As(err, &exprErr) {
+			t.Errorf("%q: expected an expression error, got %v", c.expression, err)
+			continue
+		}
+		if exprErr.Message != c.message || exprErr.Position != c.position {
+			t.Errorf("%q: expected %q at %d, got %q at %d", c.expression, c.message, c.position, exprErr.Message, exprErr.Position)
+		}
+	}
+}
+
+func TestExpressionError_Error(t *testing.T) {
+	_, err := CompileExpression("price * popularity")
+	expected := "unknown variable \"popularity\" at column 9\n  price * popularity\n          ^"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestExpressionRule_Json(t *testing.T) {
+	var rules JsonTypes
+	err := json.Unmarshal([]byte(`[{"$type":"ExpressionRule","expression":"price * 2"}]`), &rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := CollectPopularity(expressionItem, FromJsonTypes[ItemPopularityRule](rules)...); res != 300 {
+		t.Errorf("expected 300, got %v", res)
+	}
+	b, err := json.Marshal(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `[{"expression":"price * 2","$type":"ExpressionRule"}]` {
+		t.Errorf("unexpected json %s", b)
+	}
+
+	err = json.Unmarshal([]byte(`[{"$type":"ExpressionRule","expression":"price *"}]`), &rules)
+	if err == nil {
+		t.Error("expected invalid expression to fail")
+	}
+}
+
+func TestExpressionRule_GetValue_NotCompiled(t *testing.T) {
+	rule := &ExpressionRule{Expression: "stock * 10"}
+	if res := rule.GetValue(expressionItem); res != 50 {
+		t.Errorf("expected 50, got %v", res)
+	}
+	invalid := &ExpressionRule{Expression: "stock *"}
+	if res := invalid.GetValue(expressionItem); res != 0 {
+		t.Errorf("expected 0, got %v", res)
+	}
+}
@@ -1,6 +1,13 @@
 package types
 
-import "log"
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
 
 type StringFilterValue = []string
 
@@ -10,10 +17,208 @@ type StringFilter struct {
 	Not   bool              `json:"exclude"`
 }
 
+// RangeFilter matches values between Min and Max, a missing bound is open
+// ended and the bounds are included unless flagged as exclusive
 type RangeFilter struct {
-	Id  FacetId `json:"id"`
-	Min any     `json:"min"`
-	Max any     `json:"max"`
+	Id           FacetId  `json:"id"`
+	Min          *float64 `json:"min,omitempty"`
+	Max          *float64 `json:"max,omitempty"`
+	MinExclusive bool     `json:"minExclusive,omitempty"`
+	MaxExclusive bool     `json:"maxExclusive,omitempty"`
+	Not          bool     `json:"exclude,omitempty"`
+	// MinText and MaxText are bounds given as strings that are not numbers,
+	// date strings for date facets
+	MinText string `json:"-"`
+	MaxText string `json:"-"`
+}
+
+// UnmarshalJSON accepts numbers and strings as bounds, strings that are not
+// numbers are kept as text bounds
+func (r *RangeFilter) UnmarshalJSON(b []byte) error {
+	type rangeFilter RangeFilter
+	aux := struct {
+		*rangeFilter
+		Min any `json:"min"`
+		Max any `json:"max"`
+	}{rangeFilter: (*rangeFilter)(r)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	var err error
+	if r.Min, r.MinText, err = jsonRangeBound(aux.Min); err != nil {
+		return err
+	}
+	r.Max, r.MaxText, err = jsonRangeBound(aux.Max)
+	return err
+}
+
+// MarshalJSON writes text bounds as strings so the filter reads back the same
+func (r RangeFilter) MarshalJSON() ([]byte, error) {
+	type rangeFilter RangeFilter
+	aux := struct {
+		rangeFilter
+		Min any `json:"min,omitempty"`
+		Max any `json:"max,omitempty"`
+	}{rangeFilter: rangeFilter(r)}
+	if r.Min != nil {
+		aux.Min = *r.Min
+	} else if r.MinText != "" {
+		aux.Min = r.MinText
+	}
+	if r.Max != nil {
+		aux.Max = *r.Max
+	} else if r.MaxText != "" {
+		aux.Max = r.MaxText
+	}
+	return json.Marshal(aux)
+}
+
+func jsonRangeBound(value any) (*float64, string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, "", nil
+	case float64:
+		return &v, "", nil
+	case string:
+		if f, err := parseRangeBound(v); err == nil {
+			return f, "", nil
+		}
+		return nil, strings.TrimSpace(v), nil
+	}
+	return nil, "", fmt.Errorf("invalid range bound %v", value)
+}
+
+// HasTextBounds reports if any bound is a string that is not a number, only
+// date facets can match them
+func (r RangeFilter) HasTextBounds() bool {
+	return r.MinText != "" || r.MaxText != ""
+}
+
+// NewRangeFilter creates an inclusive range filter
+func NewRangeFilter(id FacetId, min, max float64) RangeFilter {
+	return RangeFilter{Id: id, Min: &min, Max: &max}
+}
+
+// IsOpen reports if the filter has no bounds at all
+func (r RangeFilter) IsOpen() bool {
+	return r.Min == nil && r.Max == nil && !r.HasTextBounds()
+}
+
+// Bounds returns the bounds with infinity for open ends
+func (r RangeFilter) Bounds() (float64, float64) {
+	minValue, maxValue := math.Inf(-1), math.Inf(1)
+	if r.Min != nil {
+		minValue = *r.Min
+	}
+	if r.Max != nil {
+		maxValue = *r.Max
+	}
+	return minValue, maxValue
+}
+
+func parseRangeBound(value string) (*float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "*" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// ParseRangeFilter parses the query string form of a range filter value, a
+// leading ! negates the range. Supported forms:
+//
+//	100-200      inclusive, either side can be left out: 100- or -200
+//	[100,200)    interval notation, ( and ) exclude the bound, empty is open
+//	>=100 <2     comparisons with >, >=, < and <=
+func ParseRangeFilter(id FacetId, value string) (RangeFilter, error) {
+	r := RangeFilter{Id: id}
+	value = strings.TrimSpace(value)
+	if rest, found := strings.CutPrefix(value, "!"); found {
+		r.Not = true
+		value = strings.TrimSpace(rest)
+	}
+	var err error
+	switch {
+	case strings.HasPrefix(value, "[") || strings.HasPrefix(value, "("):
+		if !strings.HasSuffix(value, "]") && !strings.HasSuffix(value, ")") {
+			return r, fmt.Errorf("unterminated range %q", value)
+		}
+		r.MinExclusive = value[0] == '('
+		r.MaxExclusive = value[len(value)-1] == ')'
+		minValue, maxValue, found := strings.Cut(value[1:len(value)-1], ",")
+		if !found {
+			return r, fmt.Errorf("missing comma in range %q", value)
+		}
+		if r.Min, err = parseRangeBound(minValue); err != nil {
+			return r, err
+		}
+		r.Max, err = parseRangeBound(maxValue)
+	case strings.HasPrefix(value, ">="):
+		r.Min, err = parseRangeBound(value[2:])
+	case strings.HasPrefix(value, ">"):
+		r.MinExclusive = true
+		r.Min, err = parseRangeBound(value[1:])
+	case strings.HasPrefix(value, "<="):
+		r.Max, err = parseRangeBound(value[2:])
+	case strings.HasPrefix(value, "<"):
+		r.MaxExclusive = true
+		r.Max, err = parseRangeBound(value[1:])
+	default:
+		// the separator is the first dash that is not a sign
+		sep := -1
+		for i := 1; i < len(value); i++ {
+			if value[i] == '-' && value[i-1] != 'e' && value[i-1] != 'E' && value[i-1] != '-' {
+				sep = i
+				break
+			}
+		}
+		if sep == -1 {
+			if strings.HasPrefix(value, "-") {
+				// -200 is an open lower bound
+				r.Max, err = parseRangeBound(value[1:])
+			} else {
+				return r, fmt.Errorf("missing separator in range %q", value)
+			}
+		} else {
+			if r.Min, err = parseRangeBound(value[:sep]); err != nil {
+				return r, err
+			}
+			r.Max, err = parseRangeBound(value[sep+1:])
+		}
+	}
+	if err != nil {
+		return r, err
+	}
+	if r.IsOpen() {
+		return r, fmt.Errorf("range %q has no bounds", value)
+	}
+	return r, nil
+}
+
+// TreePathSeparator separates the levels in tree facet values, "a > b > c"
+const TreePathSeparator = ">"
+
+// PathFilter matches items below a node in a tree facet
+type PathFilter struct {
+	Id   FacetId  `json:"id"`
+	Path []string `json:"path"`
+	Not  bool     `json:"exclude"`
+}
+
+// SplitTreePath splits a tree facet value into its trimmed, non empty levels
+func SplitTreePath(value string) []string {
+	parts := strings.Split(value, TreePathSeparator)
+	ret := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if p := strings.TrimSpace(part); p != "" {
+			ret = append(ret, p)
+		}
+	}
+	return ret
 }
 
 func AsKeyFilterValue(value any) (StringFilterValue, bool) {
@@ -42,6 +247,7 @@ type Filters struct {
 	ids          *FilterIds
 	StringFilter []StringFilter `json:"string" schema:"-"`
 	RangeFilter  []RangeFilter  `json:"range" schema:"-"`
+	PathFilter   []PathFilter   `json:"path,omitempty" schema:"-"`
 }
 
 func (f *Filters) WithOut(id FacetId, dontExclude bool) *Filters {
@@ -51,6 +257,7 @@ func (f *Filters) WithOut(id FacetId, dontExclude bool) *Filters {
 	result := Filters{
 		StringFilter: make([]StringFilter, 0, len(f.StringFilter)),
 		RangeFilter:  make([]RangeFilter, 0, len(f.RangeFilter)),
+		PathFilter:   make([]PathFilter, 0, len(f.PathFilter)),
 	}
 	for _, filter := range f.StringFilter {
 		if filter.Id != id {
@@ -62,6 +269,11 @@ func (f *Filters) WithOut(id FacetId, dontExclude bool) *Filters {
 			result.RangeFilter = append(result.RangeFilter, filter)
 		}
 	}
+	for _, filter := range f.PathFilter {
+		if filter.Id != id {
+			result.PathFilter = append(result.PathFilter, filter)
+		}
+	}
 	return &result
 }
 
@@ -78,6 +290,9 @@ func (f *Filters) getIds() *FilterIds {
 				ids[filter.Id] = struct{}{}
 			}
 		}
+		for _, filter := range f.PathFilter {
+			ids[filter.Id] = struct{}{}
+		}
 		f.ids = &ids
 	}
 	return f.ids
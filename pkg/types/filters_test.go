@@ -0,0 +1,112 @@
+package types
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+func floatValue(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func TestParseRangeFilter(t *testing.T) {
+	tests := []struct {
+		input        string
+		min, max     any
+		minExclusive bool
+		maxExclusive bool
+		not          bool
+	}{
+		{"100-200", 100.0, 200.0, false, false, false},
+		{"5000-", 5000.0, nil, false, false, false},
+		{"-200", nil, 200.0, false, false, false},
+		{"-5--2", -5.0, -2.0, false, false, false},
+		{"1.5e-3-2", 0.0015, 2.0, false, false, false},
+		{">=5000", 5000.0, nil, false, false, false},
+		{">5000", 5000.0, nil, true, false, false},
+		{"<2", nil, 2.0, false, true, false},
+		{"<=2", nil, 2.0, false, false, false},
+		{"[10,20)", 10.0, 20.0, false, true, false},
+		{"(10,]", 10.0, nil, true, false, false},
+		{"!100-200", 100.0, 200.0, false, false, true},
+		{"!(,-3)", nil, -3.0, true, true, true},
+	}
+	for _, test := range tests {
+		r, err := ParseRangeFilter(4, test.input)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.input, err)
+			continue
+		}
+		if floatValue(r.Min) != test.min || floatValue(r.Max) != test.max {
+			t.Errorf("%s: expected %v-%v got %v-%v", test.input, test.min, test.max, floatValue(r.Min), floatValue(r.Max))
+		}
+		if r.MinExclusive != test.minExclusive || r.MaxExclusive != test.maxExclusive || r.Not != test.not {
+			t.Errorf("%s: unexpected flags %+v", test.input, r)
+		}
+	}
+	for _, input := range []string{"", "-", "abc", "[1,2", "(,)", "1-x"} {
+		if _, err := ParseRangeFilter(4, input); err == nil {
+			t.Errorf("%q: expected error", input)
+		}
+	}
+}
+
+func TestDecodeRangeFilterFromQuery(t *testing.T) {
+	query := url.Values{"rng": []string{"4:>=5000", "5:!1-2", "bad", "6:nope"}}
+	result := makeBaseFacetRequest()
+	if err := decodeFiltersFromRequest(query, result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.RangeFilter) != 2 {
+		t.Fatalf("Expected 2 range filters got %+v", result.RangeFilter)
+	}
+	for _, r := range result.RangeFilter {
+		switch r.Id {
+		case 4:
+			if *r.Min != 5000 || r.Max != nil {
+				t.Errorf("Unexpected filter %+v", r)
+			}
+		case 5:
+			if !r.Not {
+				t.Errorf("Expected filter 5 to be negated")
+			}
+		}
+	}
+}
+
+func TestRangeFilterUnmarshalJSON(t *testing.T) {
+	cases := []struct {
+		input            string
+		min, max         any
+		minText, maxText string
+	}{
+		{`{"id":1,"min":10,"max":20}`, 10.0, 20.0, "", ""},
+		{`{"id":1,"min":"10","max":null}`, 10.0, nil, "", ""},
+		{`{"id":1,"min":"2024-01-01","max":"*"}`, nil, nil, "2024-01-01", ""},
+		{`{"id":1,"max":"2024-12-31"}`, nil, nil, "", "2024-12-31"},
+	}
+	for _, c := range cases {
+		var r RangeFilter
+		if err := json.Unmarshal([]byte(c.input), &r); err != nil {
+			t.Fatalf("%s: %v", c.input, err)
+		}
+		if r.Id != 1 || floatValue(r.Min) != c.min || floatValue(r.Max) != c.max || r.MinText != c.minText || r.MaxText != c.maxText {
+			t.Errorf("%s: unexpected filter %+v", c.input, r)
+		}
+	}
+	b, err := json.Marshal(RangeFilter{Id: 1, MinText: "2024-01-01", Max: &[]float64{5}[0]})
+	if err != nil || string(b) != `{"id":1,"min":"2024-01-01","max":5}` {
+		t.Errorf("Unexpected json %s %v", b, err)
+	}
+	var r RangeFilter
+	if err := json.Unmarshal([]byte(`{"id":1,"min":true}`), &r); err == nil {
+		t.Error("Expected an error for a bool bound")
+	}
+	if text := (RangeFilter{MinText: "2024-01-01"}); text.IsOpen() {
+		t.Error("Expected text bounds to close the range")
+	}
+}
@@ -20,9 +20,11 @@ type Item interface {
 	//GetFieldValue(id uint) (interface{}, bool)
 	GetStringFields() map[FacetId]string
 	GetNumberFields() map[FacetId]float64
+	GetNumberListFields() map[FacetId][]float64
 	GetStringFieldValue(id FacetId) (string, bool)
 	GetStringsFieldValue(id FacetId) ([]string, bool)
 	GetNumberFieldValue(id FacetId) (float64, bool)
+	GetNumbersFieldValue(id FacetId) ([]float64, bool)
 
 	GetLastUpdated() int64
 	GetCreated() int64
@@ -10,26 +10,43 @@ import (
 
 // ItemFields stores string and numeric facets separately to avoid interface{} boxing.
 // It implements custom JSON marshal / unmarshal optimized for low allocations.
+// Multi-valued numeric facets are kept in numberListFacets.
 type ItemFields struct {
-	keyFacets    map[FacetId]string
-	numberFacets map[FacetId]float64
+	keyFacets        map[FacetId]string
+	numberFacets     map[FacetId]float64
+	numberListFacets map[FacetId][]float64
 }
 
 func NewItemFields() *ItemFields {
 	return &ItemFields{
-		keyFacets:    make(map[FacetId]string),
-		numberFacets: make(map[FacetId]float64),
+		keyFacets:        make(map[FacetId]string),
+		numberFacets:     make(map[FacetId]float64),
+		numberListFacets: make(map[FacetId][]float64),
 	}
 }
 
+// GetNumberFieldValue returns the value of a numeric facet, the first value
+// for multi-valued facets
 func (f ItemFields) GetNumberFieldValue(id FacetId) (float64, bool) {
 	v, ok := f.numberFacets[id]
 	if ok {
 		return v, true
 	}
+	if values, ok := f.numberListFacets[id]; ok && len(values) > 0 {
+		return values[0], true
+	}
 	return 0, false
 }
 
+// GetNumbersFieldValue returns all values of a numeric facet
+func (f ItemFields) GetNumbersFieldValue(id FacetId) ([]float64, bool) {
+	if v, ok := f.numberFacets[id]; ok {
+		return []float64{v}, true
+	}
+	values, ok := f.numberListFacets[id]
+	return values, ok
+}
+
 func (f ItemFields) GetStringsFieldValue(id FacetId) ([]string, bool) {
 
 	v, ok := f.keyFacets[id]
@@ -55,6 +72,10 @@ func (f ItemFields) GetStringFields() map[FacetId]string {
 	return f.keyFacets
 }
 
+func (f ItemFields) GetNumberListFields() map[FacetId][]float64 {
+	return f.numberListFacets
+}
+
 // // GetFacets materializes all facets into a map[uint]any. This allocates;
 // // prefer using GetFacetValue when possible.
 // func (f *ItemFields) GetFacets() map[uint]any {
@@ -78,12 +99,41 @@ func (f *ItemFields) upsertNumber(id uint, val float64) {
 	f.numberFacets[FacetId(id)] = val
 }
 
+// upsertNumbers inserts / updates a multi-valued numeric facet.
+func (f *ItemFields) upsertNumbers(id uint, values []float64) {
+	f.numberListFacets[FacetId(id)] = values
+}
+
 func (f *ItemFields) Remove(id FacetId) {
 	delete(f.keyFacets, id)
 	delete(f.numberFacets, id)
+	delete(f.numberListFacets, id)
+}
+
+// SetNumberFacet sets the values of a numeric facet, a single value is stored
+// as a plain number
+func (f *ItemFields) SetNumberFacet(id FacetId, values []float64) {
+	if f.numberFacets == nil {
+		f.numberFacets = make(map[FacetId]float64)
+	}
+	if f.numberListFacets == nil {
+		f.numberListFacets = make(map[FacetId][]float64)
+	}
+	delete(f.numberFacets, id)
+	delete(f.numberListFacets, id)
+	switch len(values) {
+	case 0:
+	case 1:
+		f.numberFacets[id] = values[0]
+	default:
+		f.numberListFacets[id] = values
+	}
 }
 
 func (f *ItemFields) SetKeyFacet(id FacetId, values []string) {
+	if f.keyFacets == nil {
+		f.keyFacets = make(map[FacetId]string)
+	}
 	f.keyFacets[id] = strings.Join(values, ";")
 }
 
@@ -92,7 +142,7 @@ func (f *ItemFields) SetKeyFacet(id FacetId, values []string) {
 func (f ItemFields) MarshalJSON() ([]byte, error) {
 	// Pre-size buffer roughly (heuristic).
 	var buf bytes.Buffer
-	buf.Grow((len(f.keyFacets)+len(f.numberFacets))*24 + 2)
+	buf.Grow((len(f.keyFacets)+len(f.numberFacets)+len(f.numberListFacets))*24 + 2)
 	buf.WriteByte('{')
 	first := true
 	var tmp []byte
@@ -134,16 +184,33 @@ func (f ItemFields) MarshalJSON() ([]byte, error) {
 		tmp = strconv.AppendFloat(tmp[:0], value, 'f', -1, 64)
 		buf.Write(tmp)
 	}
+	// Number lists
+	for id, values := range f.numberListFacets {
+		writeComma()
+		buf.WriteByte('"')
+		buf.WriteString(strconv.FormatUint(uint64(id), 10))
+		buf.WriteString(`":[`)
+		for i, value := range values {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			tmp = strconv.AppendFloat(tmp[:0], value, 'f', -1, 64)
+			buf.Write(tmp)
+		}
+		buf.WriteByte(']')
+	}
 	buf.WriteByte('}')
 	return buf.Bytes(), nil
 }
 
 // UnmarshalJSON parses the custom facet object.
-// Accepts values: string, number, or array of strings (joined with ", ").
+// Accepts values: string, number, array of strings (joined with ";") or
+// array of numbers.
 func (f *ItemFields) UnmarshalJSON(data []byte) error {
 	// Reset slices (allow reuse of underlying arrays).
 	f.keyFacets = map[FacetId]string{}
 	f.numberFacets = map[FacetId]float64{}
+	f.numberListFacets = map[FacetId][]float64{}
 
 	i := 0
 	skipWS := func() {
@@ -187,6 +254,26 @@ func (f *ItemFields) UnmarshalJSON(data []byte) error {
 		}
 		return "", errors.New("unterminated string")
 	}
+	parseNumber := func() (float64, error) {
+		startNum := i
+		if i < len(data) && data[i] == '-' {
+			i++
+		}
+		for i < len(data) && (data[i] >= '0' && data[i] <= '9') {
+			i++
+		}
+		if i < len(data) && data[i] == '.' {
+			i++
+			for i < len(data) && (data[i] >= '0' && data[i] <= '9') {
+				i++
+			}
+		}
+		numBytes := data[startNum:i]
+		if len(numBytes) == 0 {
+			return 0, errors.New("invalid number")
+		}
+		return strconv.ParseFloat(string(numBytes), 64)
+	}
 
 	skipWS()
 	if i >= len(data) || data[i] != '{' {
@@ -232,6 +319,33 @@ func (f *ItemFields) UnmarshalJSON(data []byte) error {
 			if i >= len(data) {
 				return errors.New("unexpected end in array")
 			}
+			if data[i] != '"' && data[i] != ']' {
+				// array of numbers
+				values := make([]float64, 0, 4)
+				for {
+					skipWS()
+					num, err := parseNumber()
+					if err != nil {
+						return err
+					}
+					values = append(values, num)
+					skipWS()
+					if i >= len(data) {
+						return errors.New("unexpected end in array")
+					}
+					if data[i] == ',' {
+						i++
+						continue
+					}
+					if data[i] == ']' {
+						i++
+						break
+					}
+					return errors.New("expected ',' or ']' in array")
+				}
+				f.upsertNumbers(uint(id64), values)
+				break
+			}
 			var sb strings.Builder
 			firstElem := true
 			for {
@@ -266,25 +380,7 @@ func (f *ItemFields) UnmarshalJSON(data []byte) error {
 			}
 			f.upsertString(uint(id64), sb.String())
 		default:
-			// Parse number
-			startNum := i
-			if data[i] == '-' {
-				i++
-			}
-			for i < len(data) && (data[i] >= '0' && data[i] <= '9') {
-				i++
-			}
-			if i < len(data) && data[i] == '.' {
-				i++
-				for i < len(data) && (data[i] >= '0' && data[i] <= '9') {
-					i++
-				}
-			}
-			numBytes := data[startNum:i]
-			if len(numBytes) == 0 {
-				return errors.New("invalid number")
-			}
-			num, err := strconv.ParseFloat(string(numBytes), 64)
+			num, err := parseNumber()
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,50 @@
+package types
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestItemFieldsNumberLists(t *testing.T) {
+	fields := ItemFields{}
+	if err := json.Unmarshal([]byte(`{"1":"a","2":["b","c"],"3":12.5,"4":[720, 1080,-1.5],"5":[]}`), &fields); err != nil {
+		t.Fatal(err)
+	}
+	values, ok := fields.GetNumbersFieldValue(4)
+	if !ok || !slices.Equal(values, []float64{720, 1080, -1.5}) {
+		t.Errorf("Expected number list got %v", values)
+	}
+	if first, ok := fields.GetNumberFieldValue(4); !ok || first != 720 {
+		t.Errorf("Expected the first value got %v", first)
+	}
+	if single, ok := fields.GetNumbersFieldValue(3); !ok || !slices.Equal(single, []float64{12.5}) {
+		t.Errorf("Expected single value as list got %v", single)
+	}
+	if v, ok := fields.GetStringFieldValue(2); !ok || v != "b;c" {
+		t.Errorf("Expected joined strings got %q", v)
+	}
+	if _, ok := fields.GetStringFieldValue(5); !ok {
+		t.Errorf("Expected empty array to be kept as string value")
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := ItemFields{}
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to decode %s: %v", data, err)
+	}
+	if values, _ = decoded.GetNumbersFieldValue(4); !slices.Equal(values, []float64{720, 1080, -1.5}) {
+		t.Errorf("Expected number list after round trip got %v (%s)", values, data)
+	}
+
+	decoded.SetNumberFacet(4, []float64{55})
+	if _, ok := decoded.GetNumberListFields()[4]; ok {
+		t.Errorf("Expected a single value to be stored as a number")
+	}
+	if v, ok := decoded.GetNumberFields()[4]; !ok || v != 55 {
+		t.Errorf("Expected single value got %v", v)
+	}
+}
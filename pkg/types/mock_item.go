@@ -13,6 +13,7 @@ type MockItem struct {
 	//Fields      map[uint]interface{}
 	StringFields map[FacetId]string
 	NumberFields map[FacetId]float64
+	NumberLists  map[FacetId][]float64
 	Deleted      bool
 	Price        int
 	OrgPrice     int
@@ -67,6 +68,10 @@ func (m *MockItem) GetNumberFields() map[FacetId]float64 {
 	return m.NumberFields
 }
 
+func (m *MockItem) GetNumberListFields() map[FacetId][]float64 {
+	return m.NumberLists
+}
+
 func (m *MockItem) GetStringFieldValue(id FacetId) (string, bool) {
 	if v, ok := m.StringFields[id]; ok && len(v) > 0 {
 		return v, true
@@ -84,9 +89,20 @@ func (m *MockItem) GetNumberFieldValue(id FacetId) (float64, bool) {
 	if v, ok := m.NumberFields[id]; ok {
 		return v, true
 	}
+	if v, ok := m.NumberLists[id]; ok && len(v) > 0 {
+		return v[0], true
+	}
 	return 0, false
 }
 
+func (m *MockItem) GetNumbersFieldValue(id FacetId) ([]float64, bool) {
+	if v, ok := m.NumberFields[id]; ok {
+		return []float64{v}, true
+	}
+	v, ok := m.NumberLists[id]
+	return v, ok
+}
+
 func (m *MockItem) GetId() ItemId {
 	return m.Id
 }
@@ -12,6 +12,8 @@ func init() {
 	Register(&PercentMultiplierRule{})
 	Register(&RatingRule{})
 	Register(&AgedRule{})
+	Register(&ExpressionRule{})
+	Register(&CampaignRule{})
 }
 
 type ItemPopularityRules []ItemPopularityRule
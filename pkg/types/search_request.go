@@ -2,8 +2,8 @@ package types
 
 import (
 	"encoding/json"
-	"fmt"
 	"maps"
+	"math"
 	"net/http"
 	"net/url"
 	"slices"
@@ -17,9 +17,13 @@ type SearchRequest struct {
 	*FacetRequest
 	Filter       string `json:"filter" schema:"filter"`
 	SkipTracking bool   `json:"skipTracking" schema:"nt"`
-	Sort         string `json:"sort" schema:"sort,default:popular"`
-	Page         int    `json:"page" schema:"page"`
-	PageSize     int    `json:"pageSize" schema:"size,default:40"`
+	// SkipPersonalization returns the same order for all sessions
+	SkipPersonalization bool `json:"skipPersonalization" schema:"np"`
+	// Diversify is on, off or the strength between 0 and 1, empty uses the settings
+	Diversify string `json:"diversify" schema:"diversify"`
+	Sort      string `json:"sort" schema:"sort,default:popular"`
+	Page      int    `json:"page" schema:"page"`
+	PageSize  int    `json:"pageSize" schema:"size,default:40"`
 }
 
 var decoder = schema.NewDecoder()
@@ -32,6 +36,27 @@ func (s *SearchRequest) UseStaticPosition() bool {
 	return s.Sort == "popular" || s.Sort == ""
 }
 
+// DiversityStrength returns the strength of the diversity re-ranking for the
+// request, 0 when disabled
+func (s *SearchRequest) DiversityStrength(defaults Diversity) float64 {
+	switch strings.ToLower(s.Diversify) {
+	case "":
+		return clamp(defaults.Strength, 0, 1)
+	case "off", "false":
+		return 0
+	case "on", "true":
+		if defaults.Strength > 0 {
+			return clamp(defaults.Strength, 0, 1)
+		}
+		return DefaultDiversityStrength
+	}
+	strength, err := strconv.ParseFloat(s.Diversify, 64)
+	if err != nil || math.IsNaN(strength) {
+		return 0
+	}
+	return clamp(strength, 0, 1)
+}
+
 func clamp[T int | float64](value, min, max T) T {
 	if value < min {
 		return min
@@ -48,6 +73,9 @@ func (s *SearchRequest) Sanitize() {
 	if s.Sort == "" {
 		s.Sort = "popular"
 	}
+	if keys, ok := ParseSortSpec(s.Sort); ok {
+		s.Sort = FormatSortSpec(keys)
+	}
 	s.FacetRequest.Sanitize()
 
 }
@@ -139,20 +167,43 @@ func decodeFiltersFromRequest(query url.Values, result *FacetRequest) error {
 	}
 
 	for _, v := range query["rng"] {
-		var id FacetId
-		var _min float64
-		var _max float64
-		_, err := fmt.Sscanf(v, "%d:%f-%f", &id, &_min, &_max)
-
+		idKey, value, found := strings.Cut(v, ":")
+		if !found {
+			continue
+		}
+		id64, err := strconv.ParseUint(strings.TrimSpace(idKey), 10, 32)
+		if err != nil {
+			continue
+		}
+		r, err := ParseRangeFilter(FacetId(id64), value)
+		if err != nil {
+			continue
+		}
+		rng[r.Id] = r
+	}
+	pth := map[FacetId]PathFilter{}
+	for _, v := range query["pth"] {
+		idKey, value, found := strings.Cut(v, ":")
+		if !found {
+			continue
+		}
+		id64, err := strconv.ParseUint(strings.TrimSpace(idKey), 10, 32)
 		if err != nil {
 			continue
 		}
-		rng[id] = RangeFilter{
-			Id:  id,
-			Min: _min,
-			Max: _max,
+		value = strings.TrimSpace(value)
+		exclude := strings.HasPrefix(value, "!")
+		path := SplitTreePath(strings.TrimPrefix(value, "!"))
+		if len(path) == 0 {
+			continue
+		}
+		pth[FacetId(id64)] = PathFilter{
+			Id:   FacetId(id64),
+			Path: path,
+			Not:  exclude,
 		}
 	}
+	result.PathFilter = slices.Collect(maps.Values(pth))
 	result.RangeFilter = slices.Collect(maps.Values(rng))
 	result.StringFilter = slices.Collect(maps.Values(key))
 	result.Sanitize()
@@ -164,6 +215,7 @@ func makeBaseFacetRequest() *FacetRequest {
 		Filters: &Filters{
 			StringFilter: []StringFilter{},
 			RangeFilter:  []RangeFilter{},
+			PathFilter:   []PathFilter{},
 		},
 		IgnoreFacets: []FacetId{},
 		Stock:        []string{},
@@ -179,3 +231,36 @@ func makeBaseSearchRequest() *SearchRequest {
 		PageSize:     40,
 	}
 }
+
+// ValueSearchRequest searches the values of a single facet, the facet request
+// filters limit the items the value counts are based on
+type ValueSearchRequest struct {
+	*FacetRequest
+	Q        string `json:"q" schema:"q"`
+	Page     int    `json:"page" schema:"page"`
+	PageSize int    `json:"pageSize" schema:"size,default:100"`
+}
+
+func (s *ValueSearchRequest) Sanitize() {
+	s.Page = clamp(s.Page, 0, 1000)
+	s.PageSize = clamp(s.PageSize, 1, 1000)
+	s.FacetRequest.Sanitize()
+}
+
+func GetValueSearchFromRequest(r *http.Request) (*ValueSearchRequest, error) {
+	sr := &ValueSearchRequest{
+		FacetRequest: makeBaseFacetRequest(),
+		PageSize:     100,
+	}
+	var err error
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		if err = decoder.Decode(sr, query); err == nil {
+			err = decodeFiltersFromRequest(query, sr.FacetRequest)
+		}
+	} else {
+		err = json.NewDecoder(r.Body).Decode(sr)
+	}
+	sr.Sanitize()
+	return sr, err
+}
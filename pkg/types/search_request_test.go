@@ -0,0 +1,30 @@
+package types
+
+import "testing"
+
+func TestDiversityStrength(t *testing.T) {
+	defaults := Diversity{Strength: 0.3}
+	cases := map[string]float64{
+		"":     0.3,
+		"on":   0.3,
+		"off":  0,
+		"0.8":  0.8,
+		"4":    1,
+		"nope": 0,
+		"NaN":  0,
+	}
+	for value, want := range cases {
+		sr := &SearchRequest{Diversify: value}
+		if got := sr.DiversityStrength(defaults); got != want {
+			t.Errorf("Expected %v for %q got %v", want, value, got)
+		}
+	}
+	sr := &SearchRequest{Diversify: "true"}
+	if got := sr.DiversityStrength(Diversity{}); got != DefaultDiversityStrength {
+		t.Errorf("Expected default strength got %v", got)
+	}
+	sr.Diversify = ""
+	if got := sr.DiversityStrength(Diversity{}); got != 0 {
+		t.Errorf("Expected diversity off without settings got %v", got)
+	}
+}
@@ -17,6 +17,102 @@ type Settings struct {
 	FacetRelations   []FacetRelationGroup `json:"facetRelations"`
 	PopularityRules  *ItemPopularityRules `json:"popularityRules"`
 	FacetGroups      []FacetGroup         `json:"facetGroups"`
+	// EmbeddingsTemplates is keyed by the item value of ProductTypeId,
+	// DefaultEmbeddingsTemplateKey is used when no product type matches
+	EmbeddingsTemplates map[string]EmbeddingsTemplate `json:"embeddingsTemplates,omitempty"`
+	// FacetProfiles select and order the facets shown for a category
+	FacetProfiles []FacetProfile `json:"facetProfiles,omitempty"`
+	// SessionBoost personalizes the popular sort for sessions with interactions
+	SessionBoost *SessionBoost `json:"sessionBoost,omitempty"`
+	// Diversity are the defaults for re-ranking the top of search results
+	Diversity *Diversity `json:"diversity,omitempty"`
+	// Experiment is the running ranking experiment
+	Experiment *Experiment `json:"experiment,omitempty"`
+}
+
+const DefaultEmbeddingsTemplateKey = "*"
+
+// EmbeddingsTemplate controls the text sent to the embeddings engine for items
+// of a product type, Template is a go text/template and FacetIds selects the
+// facets made available to it
+type EmbeddingsTemplate struct {
+	Template string    `json:"template"`
+	FacetIds []FacetId `json:"facetIds,omitempty"`
+}
+
+// FacetProfile controls the facets shown when a category is filtered on or
+// dominates the result
+type FacetProfile struct {
+	// FacetId is the category facet, 0 matches the value in any category facet
+	FacetId  FacetId `json:"facetId,omitempty"`
+	Category string  `json:"category"`
+	// FacetIds are shown first and in this order
+	FacetIds []FacetId `json:"facetIds"`
+	// Exclusive only shows the facets in FacetIds
+	Exclusive bool      `json:"exclusive,omitempty"`
+	Hidden    []FacetId `json:"hidden,omitempty"`
+	// Defaults override the facet settings within the category
+	Defaults map[FacetId]FacetDefaults `json:"defaults,omitempty"`
+}
+
+type FacetDefaults struct {
+	ValueSorting *uint    `json:"sorting,omitempty"`
+	ValueLimit   int      `json:"valueLimit,omitempty"`
+	ValueOrder   []string `json:"valueOrder,omitempty"`
+}
+
+// Apply returns a copy of the field with the defaults set
+func (d FacetDefaults) Apply(field *BaseField) *BaseField {
+	ret := *field
+	if d.ValueSorting != nil {
+		ret.ValueSorting = *d.ValueSorting
+	}
+	if d.ValueLimit > 0 {
+		ret.ValueLimit = d.ValueLimit
+	}
+	if len(d.ValueOrder) > 0 {
+		ret.ValueOrder = d.ValueOrder
+	}
+	return &ret
+}
+
+func (p *FacetProfile) Matches(id FacetId, category string) bool {
+	return p.Category == category && (p.FacetId == 0 || p.FacetId == id)
+}
+
+func (p *FacetProfile) Position(id FacetId) int {
+	return slices.Index(p.FacetIds, id)
+}
+
+func (p *FacetProfile) IsHidden(id FacetId) bool {
+	if slices.Contains(p.Hidden, id) {
+		return true
+	}
+	return p.Exclusive && !slices.Contains(p.FacetIds, id)
+}
+
+// SessionBoost lifts items sharing facet values, like brand or category, with
+// the items a session interacted with
+type SessionBoost struct {
+	FacetIds []FacetId `json:"facetIds"`
+	// Weight is added to the popularity per unit of interaction
+	Weight float64 `json:"weight"`
+}
+
+const (
+	DefaultDiversityStrength = 0.5
+	DefaultDiversityWindow   = 40
+)
+
+// Diversity re-ranks the first results to avoid runs of items sharing values,
+// like brand or product type, for the facets
+type Diversity struct {
+	FacetIds []FacetId `json:"facetIds"`
+	// Strength weighs the penalty for items similar to the ones above, 0 keeps
+	// the order and 1 ignores it
+	Strength float64 `json:"strength"`
+	// Window is the number of results re-ranked
+	Window int `json:"window,omitempty"`
 }
 
 type FacetGroup struct {
@@ -359,3 +455,75 @@ func (s *Settings) SetPopularityRules(rules *ItemPopularityRules) {
 	defer s.mu.Unlock()
 	s.PopularityRules = rules
 }
+
+func (s *Settings) GetFacetProfiles() []FacetProfile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.FacetProfiles
+}
+
+func (s *Settings) GetSessionBoost() *SessionBoost {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.SessionBoost
+}
+
+func (s *Settings) GetExperiment() *Experiment {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Experiment
+}
+
+// GetDiversity returns the diversity defaults, the product type is used when
+// no facets are set
+func (s *Settings) GetDiversity() Diversity {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ret := Diversity{}
+	if s.Diversity != nil {
+		ret = *s.Diversity
+	}
+	if len(ret.FacetIds) == 0 && s.ProductTypeId != 0 {
+		ret.FacetIds = []FacetId{s.ProductTypeId}
+	}
+	if ret.Window <= 0 {
+		ret.Window = DefaultDiversityWindow
+	}
+	return ret
+}
+
+// FindFacetProfile returns the profile for a category value, profiles bound to
+// the facet take precedence over the ones matching any category facet
+func (s *Settings) FindFacetProfile(id FacetId, category string) (*FacetProfile, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var found *FacetProfile
+	for i := range s.FacetProfiles {
+		p := &s.FacetProfiles[i]
+		if !p.Matches(id, category) {
+			continue
+		}
+		if p.FacetId == id {
+			return p, true
+		}
+		if found == nil {
+			found = p
+		}
+	}
+	return found, found != nil
+}
+
+func (s *Settings) GetEmbeddingsTemplate(item Item) (EmbeddingsTemplate, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.EmbeddingsTemplates) == 0 {
+		return EmbeddingsTemplate{}, false
+	}
+	if productType, ok := item.GetStringFieldValue(s.ProductTypeId); ok {
+		if tmpl, found := s.EmbeddingsTemplates[productType]; found {
+			return tmpl, true
+		}
+	}
+	tmpl, found := s.EmbeddingsTemplates[DefaultEmbeddingsTemplateKey]
+	return tmpl, found
+}
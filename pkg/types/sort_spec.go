@@ -0,0 +1,56 @@
+package types
+
+import (
+	"slices"
+	"strings"
+)
+
+// SortKey is one key of a composite sort, Name is a sort name like price,
+// popular or field:12
+type SortKey struct {
+	Name       string `json:"name"`
+	Descending bool   `json:"desc,omitempty"`
+}
+
+func (k SortKey) String() string {
+	if k.Descending {
+		return "-" + k.Name
+	}
+	return k.Name
+}
+
+// ParseSortSpec splits composite sorts like "price,-popular" into keys, keys
+// are ascending unless prefixed with - and later keys break ties of earlier
+// keys. A single name without a prefix is not composite and keeps the
+// direction of the named sort
+func ParseSortSpec(spec string) ([]SortKey, bool) {
+	if !strings.Contains(spec, ",") && !strings.HasPrefix(spec, "-") && !strings.HasPrefix(spec, "+") {
+		return nil, false
+	}
+	keys := make([]SortKey, 0, 2)
+	for part := range strings.SplitSeq(spec, ",") {
+		part = strings.TrimSpace(part)
+		key := SortKey{}
+		if name, ok := strings.CutPrefix(part, "-"); ok {
+			key.Name = name
+			key.Descending = true
+		} else {
+			key.Name = strings.TrimPrefix(part, "+")
+		}
+		// a repeated key can never break a tie
+		if key.Name == "" || slices.ContainsFunc(keys, func(k SortKey) bool { return k.Name == key.Name }) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, len(keys) > 0
+}
+
+// FormatSortSpec is the inverse of ParseSortSpec
+func FormatSortSpec(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key.String()
+	}
+	return strings.Join(parts, ",")
+}
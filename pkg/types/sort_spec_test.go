@@ -0,0 +1,30 @@
+package types
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSortSpec(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []SortKey
+		ok       bool
+	}{
+		{"popular", nil, false},
+		{"field:12:desc", nil, false},
+		{"price,-popular", []SortKey{{Name: "price"}, {Name: "popular", Descending: true}}, true},
+		{"-updated", []SortKey{{Name: "updated", Descending: true}}, true},
+		{" +price , -field:4 ,, -price", []SortKey{{Name: "price"}, {Name: "field:4", Descending: true}}, true},
+		{",", nil, false},
+	}
+	for _, test := range tests {
+		got, ok := ParseSortSpec(test.input)
+		if ok != test.ok || !slices.Equal(got, test.expected) {
+			t.Errorf("%q: expected %v (%v) got %v (%v)", test.input, test.expected, test.ok, got, ok)
+		}
+	}
+	if spec := FormatSortSpec([]SortKey{{Name: "price"}, {Name: "popular", Descending: true}}); spec != "price,-popular" {
+		t.Errorf("Expected price,-popular got %s", spec)
+	}
+}
@@ -8,12 +8,27 @@ import (
 
 type SortOverride map[uint32]float64
 
+// EventPopularityKey is the override with the popularity from tracking events,
+// it is added to the popular override
+const EventPopularityKey = "popular-events"
+
+// MergeSortOverrides returns the sum of the overrides
+func MergeSortOverrides(overrides ...SortOverride) SortOverride {
+	ret := SortOverride{}
+	for _, o := range overrides {
+		for id, value := range o {
+			ret[id] += value
+		}
+	}
+	return ret
+}
+
 func (s *SortOverride) ToString() string {
-	ret := ""
+	var ret strings.Builder
 	for key, value := range *s {
-		ret += fmt.Sprintf("%d:%f,", key, value)
+		fmt.Fprintf(&ret, "%d:%f,", key, value)
 	}
-	return ret
+	return ret.String()
 }
 
 func (s *SortOverride) Set(id uint32, value float64) {
@@ -16,6 +16,6 @@ type TrackingAction struct {
 
 type Tracking interface {
 	TrackSession(session_id int, r *http.Request)
-	TrackSearch(session_id int, filters *Filters, resultLen int, query string, page int, r *http.Request)
+	TrackSearch(session_id int, filters *Filters, resultLen int, query string, page int, assignment *ExperimentAssignment, r *http.Request)
 	Close() error
 }
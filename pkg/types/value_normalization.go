@@ -0,0 +1,136 @@
+package types
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Case modes for ValueNormalization
+const (
+	CaseKeep  = ""
+	CaseLower = "lower"
+	CaseUpper = "upper"
+	CaseTitle = "title"
+	// CaseFold merges values differing only by case into the first indexed spelling
+	CaseFold = "fold"
+)
+
+// ValueNormalization are the rules applied to key facet values before they are
+// indexed, values are always trimmed
+type ValueNormalization struct {
+	Case string `json:"case,omitempty"`
+	// CollapseSpaces replaces runs of whitespace with a single space
+	CollapseSpaces bool `json:"collapseSpaces,omitempty"`
+	// Units rewrites amounts like "1000 GB" to "1 TB" and "16GB" to "16 GB"
+	Units bool `json:"units,omitempty"`
+	// Aliases maps raw values to the value they are indexed as
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+type unitStep struct {
+	name   string
+	factor float64
+}
+
+// unit families, each ordered from the smallest unit
+var unitFamilies = [][]unitStep{
+	{{"B", 1}, {"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12}, {"PB", 1e15}},
+	{{"Hz", 1}, {"kHz", 1e3}, {"MHz", 1e6}, {"GHz", 1e9}},
+	{{"mAh", 1}, {"Ah", 1e3}},
+	{{"g", 1}, {"kg", 1e3}},
+	{{"W", 1}, {"kW", 1e3}},
+}
+
+func findUnit(unit string) ([]unitStep, int, bool) {
+	for _, family := range unitFamilies {
+		for i, step := range family {
+			if step.name == unit {
+				return family, i, true
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// NormalizeUnit rewrites a number followed by a known unit to the largest unit
+// of its family keeping the amount at or above one. Units are case sensitive
+// and must end the value or be followed by a space, so 5G is left as is
+func NormalizeUnit(value string) (string, bool) {
+	idx := strings.IndexFunc(value, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.' && r != ','
+	})
+	if idx <= 0 {
+		return value, false
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(value[:idx], ",", "."), 64)
+	if err != nil {
+		return value, false
+	}
+	unit := strings.TrimLeft(value[idx:], " ")
+	rest := ""
+	if end := strings.IndexByte(unit, ' '); end >= 0 {
+		unit, rest = unit[:end], unit[end:]
+	}
+	family, i, ok := findUnit(unit)
+	if !ok {
+		return value, false
+	}
+	base := amount * family[i].factor
+	best := 0
+	for j, step := range family {
+		if base/step.factor >= 1 {
+			best = j
+		}
+	}
+	scaled := math.Round(base/family[best].factor*100) / 100
+	return strconv.FormatFloat(scaled, 'f', -1, 64) + " " + family[best].name + rest, true
+}
+
+func titleCase(value string) string {
+	words := strings.Fields(value)
+	for i, word := range words {
+		runes := []rune(strings.ToLower(word))
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+// Apply returns the normalized value, CaseFold is left to the facet as it
+// depends on the values already indexed
+func (n *ValueNormalization) Apply(value string) string {
+	v := strings.TrimSpace(value)
+	if n == nil || v == "" {
+		return v
+	}
+	if n.CollapseSpaces {
+		v = strings.Join(strings.Fields(v), " ")
+	}
+	if alias, ok := n.Aliases[v]; ok {
+		return alias
+	}
+	if n.Units {
+		if unit, ok := NormalizeUnit(v); ok {
+			v = unit
+		}
+	}
+	switch n.Case {
+	case CaseLower:
+		v = strings.ToLower(v)
+	case CaseUpper:
+		v = strings.ToUpper(v)
+	case CaseTitle:
+		v = titleCase(v)
+	}
+	return v
+}
+
+// DisplayName returns the display name of a value, or the value itself
+func (b *BaseField) DisplayName(value string) string {
+	if name, ok := b.ValueNames[value]; ok {
+		return name
+	}
+	return value
+}
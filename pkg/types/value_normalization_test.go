@@ -0,0 +1,51 @@
+package types
+
+import "testing"
+
+func TestNormalizeUnit(t *testing.T) {
+	tests := map[string]string{
+		"1000 GB":  "1 TB",
+		"1500GB":   "1.5 TB",
+		"16GB":     "16 GB",
+		"16GB RAM": "16 GB RAM",
+		"512 MB":   "512 MB",
+		"2400 MHz": "2.4 GHz",
+		"0,5 kg":   "500 g",
+		"5000 mAh": "5 Ah",
+	}
+	for input, expected := range tests {
+		got, ok := NormalizeUnit(input)
+		if !ok || got != expected {
+			t.Errorf("%q: expected %q got %q (%v)", input, expected, got, ok)
+		}
+	}
+	for _, input := range []string{"4K TV", "Samsung", "12", "GB", "5G", "4G LTE", "16gb", "16GBx"} {
+		if got, ok := NormalizeUnit(input); ok {
+			t.Errorf("%q: expected no unit got %q", input, got)
+		}
+	}
+}
+
+func TestValueNormalizationApply(t *testing.T) {
+	n := &ValueNormalization{
+		Case:           CaseTitle,
+		CollapseSpaces: true,
+		Aliases:        map[string]string{"HP Inc": "HP"},
+	}
+	tests := map[string]string{
+		" SAMSUNG ":    "Samsung",
+		"samsung":      "Samsung",
+		"sony   music": "Sony Music",
+		"HP   Inc":     "HP",
+		"":             "",
+	}
+	for input, expected := range tests {
+		if got := n.Apply(input); got != expected {
+			t.Errorf("%q: expected %q got %q", input, expected, got)
+		}
+	}
+	var none *ValueNormalization
+	if got := none.Apply(" Samsung "); got != "Samsung" {
+		t.Errorf("Expected trimmed value got %q", got)
+	}
+}